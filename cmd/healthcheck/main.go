@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// Проба grpc.health.v1 для docker-compose healthcheck, код выхода 0 - SERVING
+func main() {
+	var (
+		addr    string
+		service string
+		timeout time.Duration
+	)
+
+	flag.StringVar(&addr, "addr", "localhost:8082", "gRPC server address")
+	flag.StringVar(&service, "service", "", "service name (empty = whole server)")
+	flag.DurationVar(&timeout, "timeout", time.Second, "check timeout")
+	flag.Parse()
+
+	if err := check(addr, service, timeout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func check(addr, service string, timeout time.Duration) error {
+	cc, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return err
+	}
+	defer cc.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	resp, err := healthpb.NewHealthClient(cc).Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+	if err != nil {
+		return err
+	}
+
+	if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		return fmt.Errorf("status %s", resp.GetStatus())
+	}
+
+	return nil
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	pb "VK_task/pkg/api/pubsub"
+)
+
+func runBench(ctx context.Context, args []string) error {
+	var (
+		conn    connFlags
+		in      inputFlags
+		total   int
+		workers int
+		size    int
+		sub     bool
+		timeout time.Duration
+	)
+
+	fs := flag.NewFlagSet("bench", flag.ContinueOnError)
+	conn.register(fs)
+	fs.StringVar(&in.file, "file", "", "messages from file, one per line, used in a loop (default -size bytes)")
+	fs.BoolVar(&in.jsonl, "jsonl", false, "with -file lines are JSON objects")
+	fs.StringVar(&in.field, "field", "", "with -jsonl publish this field instead of the whole line")
+	fs.IntVar(&total, "n", 10000, "number of messages")
+	fs.IntVar(&workers, "c", 8, "concurrent publishers")
+	fs.IntVar(&size, "size", 128, "message size in bytes without -file")
+	fs.BoolVar(&sub, "sub", true, "subscribe to the key and count received messages")
+	fs.DurationVar(&timeout, "timeout", 5*time.Second, "timeout of each Publish call")
+
+	args, err := parseFlags(fs, args, 1, 1, "<key>")
+	if err != nil {
+		return err
+	}
+	if total <= 0 || workers <= 0 || size <= 0 {
+		return errors.New("-n, -c and -size must be positive")
+	}
+
+	messages, err := benchMessages(&in, size)
+	if err != nil {
+		return err
+	}
+
+	client, closeConn, err := conn.dial()
+	if err != nil {
+		return err
+	}
+	defer closeConn()
+
+	key := args[0]
+
+	var received atomic.Int64
+
+	if sub {
+		subCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		stream, err := client.Subscribe(subCtx, &pb.SubscribeRequest{Key: key})
+		if err != nil {
+			return err
+		}
+
+		go func() {
+			for {
+				if _, err := stream.Recv(); err != nil {
+					return
+				}
+				received.Add(1)
+			}
+		}()
+
+		// Подписка регистрируется на сервере асинхронно
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	var (
+		next      atomic.Int64
+		failed    atomic.Int64
+		bytes     atomic.Int64
+		firstErr  error
+		errOnce   sync.Once
+		latencies = make([][]time.Duration, workers)
+		wg        sync.WaitGroup
+	)
+
+	start := time.Now()
+
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for ctx.Err() == nil {
+				i := next.Add(1) - 1
+				if i >= int64(total) {
+					return
+				}
+
+				data := messages[i%int64(len(messages))]
+
+				callCtx, cancel := context.WithTimeout(ctx, timeout)
+				t := time.Now()
+				_, err := client.Publish(callCtx, &pb.PublishRequest{Key: key, Data: data})
+				elapsed := time.Since(t)
+				cancel()
+
+				if err != nil {
+					failed.Add(1)
+					errOnce.Do(func() { firstErr = err })
+					continue
+				}
+
+				latencies[w] = append(latencies[w], elapsed)
+				bytes.Add(int64(len(data)))
+			}
+		}()
+	}
+
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	// Доставка подписчику отстаёт от ответов Publish
+	if sub {
+		time.Sleep(200 * time.Millisecond)
+	}
+
+	fmt.Print(benchReport(slices.Concat(latencies...), elapsed, bytes.Load(), failed.Load(), received.Load(), sub))
+
+	if firstErr != nil {
+		return fmt.Errorf("%d publish error(s), first: %w", failed.Load(), firstErr)
+	}
+
+	return nil
+}
+
+func benchMessages(in *inputFlags, size int) ([]string, error) {
+	if in.file == "" {
+		return []string{strings.Repeat("x", size)}, nil
+	}
+
+	var messages []string
+
+	err := in.each(func(_ int, data string) error {
+		messages = append(messages, data)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("no messages in %s", in.file)
+	}
+
+	return messages, nil
+}
+
+func benchReport(latencies []time.Duration, elapsed time.Duration, bytes, failed, received int64, sub bool) string {
+	var b strings.Builder
+
+	published := len(latencies)
+	seconds := elapsed.Seconds()
+
+	fmt.Fprintf(&b, "published:  %d ok, %d failed in %s\n", published, failed, elapsed.Round(time.Millisecond))
+	fmt.Fprintf(&b, "throughput: %.0f msg/s, %.2f MB/s\n", float64(published)/seconds, float64(bytes)/seconds/1e6)
+
+	if sub {
+		fmt.Fprintf(&b, "received:   %d\n", received)
+	}
+
+	if published == 0 {
+		return b.String()
+	}
+
+	slices.Sort(latencies)
+
+	percentile := func(p float64) time.Duration {
+		return latencies[int(float64(published-1)*p)]
+	}
+
+	fmt.Fprintf(&b, "latency:    p50 %s, p90 %s, p99 %s, max %s\n",
+		percentile(0.50), percentile(0.90), percentile(0.99), latencies[published-1])
+
+	return b.String()
+}
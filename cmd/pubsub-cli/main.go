@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	pb "VK_task/pkg/api/pubsub"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+const usage = `Usage: pubsub-cli <command> [flags] <key> [data]
+
+Commands:
+  pub    publish data, or every line of stdin / -file (JSONL with -jsonl)
+  sub    subscribe and print raw data, one message per line
+  tail   subscribe and pretty-print events with timestamps
+  bench  publish -n messages from -c workers and report throughput and latency
+
+Run "pubsub-cli <command> -h" for command flags.
+`
+
+// Клиент PubSub для ручной проверки сервера
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	commands := map[string]func(ctx context.Context, args []string) error{
+		"pub":   runPub,
+		"sub":   runSub,
+		"tail":  runTail,
+		"bench": runBench,
+	}
+
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if err := cmd(ctx, os.Args[2:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+
+		fmt.Fprintln(os.Stderr, "pubsub-cli:", err)
+		os.Exit(1)
+	}
+}
+
+// connFlags - подключение к серверу, общие для всех команд
+type connFlags struct {
+	addr       string
+	token      string
+	tls        bool
+	caFile     string
+	certFile   string
+	keyFile    string
+	serverName string
+}
+
+func (c *connFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&c.addr, "addr", "localhost:8082", "gRPC server address")
+	fs.StringVar(&c.token, "token", os.Getenv("PUBSUB_TOKEN"), "bearer token or JWT (default $PUBSUB_TOKEN)")
+	fs.BoolVar(&c.tls, "tls", false, "connect over TLS")
+	fs.StringVar(&c.caFile, "ca", "", "CA file to verify the server (default system roots)")
+	fs.StringVar(&c.certFile, "cert", "", "client certificate file for mTLS")
+	fs.StringVar(&c.keyFile, "key", "", "client key file for mTLS")
+	fs.StringVar(&c.serverName, "server-name", "", "server name for TLS verification")
+}
+
+func (c *connFlags) dial() (pb.PubSubClient, func(), error) {
+	creds := insecure.NewCredentials()
+
+	if c.tls || c.caFile != "" || c.certFile != "" {
+		tlsCfg, err := c.tlsConfig()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		creds = credentials.NewTLS(tlsCfg)
+	}
+
+	opts := []grpc.DialOption{grpc.WithTransportCredentials(creds)}
+
+	if c.token != "" {
+		opts = append(opts, grpc.WithPerRPCCredentials(bearer(c.token)))
+	}
+
+	cc, err := grpc.NewClient(c.addr, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return pb.NewPubSubClient(cc), func() { cc.Close() }, nil
+}
+
+func (c *connFlags) tlsConfig() (*tls.Config, error) {
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: c.serverName,
+	}
+
+	if c.caFile != "" {
+		pem, err := os.ReadFile(c.caFile)
+		if err != nil {
+			return nil, fmt.Errorf("read CA file: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in %s", c.caFile)
+		}
+		cfg.RootCAs = pool
+	}
+
+	if c.certFile != "" || c.keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return cfg, nil
+}
+
+// bearer - токен в metadata authorization, разрешён и без TLS для локальной отладки
+type bearer string
+
+func (b bearer) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(b)}, nil
+}
+
+func (b bearer) RequireTransportSecurity() bool {
+	return false
+}
+
+// parseFlags - флаги команды и ожидаемое число позиционных аргументов
+func parseFlags(fs *flag.FlagSet, args []string, minArgs, maxArgs int, argsUsage string) ([]string, error) {
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: pubsub-cli %s [flags] %s\n\nFlags:\n", fs.Name(), argsUsage)
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if fs.NArg() < minArgs || fs.NArg() > maxArgs {
+		fs.Usage()
+		return nil, flag.ErrHelp
+	}
+
+	return fs.Args(), nil
+}
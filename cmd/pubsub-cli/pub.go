@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	pb "VK_task/pkg/api/pubsub"
+
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+const maxLineSize = 4 << 20 // как максимальное сообщение gRPC по умолчанию
+
+func runPub(ctx context.Context, args []string) error {
+	var (
+		conn    connFlags
+		in      inputFlags
+		timeout time.Duration
+		ttl     time.Duration
+		retain  bool
+	)
+
+	fs := flag.NewFlagSet("pub", flag.ContinueOnError)
+	conn.register(fs)
+	in.register(fs)
+	fs.DurationVar(&timeout, "timeout", 5*time.Second, "timeout of each Publish call")
+	fs.DurationVar(&ttl, "ttl", 0, "message time-to-live (0 = server default for the subject)")
+	fs.BoolVar(&retain, "retain", false, `keep as the latest value of the key for new subscribers ("" data clears it)`)
+
+	args, err := parseFlags(fs, args, 1, 2, "<key> [data]")
+	if err != nil {
+		return err
+	}
+
+	client, closeConn, err := conn.dial()
+	if err != nil {
+		return err
+	}
+	defer closeConn()
+
+	key := args[0]
+
+	var ttlpb *durationpb.Duration
+	if ttl > 0 {
+		ttlpb = durationpb.New(ttl)
+	}
+
+	publish := func(data string) (uint32, error) {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		resp, err := client.Publish(ctx, &pb.PublishRequest{Key: key, Data: data, Ttl: ttlpb, Retain: retain})
+		if err != nil {
+			return 0, err
+		}
+
+		return resp.Delivered, nil
+	}
+
+	if len(args) == 2 {
+		delivered, err := publish(args[1])
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("delivered to %d subscriber(s)\n", delivered)
+
+		return nil
+	}
+
+	var published, delivered int
+
+	err = in.each(func(line int, data string) error {
+		n, err := publish(data)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+
+		published++
+		delivered += int(n)
+
+		return ctx.Err()
+	})
+
+	fmt.Fprintf(os.Stderr, "published %d message(s), %d deliveries\n", published, delivered)
+
+	return err
+}
+
+// inputFlags - сообщения из stdin или файла, по одному на строку
+type inputFlags struct {
+	file  string
+	jsonl bool
+	field string
+}
+
+func (in *inputFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&in.file, "file", "-", "file with one message per line (- = stdin)")
+	fs.BoolVar(&in.jsonl, "jsonl", false, "lines are JSON objects, invalid lines are rejected")
+	fs.StringVar(&in.field, "field", "", "with -jsonl publish this field instead of the whole line")
+}
+
+// each вызывает fn для каждой непустой строки входа с её номером
+func (in *inputFlags) each(fn func(line int, data string) error) error {
+	var r io.Reader = os.Stdin
+
+	if in.file != "-" {
+		f, err := os.Open(in.file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		r = f
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		data, err := in.message(text)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+
+		if err := fn(line, data); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+func (in *inputFlags) message(line string) (string, error) {
+	if !in.jsonl {
+		return line, nil
+	}
+
+	if in.field == "" {
+		if !json.Valid([]byte(line)) {
+			return "", errors.New("invalid JSON")
+		}
+
+		return line, nil
+	}
+
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(line), &obj); err != nil {
+		return "", errors.New("invalid JSON object")
+	}
+
+	raw, ok := obj[in.field]
+	if !ok {
+		return "", fmt.Errorf("no field %q", in.field)
+	}
+
+	// Строка - без кавычек, остальное - как JSON
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s, nil
+	}
+
+	return string(raw), nil
+}
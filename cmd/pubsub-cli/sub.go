@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	pb "VK_task/pkg/api/pubsub"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// subFlags - параметры подписки, общие для sub и tail
+type subFlags struct {
+	group string
+	start string
+	count int
+}
+
+func (s *subFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&s.group, "group", "", "queue group")
+	fs.StringVar(&s.start, "start", "", `replay durable log: "earliest" or offset (default new messages only)`)
+	fs.IntVar(&s.count, "n", 0, "exit after n messages (0 = until interrupted)")
+}
+
+func (s *subFlags) request(key string) (*pb.SubscribeRequest, error) {
+	req := &pb.SubscribeRequest{Key: key, Group: s.group}
+
+	switch s.start {
+	case "":
+	case "earliest":
+		req.Start = &pb.SubscribeRequest_StartEarliest{StartEarliest: true}
+	default:
+		offset, err := strconv.ParseUint(s.start, 10, 64)
+		if err != nil {
+			return nil, errors.New(`-start must be "earliest" or offset`)
+		}
+
+		req.Start = &pb.SubscribeRequest_StartOffset{StartOffset: offset}
+	}
+
+	return req, nil
+}
+
+func runSub(ctx context.Context, args []string) error {
+	return subscribe(ctx, "sub", args, func(event *pb.Event) {
+		fmt.Println(event.Data)
+	})
+}
+
+func runTail(ctx context.Context, args []string) error {
+	return subscribe(ctx, "tail", args, func(event *pb.Event) {
+		fmt.Print(formatEvent(time.Now(), event))
+	})
+}
+
+func subscribe(ctx context.Context, name string, args []string, print func(event *pb.Event)) error {
+	var (
+		conn connFlags
+		sub  subFlags
+	)
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	conn.register(fs)
+	sub.register(fs)
+
+	args, err := parseFlags(fs, args, 1, 1, "<key>")
+	if err != nil {
+		return err
+	}
+
+	req, err := sub.request(args[0])
+	if err != nil {
+		return err
+	}
+
+	client, closeConn, err := conn.dial()
+	if err != nil {
+		return err
+	}
+	defer closeConn()
+
+	stream, err := client.Subscribe(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	for received := 0; sub.count == 0 || received < sub.count; received++ {
+		event, err := stream.Recv()
+		if err != nil {
+			// Прерывание пользователем - не ошибка
+			if errors.Is(err, io.EOF) || status.Code(err) == codes.Canceled && ctx.Err() != nil {
+				return nil
+			}
+
+			return err
+		}
+
+		print(event)
+	}
+
+	return nil
+}
+
+/*
+formatEvent
+
+Время получения, subject, смещение в durable log и отметка
+сохранённого значения, JSON данные - с отступами, остальное - как есть:
+
+	15:04:05.000  orders.created  #12  retained
+	  {"id": 1}
+*/
+func formatEvent(received time.Time, event *pb.Event) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%s  %s", received.Format("15:04:05.000"), event.Key)
+	if event.Offset != 0 {
+		fmt.Fprintf(&b, "  #%d", event.Offset)
+	}
+	if event.Retained {
+		b.WriteString("  retained")
+	}
+	b.WriteByte('\n')
+
+	data := event.Data
+
+	var pretty bytes.Buffer
+	if json.Valid([]byte(data)) && json.Indent(&pretty, []byte(data), "  ", "  ") == nil {
+		data = pretty.String()
+	}
+
+	b.WriteString("  ")
+	b.WriteString(data)
+	b.WriteByte('\n')
+
+	return b.String()
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	sp "VK_task/pkg/subpub"
+
+	"golang.org/x/time/rate"
+)
+
+// options - параметры прогона, попадают в отчёт
+type options struct {
+	Target      string   `json:"target"`
+	Publishers  int      `json:"publishers"`
+	Subscribers int      `json:"subscribers"`
+	Subjects    int      `json:"subjects"`
+	Duration    duration `json:"duration"`
+	Rate        float64  `json:"rate"` // сообщений в секунду на издателя, 0 - без ограничения
+	Size        int      `json:"size"`
+	HandlerTime duration `json:"handler_time"`
+
+	// Только для -target inproc
+	SubjectBuffer      int    `json:"subject_buffer,omitempty"`
+	SubscriptionBuffer int    `json:"subscription_buffer,omitempty"`
+	Overflow           string `json:"overflow,omitempty"`
+
+	prefix string
+	drain  time.Duration
+}
+
+/*
+Нагрузка на шину: N издателей и M подписчиков на K subject,
+в процессе (подбор SubjectBuffer и SubscriptionBuffer) или на gRPC сервере.
+Отчёт - пропускная способность, задержка от Publish до обработчика
+и потери; с -report пишется в JSON для сравнения прогонов.
+*/
+func main() {
+	var (
+		opts       options
+		conn       grpcFlags
+		reportPath string
+		label      string
+	)
+
+	flag.StringVar(&opts.Target, "target", "inproc", "inproc (subpub.SubPub in this process) or grpc")
+	flag.IntVar(&opts.Publishers, "publishers", 4, "publishers (N)")
+	flag.IntVar(&opts.Subscribers, "subscribers", 4, "subscribers (M), spread over subjects round-robin")
+	flag.IntVar(&opts.Subjects, "subjects", 1, "subjects (K), every subject needs a subscriber")
+	flag.DurationVar((*time.Duration)(&opts.Duration), "duration", 10*time.Second, "publishing time")
+	flag.Float64Var(&opts.Rate, "rate", 0, "messages per second per publisher (0 = unlimited)")
+	flag.IntVar(&opts.Size, "size", 128, "message size in bytes")
+	flag.DurationVar((*time.Duration)(&opts.HandlerTime), "handler-time", 0, "simulated processing time per message")
+	flag.IntVar(&opts.SubjectBuffer, "subject-buffer", 0, "inproc: SubjectBuffer (0 = subpub default)")
+	flag.IntVar(&opts.SubscriptionBuffer, "subscription-buffer", 0, "inproc: SubscriptionBuffer (0 = subpub default)")
+	flag.StringVar(&opts.Overflow, "overflow", "drop_newest", "inproc: drop_newest, drop_oldest, block, disconnect")
+	flag.StringVar(&opts.prefix, "prefix", "load", "subject prefix, subjects are <prefix>.<n>")
+	flag.DurationVar(&opts.drain, "drain", 5*time.Second, "max wait for in-flight messages after publishing")
+	flag.StringVar(&conn.addr, "addr", "localhost:8082", "grpc: server address")
+	flag.StringVar(&conn.token, "token", os.Getenv("PUBSUB_TOKEN"), "grpc: bearer token or JWT (default $PUBSUB_TOKEN)")
+	flag.BoolVar(&conn.tls, "tls", false, "grpc: connect over TLS")
+	flag.StringVar(&conn.caFile, "ca", "", "grpc: CA file to verify the server")
+	flag.StringVar(&reportPath, "report", "", "write JSON report to file (- = stdout)")
+	flag.StringVar(&label, "label", "", "run label in the report (default VCS revision)")
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	rep, err := run(ctx, opts, conn)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "pubsub-load:", err)
+		os.Exit(1)
+	}
+
+	rep.setLabel(label)
+	fmt.Print(rep.summary())
+
+	if reportPath != "" {
+		if err := rep.write(reportPath); err != nil {
+			fmt.Fprintln(os.Stderr, "pubsub-load:", err)
+			os.Exit(1)
+		}
+	}
+}
+
+func run(ctx context.Context, opts options, conn grpcFlags) (*report, error) {
+	if opts.Publishers <= 0 || opts.Subscribers <= 0 || opts.Subjects <= 0 {
+		return nil, errors.New("-publishers, -subscribers and -subjects must be positive")
+	}
+	if opts.Subscribers < opts.Subjects {
+		return nil, errors.New("every subject needs a subscriber: -subscribers < -subjects")
+	}
+
+	t, err := newTarget(&opts, conn)
+	if err != nil {
+		return nil, err
+	}
+	defer t.close()
+
+	subjects := make([]string, opts.Subjects)
+	for i := range subjects {
+		subjects[i] = opts.prefix + "." + strconv.Itoa(i)
+	}
+
+	subCtx, cancelSubs := context.WithCancel(context.Background())
+	defer cancelSubs()
+
+	recorders := make([]*recorder, opts.Subscribers)
+	for i := range recorders {
+		recorders[i] = &recorder{handlerTime: time.Duration(opts.HandlerTime)}
+
+		if err := t.subscribe(subCtx, subjects[i%len(subjects)], recorders[i].handle); err != nil {
+			return nil, fmt.Errorf("subscribe: %w", err)
+		}
+	}
+
+	if opts.Target == "grpc" {
+		// Подписки регистрируются на сервере асинхронно
+		time.Sleep(200 * time.Millisecond)
+	}
+
+	var (
+		published atomic.Int64
+		expected  atomic.Int64
+		failed    atomic.Int64
+		firstErr  atomic.Value
+		wg        sync.WaitGroup
+	)
+
+	pubCtx, cancelPub := context.WithTimeout(ctx, time.Duration(opts.Duration))
+	defer cancelPub()
+
+	padding := strings.Repeat("x", opts.Size)
+	start := time.Now()
+
+	for p := range opts.Publishers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			var limiter *rate.Limiter
+			if opts.Rate > 0 {
+				limiter = rate.NewLimiter(rate.Limit(opts.Rate), 1)
+			}
+
+			for n := p; pubCtx.Err() == nil; n++ {
+				if limiter != nil && limiter.Wait(pubCtx) != nil {
+					return
+				}
+
+				delivered, err := t.publish(pubCtx, subjects[n%len(subjects)], encode(time.Now(), padding))
+				if err != nil {
+					if pubCtx.Err() == nil {
+						failed.Add(1)
+						firstErr.CompareAndSwap(nil, err.Error())
+					}
+					continue
+				}
+
+				published.Add(1)
+				expected.Add(int64(delivered))
+			}
+		}()
+	}
+
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	// Ожидание сообщений, ещё стоящих в очередях
+	received := waitReceived(recorders, expected.Load(), opts.drain)
+
+	rep := newReport(opts, elapsed, recorders)
+	rep.Published = published.Load()
+	rep.PublishErrors = failed.Load()
+	rep.Expected = expected.Load()
+	rep.Received = received
+	rep.Lost = rep.Expected - received
+	rep.QueueDropped = t.dropped()
+
+	if err, ok := firstErr.Load().(string); ok {
+		rep.FirstError = err
+	}
+
+	return rep, nil
+}
+
+// newTarget - для inproc в opts записываются размеры буферов после значений по умолчанию
+func newTarget(opts *options, conn grpcFlags) (target, error) {
+	switch opts.Target {
+	case "inproc":
+		cfg := sp.NewConfig(opts.SubjectBuffer, opts.SubscriptionBuffer)
+
+		overflow, err := sp.ParseOverflowPolicy(opts.Overflow)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Overflow = overflow
+
+		t := newInprocTarget(cfg)
+		opts.SubjectBuffer, opts.SubscriptionBuffer = cfg.SubjectBuffer, cfg.SubscriptionBuffer
+
+		return t, nil
+
+	case "grpc":
+		return newGRPCTarget(conn)
+	}
+
+	return nil, fmt.Errorf("unknown target %q", opts.Target)
+}
+
+func waitReceived(recorders []*recorder, expected int64, timeout time.Duration) int64 {
+	deadline := time.Now().Add(timeout)
+
+	for {
+		var received int64
+		for _, r := range recorders {
+			received += r.count()
+		}
+
+		if received >= expected || time.Now().After(deadline) {
+			return received
+		}
+
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// duration - в JSON строкой, как во флагах: "10s"
+type duration time.Duration
+
+func (d duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// encode - время публикации в начале данных для задержки у подписчика
+func encode(sent time.Time, padding string) string {
+	return strconv.FormatInt(sent.UnixNano(), 10) + " " + padding
+}
+
+func decode(data string) (time.Time, bool) {
+	ts, _, ok := strings.Cut(data, " ")
+	if !ok {
+		return time.Time{}, false
+	}
+
+	ns, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return time.Unix(0, ns), true
+}
+
+// recorder - задержки одного подписчика, обработчик вызывается последовательно
+type recorder struct {
+	handlerTime time.Duration
+
+	mu        sync.Mutex
+	latencies []time.Duration
+}
+
+func (r *recorder) handle(data string) {
+	sent, ok := decode(data)
+	if !ok {
+		return
+	}
+
+	latency := time.Since(sent)
+
+	r.mu.Lock()
+	r.latencies = append(r.latencies, latency)
+	r.mu.Unlock()
+
+	if r.handlerTime > 0 {
+		time.Sleep(r.handlerTime)
+	}
+}
+
+func (r *recorder) count() int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return int64(len(r.latencies))
+}
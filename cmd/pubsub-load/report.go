@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"time"
+)
+
+// report - результат прогона, JSON формат стабилен для сравнения между коммитами
+type report struct {
+	Label     string    `json:"label,omitempty"`
+	Revision  string    `json:"revision,omitempty"`
+	StartedAt time.Time `json:"started_at"`
+	GoVersion string    `json:"go_version"`
+	CPUs      int       `json:"cpus"`
+
+	Options options `json:"options"`
+
+	Elapsed       float64 `json:"elapsed_seconds"` // время публикации
+	Published     int64   `json:"published"`
+	PublishErrors int64   `json:"publish_errors"`
+	FirstError    string  `json:"first_error,omitempty"`
+	Expected      int64   `json:"expected"` // доставок по ответам Publish
+	Received      int64   `json:"received"`
+	Lost          int64   `json:"lost"`          // expected - received
+	QueueDropped  int64   `json:"queue_dropped"` // потери в очередях подписок, -1 - неизвестно
+
+	PublishRate float64 `json:"publish_rate"` // сообщений в секунду
+	ReceiveRate float64 `json:"receive_rate"`
+
+	Latency latency `json:"latency_us"`
+}
+
+// latency - задержка от Publish до обработчика подписчика в микросекундах
+type latency struct {
+	Mean float64 `json:"mean"`
+	P50  float64 `json:"p50"`
+	P90  float64 `json:"p90"`
+	P99  float64 `json:"p99"`
+	P999 float64 `json:"p999"`
+	Max  float64 `json:"max"`
+}
+
+func newReport(opts options, elapsed time.Duration, recorders []*recorder) *report {
+	var all []time.Duration
+	for _, r := range recorders {
+		r.mu.Lock()
+		all = append(all, r.latencies...)
+		r.mu.Unlock()
+	}
+
+	rep := &report{
+		StartedAt: time.Now().Add(-elapsed).UTC(),
+		GoVersion: runtime.Version(),
+		CPUs:      runtime.NumCPU(),
+		Options:   opts,
+		Elapsed:   elapsed.Seconds(),
+		Latency:   latencyOf(all),
+	}
+
+	if opts.Target != "inproc" {
+		rep.Options.SubjectBuffer, rep.Options.SubscriptionBuffer, rep.Options.Overflow = 0, 0, ""
+	}
+
+	return rep
+}
+
+func latencyOf(latencies []time.Duration) latency {
+	if len(latencies) == 0 {
+		return latency{}
+	}
+
+	slices.Sort(latencies)
+
+	var sum time.Duration
+	for _, l := range latencies {
+		sum += l
+	}
+
+	us := func(d time.Duration) float64 {
+		return float64(d) / float64(time.Microsecond)
+	}
+
+	percentile := func(p float64) float64 {
+		return us(latencies[int(float64(len(latencies)-1)*p)])
+	}
+
+	return latency{
+		Mean: us(sum / time.Duration(len(latencies))),
+		P50:  percentile(0.50),
+		P90:  percentile(0.90),
+		P99:  percentile(0.99),
+		P999: percentile(0.999),
+		Max:  us(latencies[len(latencies)-1]),
+	}
+}
+
+// setLabel - метка прогона, по умолчанию ревизия из сборки
+func (r *report) setLabel(label string) {
+	r.PublishRate = float64(r.Published) / r.Elapsed
+	r.ReceiveRate = float64(r.Received) / r.Elapsed
+	r.Label = label
+
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return
+	}
+
+	var revision, modified string
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			revision = s.Value
+		case "vcs.modified":
+			modified = s.Value
+		}
+	}
+
+	if revision != "" && modified == "true" {
+		revision += "-dirty"
+	}
+	r.Revision = revision
+
+	if r.Label == "" {
+		r.Label = revision
+	}
+}
+
+func (r *report) summary() string {
+	var b strings.Builder
+
+	o := r.Options
+
+	fmt.Fprintf(&b, "target %s: %d publishers, %d subscribers, %d subjects, %d B messages\n",
+		o.Target, o.Publishers, o.Subscribers, o.Subjects, o.Size)
+	if o.Target == "inproc" {
+		fmt.Fprintf(&b, "subject_buffer %d, subscription_buffer %d, overflow %s\n",
+			o.SubjectBuffer, o.SubscriptionBuffer, o.Overflow)
+	}
+
+	fmt.Fprintf(&b, "published:  %d in %.2fs (%.0f msg/s), %d errors\n",
+		r.Published, r.Elapsed, r.PublishRate, r.PublishErrors)
+	if r.FirstError != "" {
+		fmt.Fprintf(&b, "            first error: %s\n", r.FirstError)
+	}
+
+	fmt.Fprintf(&b, "received:   %d of %d (%.0f msg/s), lost %d", r.Received, r.Expected, r.ReceiveRate, r.Lost)
+	if r.QueueDropped >= 0 {
+		fmt.Fprintf(&b, ", queue dropped %d", r.QueueDropped)
+	}
+	b.WriteByte('\n')
+
+	l := r.Latency
+	fmt.Fprintf(&b, "latency:    mean %s, p50 %s, p90 %s, p99 %s, p999 %s, max %s\n",
+		us(l.Mean), us(l.P50), us(l.P90), us(l.P99), us(l.P999), us(l.Max))
+
+	return b.String()
+}
+
+func us(v float64) time.Duration {
+	return (time.Duration(v * float64(time.Microsecond))).Round(time.Microsecond)
+}
+
+func (r *report) write(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	if path == "-" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+
+	return os.WriteFile(path, data, 0o644)
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	pb "VK_task/pkg/api/pubsub"
+	sp "VK_task/pkg/subpub"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// target - шина под нагрузкой: SubPub в процессе или gRPC сервер
+type target interface {
+	// publish возвращает число подписчиков, в очереди которых попало сообщение
+	publish(ctx context.Context, subject, data string) (int, error)
+	subscribe(ctx context.Context, subject string, handler func(data string)) error
+	// dropped - потери в очередях подписок, -1 - неизвестно
+	dropped() int64
+	close() error
+}
+
+type inprocTarget struct {
+	ps sp.SubPub
+}
+
+func newInprocTarget(cfg *sp.Config) *inprocTarget {
+	// Предупреждения о переполнении на каждое сообщение исказят замер
+	return &inprocTarget{ps: sp.NewSubPub(cfg, slog.New(slog.DiscardHandler))}
+}
+
+func (t *inprocTarget) publish(ctx context.Context, subject, data string) (int, error) {
+	// С -overflow block очередь subject может не освободиться до конца прогона
+	return t.ps.PublishContext(ctx, subject, data)
+}
+
+func (t *inprocTarget) subscribe(_ context.Context, subject string, handler func(data string)) error {
+	_, err := sp.Subscribe(t.ps, subject, handler)
+
+	return err
+}
+
+func (t *inprocTarget) dropped() int64 {
+	var n uint64
+
+	for _, subj := range t.ps.Stats().Subjects {
+		for _, sub := range subj.Subscriptions {
+			n += sub.Dropped
+		}
+	}
+
+	return int64(n)
+}
+
+func (t *inprocTarget) close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return t.ps.Close(ctx)
+}
+
+type grpcTarget struct {
+	cc     *grpc.ClientConn
+	client pb.PubSubClient
+}
+
+// grpcFlags - подключение к серверу для -target grpc
+type grpcFlags struct {
+	addr   string
+	token  string
+	tls    bool
+	caFile string
+}
+
+func newGRPCTarget(f grpcFlags) (*grpcTarget, error) {
+	creds := insecure.NewCredentials()
+
+	if f.tls || f.caFile != "" {
+		cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+
+		if f.caFile != "" {
+			pem, err := os.ReadFile(f.caFile)
+			if err != nil {
+				return nil, fmt.Errorf("read CA file: %w", err)
+			}
+
+			cfg.RootCAs = x509.NewCertPool()
+			if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificates in %s", f.caFile)
+			}
+		}
+
+		creds = credentials.NewTLS(cfg)
+	}
+
+	opts := []grpc.DialOption{grpc.WithTransportCredentials(creds)}
+	if f.token != "" {
+		opts = append(opts, grpc.WithPerRPCCredentials(bearer(f.token)))
+	}
+
+	cc, err := grpc.NewClient(f.addr, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &grpcTarget{cc: cc, client: pb.NewPubSubClient(cc)}, nil
+}
+
+func (t *grpcTarget) publish(ctx context.Context, subject, data string) (int, error) {
+	resp, err := t.client.Publish(ctx, &pb.PublishRequest{Key: subject, Data: data})
+	if err != nil {
+		return 0, err
+	}
+
+	return int(resp.Delivered), nil
+}
+
+func (t *grpcTarget) subscribe(ctx context.Context, subject string, handler func(data string)) error {
+	stream, err := t.client.Subscribe(ctx, &pb.SubscribeRequest{Key: subject})
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		for {
+			event, err := stream.Recv()
+			if err != nil {
+				return
+			}
+
+			handler(event.Data)
+		}
+	}()
+
+	return nil
+}
+
+// Потери на сервере видны только как разница отправленного и полученного
+func (t *grpcTarget) dropped() int64 {
+	return -1
+}
+
+func (t *grpcTarget) close() error {
+	return t.cc.Close()
+}
+
+type bearer string
+
+func (b bearer) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(b)}, nil
+}
+
+func (b bearer) RequireTransportSecurity() bool {
+	return false
+}
@@ -20,7 +20,7 @@ func main() {
 	log.Debug("Config", slog.Any("data", cfg))
 
 	// App
-	application := app.New(log, cfg.GRPC.Addr, cfg.GRPC.Port, cfg.SubPub.SubjectBuffer, cfg.SubPub.SubscriptionBuffer)
+	application := app.MustNew(log, cfg.GRPC, cfg.SubPub, cfg.Metrics, cfg.Tracing, cfg.Gateway)
 
 	go application.MustRun()
 
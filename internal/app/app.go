@@ -2,44 +2,235 @@ package app
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"time"
 
 	grpcapp "VK_task/internal/app/grpc"
+	httpapp "VK_task/internal/app/http"
+	metricsapp "VK_task/internal/app/metrics"
+	"VK_task/internal/certs"
+	"VK_task/internal/config"
+	"VK_task/internal/grpc/handler/admin"
 	"VK_task/internal/grpc/handler/pubsub"
+	"VK_task/internal/grpc/middleware/auth"
+	"VK_task/internal/grpc/middleware/ratelimit"
+	"VK_task/internal/http/gateway"
+	"VK_task/internal/metrics"
+	"VK_task/internal/tracing"
 	"VK_task/pkg/e"
 	"VK_task/pkg/subpub"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 )
 
+const tracingShutdownTimeout = 5 * time.Second
+
 type App struct {
-	GRPCApp *grpcapp.App
-	SubPub  subpub.SubPub
+	GRPCApp    *grpcapp.App
+	MetricsApp *metricsapp.App // nil, если метрики выключены
+	HTTPApp    *httpapp.App    // nil, если HTTP gateway выключен
+	SubPub     subpub.SubPub
+
+	tracerProvider *sdktrace.TracerProvider // nil, если трассировка выключена
+	certs          *certs.Reloader          // nil, если gRPC без TLS
 }
 
-func New(log *slog.Logger,
-	grpcSrvIP string,
-	grpcSrvPort int,
-	subjectBuffer int,
-	subscriptionBuffer int,
+func MustNew(log *slog.Logger,
+	grpcCfg config.GRPC,
+	spCfg config.SubPub,
+	metricsCfg config.Metrics,
+	tracingCfg config.Tracing,
+	gatewayCfg config.Gateway,
 ) *App {
-	subPub := subpub.NewSubPub(subpub.NewConfig(
-		subjectBuffer,
-		subscriptionBuffer,
-	), log)
+	app, err := New(log, grpcCfg, spCfg, metricsCfg, tracingCfg, gatewayCfg)
+	if err != nil {
+		panic(e.Wrap("App creating failed", err))
+	}
+
+	return app
+}
+
+/*
+New
+
+Если в spCfg задан каталог durable log, сообщения восстанавливаются с диска.
+При metricsCfg.Enabled метрики шины и gRPC отдаются по HTTP.
+При tracingCfg.Enabled span gRPC вызовов, публикации и доставки
+экспортируются в stdout или OTLP.
+При grpcCfg.TLS.Enabled gRPC сервер работает по TLS (mTLS, если задан CA клиентов),
+сертификаты перечитываются при изменении файлов.
+При grpcCfg.Auth.Enabled доступ к subject проверяется по файлу политики.
+При grpcCfg.RateLimit.Enabled публикации и подписки ограничиваются по клиенту и subject.
+При gatewayCfg.Enabled v1 Publish и Subscribe доступны по HTTP/JSON и SSE,
+Session - по WebSocket, через те же interceptor, что и gRPC.
+С grpcCfg.TLS.Enabled gateway работает по HTTPS с теми же сертификатами и mTLS.
+*/
+func New(log *slog.Logger,
+	grpcCfg config.GRPC,
+	spCfg config.SubPub,
+	metricsCfg config.Metrics,
+	tracingCfg config.Tracing,
+	gatewayCfg config.Gateway,
+) (*App, error) {
+	subPubCfg, err := newSubPubConfig(spCfg)
+	if err != nil {
+		return nil, e.Wrap("invalid sub_pub config", err)
+	}
+
+	var (
+		grpcOpts   grpcapp.Options
+		metricsApp *metricsapp.App
+	)
+
+	if metricsCfg.Enabled {
+		m := metrics.New()
+		subPubCfg.Metrics = m
+		grpcOpts.Metrics = m
+
+		path := metricsCfg.Path
+		if path == "" {
+			path = "/metrics"
+		}
+
+		metricsApp = metricsapp.New(metricsCfg.Addr, metricsCfg.Port, path, log, m.Handler())
+	}
+
+	var tracerProvider *sdktrace.TracerProvider
+
+	if tracingCfg.Enabled {
+		tracerProvider, err = tracing.New(tracing.Config{
+			Exporter:    tracingCfg.Exporter,
+			Endpoint:    tracingCfg.Endpoint,
+			ServiceName: tracingCfg.ServiceName,
+			SampleRatio: tracingCfg.SampleRatio,
+		})
+		if err != nil {
+			return nil, e.Wrap("tracer provider creating failed", err)
+		}
+
+		subPubCfg.TracerProvider = tracerProvider
+		grpcOpts.Tracer = tracerProvider
+	}
+
+	var reloader *certs.Reloader
+
+	if tlsCfg := grpcCfg.TLS; tlsCfg.Enabled {
+		reloader, err = certs.New(certs.Config{
+			CertFile:          tlsCfg.CertFile,
+			KeyFile:           tlsCfg.KeyFile,
+			ClientCAFile:      tlsCfg.ClientCAFile,
+			RequireClientCert: tlsCfg.RequireClientCert,
+			ReloadInterval:    tlsCfg.ReloadInterval,
+		}, log)
+		if err != nil {
+			return nil, e.Wrap("TLS config loading failed", err)
+		}
+
+		grpcOpts.TLS = reloader.TLSConfig()
+	}
+
+	if grpcCfg.Auth.Enabled {
+		grpcOpts.Policy, err = auth.LoadPolicy(grpcCfg.Auth.PolicyFile)
+		if err != nil {
+			return nil, e.Wrap("auth policy loading failed", err)
+		}
+	}
+
+	if rl := grpcCfg.RateLimit; rl.Enabled {
+		grpcOpts.Limiter = ratelimit.New(ratelimit.Config{
+			Client:           rateLimits(rl.Client),
+			Subject:          rateLimits(rl.Subject),
+			MaxSubscriptions: rl.MaxSubscriptions,
+			IdleTimeout:      rl.IdleTimeout,
+		})
+	}
+
+	grpcOpts.Reflection = grpcCfg.Reflection
+
+	subPub, err := subpub.Open(subPubCfg, log)
+	if err != nil {
+		return nil, e.Wrap("Sub/Pub open failed", err)
+	}
 
 	// Для GracefulStop
 	grpcStopCh := make(chan struct{})
 
 	PubSubService := pubsub.New(subPub, log, grpcStopCh)
+	PubSubServiceV2 := pubsub.NewV2(subPub, log, grpcStopCh)
+
+	if grpcCfg.Admin.Enabled {
+		grpcOpts.Admin = admin.New(subPub, log)
+	}
 
-	grpcApp := grpcapp.New(grpcSrvIP, grpcSrvPort, log, PubSubService, grpcStopCh)
+	grpcApp := grpcapp.New(grpcCfg.Addr, grpcCfg.Port, log, PubSubService, PubSubServiceV2, grpcOpts, grpcStopCh)
+
+	var httpApp *httpapp.App
+
+	if gatewayCfg.Enabled {
+		unary, stream := grpcApp.Interceptors()
+
+		httpApp = httpapp.New(gatewayCfg.Addr, gatewayCfg.Port, log, gateway.New(PubSubService, unary, stream, gatewayCfg.AllowedOrigins, log), grpcOpts.TLS)
+	}
 
 	return &App{
-		GRPCApp: grpcApp,
-		SubPub:  subPub,
+		GRPCApp:    grpcApp,
+		MetricsApp: metricsApp,
+		HTTPApp:    httpApp,
+		SubPub:     subPub,
+
+		tracerProvider: tracerProvider,
+		certs:          reloader,
+	}, nil
+}
+
+func rateLimits(cfg config.RateLimits) ratelimit.Limits {
+	return ratelimit.Limits{
+		Messages: ratelimit.Bucket{Rate: cfg.PublishRate, Burst: cfg.PublishBurst},
+		Bytes:    ratelimit.Bucket{Rate: cfg.BytesRate, Burst: cfg.BytesBurst},
 	}
 }
 
+func newSubPubConfig(spCfg config.SubPub) (*subpub.Config, error) {
+	cfg := subpub.NewConfig(
+		spCfg.SubjectBuffer,
+		spCfg.SubscriptionBuffer,
+	)
+	cfg.PendingBuffer = spCfg.PendingBuffer
+	cfg.BlockTimeout = spCfg.BlockTimeout
+	cfg.AckTimeout = spCfg.AckTimeout
+	cfg.MaxAttempts = spCfg.MaxAttempts
+	cfg.DeadLetterPrefix = spCfg.DeadLetterPrefix
+	cfg.MessageTTL = spCfg.MessageTTL
+	cfg.SubjectTTL = spCfg.SubjectTTL
+	cfg.Log = subpub.LogConfig{
+		Dir:          spCfg.Log.Dir,
+		SegmentSize:  spCfg.Log.SegmentSize,
+		Sync:         spCfg.Log.Sync,
+		MaxOpenFiles: spCfg.Log.MaxOpenFiles,
+	}
+
+	var err error
+
+	cfg.NoSubscribers, err = subpub.ParseNoSubscribersPolicy(spCfg.NoSubscribers)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.Overflow, err = subpub.ParseOverflowPolicy(spCfg.Overflow)
+	if err != nil {
+		return nil, err
+	}
+
+	for pattern, ttl := range spCfg.SubjectTTL {
+		if !subpub.ValidPattern(pattern) || ttl < 0 {
+			return nil, fmt.Errorf("invalid subject_ttl %q: %s", pattern, ttl)
+		}
+	}
+
+	return cfg, nil
+}
+
 func (app *App) MustRun() {
 	if err := app.Run(); err != nil {
 		panic(e.Wrap("App starting failed", err))
@@ -47,6 +238,22 @@ func (app *App) MustRun() {
 }
 
 func (app *App) Run() error {
+	if app.certs != nil {
+		app.certs.Start()
+	}
+
+	if app.MetricsApp != nil {
+		if err := app.MetricsApp.Start(); err != nil {
+			return e.Wrap("metrics application startup failed", err)
+		}
+	}
+
+	if app.HTTPApp != nil {
+		if err := app.HTTPApp.Start(); err != nil {
+			return e.Wrap("HTTP gateway startup failed", err)
+		}
+	}
+
 	if err := app.GRPCApp.Start(); err != nil {
 		return e.Wrap("grpc application startup failed", err)
 	}
@@ -56,8 +263,18 @@ func (app *App) Run() error {
 
 func (app *App) Stop(spCloseTimeout time.Duration) error {
 	// С начало жду завершение handler которые могут использовать subPub
+	if app.HTTPApp != nil {
+		if err := app.HTTPApp.Stop(); err != nil {
+			return err
+		}
+	}
+
 	app.GRPCApp.Stop()
 
+	if app.certs != nil {
+		app.certs.Stop()
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), spCloseTimeout)
 	defer cancel()
 
@@ -65,10 +282,26 @@ func (app *App) Stop(spCloseTimeout time.Duration) error {
 		return e.Wrap("Sub/Pub close failed", err)
 	}
 
-	return nil
+	if app.MetricsApp != nil {
+		if err := app.MetricsApp.Stop(); err != nil {
+			return err
+		}
+	}
+
+	return app.stopTracing()
 }
 
 func (app *App) StopWithLog(spCloseTimeout time.Duration, log *slog.Logger) error {
+	if app.HTTPApp != nil {
+		log.Info("Stopping HTTP gateway")
+
+		if err := app.HTTPApp.Stop(); err != nil {
+			return err
+		}
+
+		log.Info("HTTP gateway stopped")
+	}
+
 	log.Info("Stopping gRPC server")
 
 	// С начало жду завершение handler которые могут использовать subPub
@@ -76,6 +309,10 @@ func (app *App) StopWithLog(spCloseTimeout time.Duration, log *slog.Logger) erro
 
 	log.Info("gRPC server stopped")
 
+	if app.certs != nil {
+		app.certs.Stop()
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), spCloseTimeout)
 	defer cancel()
 
@@ -87,5 +324,37 @@ func (app *App) StopWithLog(spCloseTimeout time.Duration, log *slog.Logger) erro
 
 	log.Info("SubPub event bus closed")
 
+	if app.MetricsApp != nil {
+		if err := app.MetricsApp.Stop(); err != nil {
+			return err
+		}
+
+		log.Info("Metrics server stopped")
+	}
+
+	if app.tracerProvider != nil {
+		if err := app.stopTracing(); err != nil {
+			return err
+		}
+
+		log.Info("Tracer provider stopped")
+	}
+
+	return nil
+}
+
+// stopTracing отправляет оставшиеся span и останавливает экспорт
+func (app *App) stopTracing() error {
+	if app.tracerProvider == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), tracingShutdownTimeout)
+	defer cancel()
+
+	if err := app.tracerProvider.Shutdown(ctx); err != nil {
+		return e.Wrap("tracer provider shutdown failed", err)
+	}
+
 	return nil
 }
@@ -1,39 +1,153 @@
 package grpcapp
 
 import (
+	"VK_task/internal/grpc/middleware/auth"
+	"VK_task/internal/grpc/middleware/identity"
 	"VK_task/internal/grpc/middleware/logger"
+	"VK_task/internal/grpc/middleware/metrics"
+	"VK_task/internal/grpc/middleware/ratelimit"
+	"VK_task/internal/grpc/middleware/tracing"
 	pb "VK_task/pkg/api/pubsub"
+	pbadmin "VK_task/pkg/api/pubsub/admin"
+	pbv2 "VK_task/pkg/api/pubsub/v2"
 	"VK_task/pkg/e"
+	"crypto/tls"
 	"fmt"
 	"log/slog"
 	"net"
+	"slices"
 
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
 )
 
 type App struct {
 	gRPCServer *grpc.Server
+	health     *health.Server
+	services   []string
 	addr       string
 	log        *slog.Logger
 
+	unary  []grpc.UnaryServerInterceptor
+	stream []grpc.StreamServerInterceptor
+
 	stop chan struct{}
 }
 
-func New(ip string, port int, log *slog.Logger, service pb.PubSubServer, stop chan struct{}) *App {
-	gRPCServer := grpc.NewServer(
-		grpc.ChainUnaryInterceptor(logger.NewUnary(log)),
-		grpc.ChainStreamInterceptor(logger.NewStream(log)),
+// Options - необязательные части сервера, nil - выключено
+type Options struct {
+	TLS     *tls.Config
+	Metrics metrics.Recorder
+	Tracer  trace.TracerProvider
+	Policy  *auth.Policy // проверка доступа к subject, только вместе с TLS или токенами
+	Limiter *ratelimit.Limiter
+
+	Admin      pbadmin.AdminServer
+	Reflection bool // server reflection для grpcurl без .proto
+}
+
+// services - имена сервисов для grpc.health.v1, пустое имя - сервер целиком
+var services = []string{
+	"",
+	pb.PubSub_ServiceDesc.ServiceName,
+	pbv2.PubSub_ServiceDesc.ServiceName,
+}
+
+func New(ip string, port int, log *slog.Logger, service pb.PubSubServer, serviceV2 pbv2.PubSubServer, opt Options, stop chan struct{}) *App {
+	var (
+		unary  []grpc.UnaryServerInterceptor
+		stream []grpc.StreamServerInterceptor
 	)
+
+	// Span открывается первым, чтобы покрыть остальные interceptor
+	if opt.Tracer != nil {
+		unary = append(unary, tracing.NewUnary(opt.Tracer))
+		stream = append(stream, tracing.NewStream(opt.Tracer))
+	}
+
+	unary = append(unary, logger.NewUnary(log))
+	stream = append(stream, logger.NewStream(log))
+
+	if opt.Metrics != nil {
+		unary = append(unary, metrics.NewUnary(opt.Metrics))
+		stream = append(stream, metrics.NewStream(opt.Metrics))
+	}
+
+	var opts []grpc.ServerOption
+
+	if opt.TLS != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(opt.TLS)))
+
+		unary = append(unary, identity.NewUnary())
+		stream = append(stream, identity.NewStream())
+	}
+
+	// После logger (requestID) и identity (mTLS principal)
+	if opt.Policy != nil {
+		unary = append(unary, auth.NewUnary(opt.Policy, log))
+		stream = append(stream, auth.NewStream(opt.Policy, log))
+	}
+
+	// После auth: лимиты считаются по principal
+	if opt.Limiter != nil {
+		unary = append(unary, ratelimit.NewUnary(opt.Limiter, log))
+		stream = append(stream, ratelimit.NewStream(opt.Limiter, log))
+	}
+
+	opts = append(opts,
+		grpc.ChainUnaryInterceptor(unary...),
+		grpc.ChainStreamInterceptor(stream...),
+	)
+
+	gRPCServer := grpc.NewServer(opts...)
 	pb.RegisterPubSubServer(gRPCServer, service)
+	pbv2.RegisterPubSubServer(gRPCServer, serviceV2)
+
+	names := slices.Clone(services)
+
+	if opt.Admin != nil {
+		pbadmin.RegisterAdminServer(gRPCServer, opt.Admin)
+		names = append(names, pbadmin.Admin_ServiceDesc.ServiceName)
+	}
+
+	// NOT_SERVING до Start
+	healthServer := health.NewServer()
+	for _, name := range names {
+		healthServer.SetServingStatus(name, healthpb.HealthCheckResponse_NOT_SERVING)
+	}
+	healthpb.RegisterHealthServer(gRPCServer, healthServer)
+
+	if opt.Reflection {
+		reflection.Register(gRPCServer)
+	}
 
 	return &App{
 		gRPCServer: gRPCServer,
+		health:     healthServer,
+		services:   names,
+		unary:      unary,
+		stream:     stream,
 		addr:       fmt.Sprintf("%s:%d", ip, port),
 		log:        log,
 		stop:       stop,
 	}
 }
 
+/*
+Interceptors
+
+Цепочки interceptor сервера в порядке вызова: для обработчиков,
+вызываемых не через gRPC (HTTP gateway), с теми же логами,
+метриками, авторизацией и лимитами.
+*/
+func (app *App) Interceptors() ([]grpc.UnaryServerInterceptor, []grpc.StreamServerInterceptor) {
+	return app.unary, app.stream
+}
+
 func (app *App) Start() error {
 	l, err := net.Listen("tcp", app.addr)
 	if err != nil {
@@ -42,6 +156,10 @@ func (app *App) Start() error {
 
 	app.log.Info("Net listen tcp", slog.String("addr", l.Addr().String()))
 
+	for _, name := range app.services {
+		app.health.SetServingStatus(name, healthpb.HealthCheckResponse_SERVING)
+	}
+
 	if err := app.gRPCServer.Serve(l); err != nil {
 		return e.Wrap("gRPC server serve failed", err)
 	}
@@ -57,6 +175,9 @@ func (app *App) Stop() {
 		}
 	default:
 
+		// NOT_SERVING до начала drain, чтобы балансировщик перестал слать запросы
+		app.health.Shutdown()
+
 		close(app.stop)
 
 		app.gRPCServer.GracefulStop()
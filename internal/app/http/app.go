@@ -0,0 +1,100 @@
+package httpapp
+
+import (
+	"VK_task/internal/pkg/logger/sl"
+	"VK_task/pkg/e"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+)
+
+const shutdownTimeout = 5 * time.Second
+
+/*
+App - HTTP сервер gateway
+
+Контекст запросов отменяется в начале Stop:
+SSE потоки и WebSocket соединения завершаются, не дожидаясь shutdownTimeout.
+С TLS конфигурацией - HTTPS с той же проверкой клиентских сертификатов, что и у gRPC.
+*/
+type App struct {
+	httpServer *http.Server
+	handler    Handler
+	addr       string
+	tls        bool
+	log        *slog.Logger
+
+	cancel context.CancelFunc
+}
+
+// Handler - обработчик с соединениями вне http.Server (WebSocket после upgrade)
+type Handler interface {
+	http.Handler
+	Wait(ctx context.Context) error
+}
+
+// tlsConfig - nil для HTTP без TLS
+func New(ip string, port int, log *slog.Logger, handler Handler, tlsConfig *tls.Config) *App {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &App{
+		httpServer: &http.Server{
+			Handler:           handler,
+			TLSConfig:         tlsConfig,
+			ReadHeaderTimeout: 5 * time.Second,
+			ErrorLog:          slog.NewLogLogger(log.Handler(), slog.LevelWarn), // ошибки TLS handshake
+			BaseContext:       func(net.Listener) context.Context { return ctx },
+		},
+		handler: handler,
+		addr:    fmt.Sprintf("%s:%d", ip, port),
+		tls:     tlsConfig != nil,
+		log:     log,
+		cancel:  cancel,
+	}
+}
+
+// Start открывает порт и обслуживает запросы в отдельной горутине
+func (app *App) Start() error {
+	l, err := net.Listen("tcp", app.addr)
+	if err != nil {
+		return e.Wrap("net listen failed", err)
+	}
+
+	app.log.Info("HTTP gateway listen tcp", slog.String("addr", l.Addr().String()), slog.Bool("tls", app.tls))
+
+	go func() {
+		serve := app.httpServer.Serve
+		if app.tls {
+			// Сертификаты уже в TLSConfig
+			serve = func(l net.Listener) error { return app.httpServer.ServeTLS(l, "", "") }
+		}
+
+		if err := serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			app.log.Error("HTTP gateway serve failed", sl.Err(err))
+		}
+	}()
+
+	return nil
+}
+
+func (app *App) Stop() error {
+	app.cancel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := app.httpServer.Shutdown(ctx); err != nil {
+		return e.Wrap("HTTP gateway shutdown failed", err)
+	}
+
+	if err := app.handler.Wait(ctx); err != nil {
+		return e.Wrap("HTTP gateway connections close failed", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package metricsapp
+
+import (
+	"VK_task/internal/pkg/logger/sl"
+	"VK_task/pkg/e"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+)
+
+const shutdownTimeout = 5 * time.Second
+
+// App - HTTP сервер с эндпоинтом метрик
+type App struct {
+	httpServer *http.Server
+	addr       string
+	log        *slog.Logger
+}
+
+func New(ip string, port int, path string, log *slog.Logger, handler http.Handler) *App {
+	mux := http.NewServeMux()
+	mux.Handle(path, handler)
+
+	return &App{
+		httpServer: &http.Server{
+			Handler:           mux,
+			ReadHeaderTimeout: 5 * time.Second,
+		},
+		addr: fmt.Sprintf("%s:%d", ip, port),
+		log:  log,
+	}
+}
+
+// Start открывает порт и обслуживает запросы в отдельной горутине
+func (app *App) Start() error {
+	l, err := net.Listen("tcp", app.addr)
+	if err != nil {
+		return e.Wrap("net listen failed", err)
+	}
+
+	app.log.Info("Metrics listen tcp", slog.String("addr", l.Addr().String()))
+
+	go func() {
+		if err := app.httpServer.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			app.log.Error("Metrics server serve failed", sl.Err(err))
+		}
+	}()
+
+	return nil
+}
+
+func (app *App) Stop() error {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := app.httpServer.Shutdown(ctx); err != nil {
+		return e.Wrap("metrics server shutdown failed", err)
+	}
+
+	return nil
+}
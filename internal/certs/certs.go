@@ -0,0 +1,200 @@
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"log/slog"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"VK_task/internal/pkg/logger/sl"
+	"VK_task/pkg/e"
+)
+
+const defaultReloadInterval = 10 * time.Second
+
+var ErrNoClientCA = errors.New("no certificates in client CA file")
+
+type Config struct {
+	CertFile          string
+	KeyFile           string
+	ClientCAFile      string        // пусто - клиентские сертификаты не проверяются
+	RequireClientCert bool          // mTLS: соединение без сертификата клиента отклоняется
+	ReloadInterval    time.Duration // период проверки файлов, <= 0 - 10s
+}
+
+/*
+Reloader
+
+TLS конфигурация сервера, которая перечитывает сертификат, ключ
+и CA клиентов при изменении файлов, без перезапуска сервера.
+Новые файлы применяются к новым соединениям, открытые не рвутся.
+*/
+type Reloader struct {
+	cfg Config
+	log *slog.Logger
+
+	current atomic.Pointer[tls.Config]
+	mtimes  map[string]time.Time // время изменения файлов последней успешной загрузки
+
+	stop chan struct{}
+	once sync.Once
+}
+
+// New загружает файлы сразу, ошибка - если сертификат или CA не читаются
+func New(cfg Config, log *slog.Logger) (*Reloader, error) {
+	if cfg.ReloadInterval <= 0 {
+		cfg.ReloadInterval = defaultReloadInterval
+	}
+
+	r := &Reloader{
+		cfg:  cfg,
+		log:  log,
+		stop: make(chan struct{}),
+	}
+
+	mtimes, err := r.modTimes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	r.mtimes = mtimes
+
+	return r, nil
+}
+
+/*
+TLSConfig
+
+Конфигурация для credentials.NewTLS: на каждое соединение
+отдаётся последняя успешно загруженная.
+*/
+func (r *Reloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return r.current.Load(), nil
+		},
+	}
+}
+
+// Start запускает проверку файлов в отдельной горутине
+func (r *Reloader) Start() {
+	go r.watch()
+}
+
+func (r *Reloader) Stop() {
+	r.once.Do(func() {
+		close(r.stop)
+	})
+}
+
+func (r *Reloader) watch() {
+	ticker := time.NewTicker(r.cfg.ReloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			r.reloadIfChanged()
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+/*
+reloadIfChanged
+
+При ошибке остаётся прежняя конфигурация, а время изменения
+не запоминается: файлы могут быть записаны не все сразу,
+загрузка повторится на следующей проверке.
+*/
+func (r *Reloader) reloadIfChanged() {
+	mtimes, err := r.modTimes()
+	if err != nil {
+		r.log.Warn("TLS files stat failed", sl.Err(err))
+		return
+	}
+
+	if !r.changed(mtimes) {
+		return
+	}
+
+	if err := r.load(); err != nil {
+		r.log.Warn("TLS reload failed, previous certificate kept", sl.Err(err))
+		return
+	}
+	r.mtimes = mtimes
+
+	r.log.Info("TLS certificate reloaded", slog.String("cert", r.cfg.CertFile))
+}
+
+func (r *Reloader) changed(mtimes map[string]time.Time) bool {
+	for path, mtime := range mtimes {
+		if !mtime.Equal(r.mtimes[path]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *Reloader) modTimes() (map[string]time.Time, error) {
+	mtimes := make(map[string]time.Time, 3)
+
+	for _, path := range []string{r.cfg.CertFile, r.cfg.KeyFile, r.cfg.ClientCAFile} {
+		if path == "" {
+			continue
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, e.Wrap("stat failed", err)
+		}
+		mtimes[path] = info.ModTime()
+	}
+
+	return mtimes, nil
+}
+
+func (r *Reloader) load() error {
+	cert, err := tls.LoadX509KeyPair(r.cfg.CertFile, r.cfg.KeyFile)
+	if err != nil {
+		return e.Wrap("certificate load failed", err)
+	}
+
+	cfg := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+		NextProtos:   []string{"h2"}, // GetConfigForClient заменяет конфигурацию gRPC целиком
+	}
+
+	if r.cfg.ClientCAFile != "" {
+		pem, err := os.ReadFile(r.cfg.ClientCAFile)
+		if err != nil {
+			return e.Wrap("client CA read failed", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return ErrNoClientCA
+		}
+
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = tls.VerifyClientCertIfGiven
+		if r.cfg.RequireClientCert {
+			cfg.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+	}
+
+	r.current.Store(cfg)
+
+	return nil
+}
@@ -10,9 +10,12 @@ import (
 )
 
 type Config struct {
-	SLOG   SLOG   `yaml:"slog"`
-	GRPC   GRPC   `yaml:"grpc"`
-	SubPub SubPub `yaml:"sub_pub"`
+	SLOG    SLOG    `yaml:"slog"`
+	GRPC    GRPC    `yaml:"grpc"`
+	SubPub  SubPub  `yaml:"sub_pub"`
+	Metrics Metrics `yaml:"metrics"`
+	Tracing Tracing `yaml:"tracing"`
+	Gateway Gateway `yaml:"gateway"`
 }
 
 type SLOG struct {
@@ -23,12 +26,90 @@ type SLOG struct {
 type GRPC struct {
 	Addr string `yaml:"addr"`
 	Port int    `yaml:"port"`
+	TLS  TLS    `yaml:"tls"`
+	Auth Auth   `yaml:"auth"`
+
+	RateLimit  RateLimit `yaml:"rate_limit"`
+	Admin      Admin     `yaml:"admin"`
+	Reflection bool      `yaml:"reflection"`
+}
+
+type Admin struct {
+	Enabled bool `yaml:"enabled"`
+}
+
+type Auth struct {
+	Enabled    bool   `yaml:"enabled"`
+	PolicyFile string `yaml:"policy_file"`
+}
+
+type TLS struct {
+	Enabled           bool          `yaml:"enabled"`
+	CertFile          string        `yaml:"cert_file"`
+	KeyFile           string        `yaml:"key_file"`
+	ClientCAFile      string        `yaml:"client_ca_file"`
+	RequireClientCert bool          `yaml:"require_client_cert"`
+	ReloadInterval    time.Duration `yaml:"reload_interval"`
+}
+
+type Metrics struct {
+	Enabled bool   `yaml:"enabled"`
+	Addr    string `yaml:"addr"`
+	Port    int    `yaml:"port"`
+	Path    string `yaml:"path"`
+}
+
+type Gateway struct {
+	Enabled        bool     `yaml:"enabled"`
+	Addr           string   `yaml:"addr"`
+	Port           int      `yaml:"port"`
+	AllowedOrigins []string `yaml:"allowed_origins"`
+}
+
+type Tracing struct {
+	Enabled     bool    `yaml:"enabled"`
+	Exporter    string  `yaml:"exporter"`
+	Endpoint    string  `yaml:"endpoint"`
+	ServiceName string  `yaml:"service_name"`
+	SampleRatio float64 `yaml:"sample_ratio"`
+}
+
+type RateLimit struct {
+	Enabled          bool          `yaml:"enabled"`
+	Client           RateLimits    `yaml:"client"`
+	Subject          RateLimits    `yaml:"subject"`
+	MaxSubscriptions int           `yaml:"max_subscriptions"`
+	IdleTimeout      time.Duration `yaml:"idle_timeout"`
+}
+
+type RateLimits struct {
+	PublishRate  float64 `yaml:"publish_rate"`
+	PublishBurst int     `yaml:"publish_burst"`
+	BytesRate    float64 `yaml:"bytes_rate"`
+	BytesBurst   int     `yaml:"bytes_burst"`
 }
 
 type SubPub struct {
-	SubjectBuffer      int           `yaml:"subject_buffer"`
-	SubscriptionBuffer int           `yaml:"subscription_buffer"`
-	CloseTimeout       time.Duration `yaml:"close_timeout"`
+	SubjectBuffer      int                      `yaml:"subject_buffer"`
+	SubscriptionBuffer int                      `yaml:"subscription_buffer"`
+	CloseTimeout       time.Duration            `yaml:"close_timeout"`
+	NoSubscribers      string                   `yaml:"no_subscribers"`
+	PendingBuffer      int                      `yaml:"pending_buffer"`
+	Overflow           string                   `yaml:"overflow"`
+	BlockTimeout       time.Duration            `yaml:"block_timeout"`
+	AckTimeout         time.Duration            `yaml:"ack_timeout"`
+	MaxAttempts        int                      `yaml:"max_attempts"`
+	DeadLetterPrefix   string                   `yaml:"dead_letter_prefix"`
+	MessageTTL         time.Duration            `yaml:"message_ttl"`
+	SubjectTTL         map[string]time.Duration `yaml:"subject_ttl"`
+	Log                SubPubLog                `yaml:"log"`
+}
+
+type SubPubLog struct {
+	Dir          string `yaml:"dir"`
+	SegmentSize  int64  `yaml:"segment_size"`
+	Sync         bool   `yaml:"sync"`
+	MaxOpenFiles int    `yaml:"max_open_files"`
 }
 
 func MustLoad(path string) *Config {
@@ -0,0 +1,152 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"VK_task/internal/grpc/middleware/logger"
+	"VK_task/internal/pkg/logger/sl"
+	pbadmin "VK_task/pkg/api/pubsub/admin"
+	sp "VK_task/pkg/subpub"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+/*
+Service
+
+pubsub.admin.Admin: состояние subject и подписок по SubPub.Stats,
+принудительное закрытие подписок и drain subject.
+*/
+type Service struct {
+	pbadmin.UnimplementedAdminServer
+	ps  sp.SubPub
+	log *slog.Logger
+}
+
+func New(ps sp.SubPub, log *slog.Logger) *Service {
+	return &Service{
+		ps:  ps,
+		log: log,
+	}
+}
+
+func (s *Service) ListSubjects(ctx context.Context, req *pbadmin.ListSubjectsRequest) (*pbadmin.ListSubjectsResponse, error) {
+	stats := s.ps.Stats()
+
+	resp := &pbadmin.ListSubjectsResponse{
+		Subjects: make([]*pbadmin.Subject, 0, len(stats.Subjects)),
+	}
+
+	for _, subj := range stats.Subjects {
+		resp.Subjects = append(resp.Subjects, &pbadmin.Subject{
+			Key:           subj.Subject,
+			Subscribers:   uint32(subj.Subscribers),
+			Groups:        uint32(subj.Groups),
+			QueueDepth:    uint32(subj.QueueDepth),
+			QueueCapacity: uint32(subj.QueueCapacity),
+		})
+	}
+
+	return resp, nil
+}
+
+func (s *Service) ListSubscriptions(ctx context.Context, req *pbadmin.ListSubscriptionsRequest) (*pbadmin.ListSubscriptionsResponse, error) {
+	resp := &pbadmin.ListSubscriptionsResponse{}
+
+	for _, subj := range s.ps.Stats().Subjects {
+		if req.Key != "" && subj.Subject != req.Key {
+			continue
+		}
+
+		for _, sub := range subj.Subscriptions {
+			resp.Subscriptions = append(resp.Subscriptions, &pbadmin.Subscription{
+				Id:            sub.ID,
+				Key:           sub.Subject,
+				Group:         sub.Group,
+				Peer:          sub.Peer,
+				Ack:           sub.Ack,
+				CreatedAt:     timestamppb.New(sub.CreatedAt),
+				QueueDepth:    uint32(sub.QueueDepth),
+				QueueCapacity: uint32(sub.QueueCapacity),
+				Delivered:     sub.Delivered,
+				Dropped:       sub.Dropped,
+				Unacked:       uint32(sub.Unacked),
+			})
+		}
+	}
+
+	return resp, nil
+}
+
+func (s *Service) CloseSubscription(ctx context.Context, req *pbadmin.CloseSubscriptionRequest) (*pbadmin.CloseSubscriptionResponse, error) {
+	log := s.log.With(
+		slog.String("requestID", logger.GetRequestID(ctx)),
+		slog.String("id", req.Id),
+	)
+
+	if req.Id == "" {
+		log.Warn("Req.Id is empty")
+
+		return nil, status.Error(codes.InvalidArgument, "id required")
+	}
+
+	if err := s.ps.CloseSubscription(req.Id); err != nil {
+		return nil, adminError(log, err)
+	}
+
+	log.Info("Subscription closed by administrator")
+
+	return &pbadmin.CloseSubscriptionResponse{}, nil
+}
+
+func (s *Service) DrainSubject(ctx context.Context, req *pbadmin.DrainSubjectRequest) (*pbadmin.DrainSubjectResponse, error) {
+	log := s.log.With(
+		slog.String("requestID", logger.GetRequestID(ctx)),
+		slog.String("subject", req.Key),
+	)
+
+	if req.Key == "" {
+		log.Warn("Req.Key is empty")
+
+		return nil, status.Error(codes.InvalidArgument, "key required")
+	}
+
+	closed, err := s.ps.DrainSubject(ctx, req.Key)
+	if err != nil {
+		return nil, adminError(log, err)
+	}
+
+	log.Info("Subject drained", slog.Int("closed", closed))
+
+	return &pbadmin.DrainSubjectResponse{
+		Closed: uint32(closed),
+	}, nil
+}
+
+func adminError(log *slog.Logger, err error) error {
+	switch {
+	case errors.Is(err, sp.ErrNoSuchSubscription):
+		log.Warn("SubPub no such subscription")
+
+		return status.Error(codes.NotFound, "no such subscription")
+
+	case errors.Is(err, sp.ErrNoSuchSubject):
+		log.Warn("SubPub no such subject")
+
+		return status.Error(codes.NotFound, "no such subject")
+
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+		// Подписки уже закрыты, не все сообщения доставлены
+		log.Warn("Drain interrupted", sl.Err(err))
+
+		return status.FromContextError(err).Err()
+	}
+
+	log.Error("SubPub admin operation failed", sl.Err(err))
+
+	return status.Error(codes.Internal, "admin operation failed")
+}
@@ -0,0 +1,135 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+
+	"VK_task/internal/grpc/middleware/logger"
+	"VK_task/internal/pkg/logger/sl"
+	pb "VK_task/pkg/api/pubsub"
+	sp "VK_task/pkg/subpub"
+
+	"google.golang.org/grpc/status"
+)
+
+// streamBatchSize - сколько сообщений PublishStream публикует за один PublishBatch
+const streamBatchSize = 256
+
+func (s *Service) PublishBatch(ctx context.Context, req *pb.PublishBatchRequest) (*pb.PublishBatchResponse, error) {
+	log := s.log.With(
+		slog.String("requestID", logger.GetRequestID(ctx)),
+	)
+
+	log.Debug("Batch size", slog.Int("messages", len(req.Messages)))
+
+	if err := ctx.Err(); err != nil {
+		log.Error("Request context is done")
+
+		return nil, status.FromContextError(err).Err()
+	}
+
+	return &pb.PublishBatchResponse{
+		Results: s.publishBatch(ctx, log, req.Messages),
+	}, nil
+}
+
+/*
+PublishStream
+
+Сообщения потока публикуются пакетами по streamBatchSize,
+результаты по всем сообщениям возвращаются после закрытия потока клиентом.
+*/
+func (s *Service) PublishStream(stream pb.PubSub_PublishStreamServer) error {
+	log := s.log.With(
+		slog.String("requestID", logger.GetRequestID(stream.Context())),
+	)
+
+	var (
+		results []*pb.PublishResult
+		batch   = make([]*pb.PublishRequest, 0, streamBatchSize)
+	)
+
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		batch = append(batch, req)
+		if len(batch) == streamBatchSize {
+			results = append(results, s.publishBatch(stream.Context(), log, batch)...)
+			batch = batch[:0]
+		}
+	}
+
+	if len(batch) > 0 {
+		results = append(results, s.publishBatch(stream.Context(), log, batch)...)
+	}
+
+	log.Debug("Stream published", slog.Int("messages", len(results)))
+
+	return stream.SendAndClose(&pb.PublishBatchResponse{
+		Results: results,
+	})
+}
+
+func (s *Service) publishBatch(ctx context.Context, log *slog.Logger, reqs []*pb.PublishRequest) []*pb.PublishResult {
+	headers := sp.WithTraceContext(ctx, nil)
+	results := make([]*pb.PublishResult, len(reqs))
+
+	msgs := make([]*sp.Message, 0, len(reqs))
+	index := make([]int, 0, len(reqs)) // позиция msgs[i] в reqs
+
+	for i, req := range reqs {
+		ttl, ttlErr := messageTTL(req.Ttl)
+
+		switch {
+		case req.Key == "":
+			results[i] = &pb.PublishResult{Status: pb.PublishStatus_PUBLISH_INVALID, Error: "key required"}
+		case req.Data == "" && !req.Retain:
+			results[i] = &pb.PublishResult{Status: pb.PublishStatus_PUBLISH_INVALID, Error: "data required"}
+		case ttlErr != nil:
+			results[i] = &pb.PublishResult{Status: pb.PublishStatus_PUBLISH_INVALID, Error: "invalid ttl"}
+		default:
+			msgs = append(msgs, &sp.Message{Subject: req.Key, Headers: headers, Data: req.Data, TTL: ttl, Retain: req.Retain})
+			index = append(index, i)
+		}
+	}
+
+	for i, res := range s.ps.PublishBatch(msgs) {
+		results[index[i]] = publishResult(log, msgs[i].Subject, res)
+	}
+
+	return results
+}
+
+func publishResult(log *slog.Logger, key string, res sp.PublishResult) *pb.PublishResult {
+	switch {
+	case res.Err == nil:
+		return &pb.PublishResult{Status: pb.PublishStatus_PUBLISH_OK, Delivered: uint32(res.Delivered)}
+
+	case errors.Is(res.Err, sp.ErrNoSuchSubject):
+		return &pb.PublishResult{Status: pb.PublishStatus_PUBLISH_NO_SUBSCRIBERS, Error: "no such subject"}
+
+	case errors.Is(res.Err, sp.ErrInvalidSubject):
+		return &pb.PublishResult{Status: pb.PublishStatus_PUBLISH_INVALID, Error: "invalid key"}
+
+	case errors.Is(res.Err, sp.ErrInvalidArgument),
+		errors.Is(res.Err, sp.ErrUnsupportedMessage),
+		errors.Is(res.Err, sp.ErrMessageTooLarge):
+		return &pb.PublishResult{Status: pb.PublishStatus_PUBLISH_INVALID, Error: res.Err.Error()}
+
+	case errors.Is(res.Err, sp.ErrSubPubClosed):
+		return &pb.PublishResult{Status: pb.PublishStatus_PUBLISH_CLOSED, Error: "Sub/Pub closed"}
+
+	default:
+		log.Error("SubPub Publish operation failed", sl.Err(res.Err), slog.String("subject", key))
+
+		return &pb.PublishResult{Status: pb.PublishStatus_PUBLISH_FAILED, Error: "failed to publish"}
+	}
+}
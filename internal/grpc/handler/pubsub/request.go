@@ -0,0 +1,96 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"VK_task/internal/grpc/middleware/logger"
+	"VK_task/internal/pkg/logger/sl"
+	pb "VK_task/pkg/api/pubsub"
+	sp "VK_task/pkg/subpub"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+/*
+Request
+
+Публикация в req.Key с временным subject для ответа и ожидание первого
+ответа до дедлайна вызова. Отвечающий получает subject в Event.ReplyTo
+и публикует ответ в него обычным Publish.
+*/
+func (s *Service) Request(ctx context.Context, req *pb.RequestMessage) (*pb.ReplyMessage, error) {
+	log := s.log.With(
+		slog.String("requestID", logger.GetRequestID(ctx)),
+	)
+
+	log.Debug("Request data",
+		slog.String("key", req.Key),
+		slog.String("data", req.Data),
+	)
+
+	if req.Key == "" {
+		log.Warn("Req.Key is empty")
+
+		return nil, status.Error(codes.InvalidArgument, "key required")
+	}
+	if req.Data == "" {
+		log.Warn("Req.Data is empty")
+
+		return nil, status.Error(codes.InvalidArgument, "data required")
+	}
+
+	// Ожидание ответа прерывается остановкой сервера
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go func() {
+		select {
+		case <-s.srvStop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	reply, err := s.ps.RequestMsg(ctx, &sp.Message{
+		Subject: req.Key,
+		Headers: sp.WithTraceContext(ctx, nil),
+		Data:    req.Data,
+	})
+	if err != nil {
+		return nil, s.requestError(log, req.Key, err)
+	}
+
+	data, ok := eventData(reply.Data)
+	if !ok {
+		log.Warn("Reply data is not a string", slog.String("subject", req.Key))
+
+		return nil, status.Error(codes.Internal, "reply data is not a string")
+	}
+
+	return &pb.ReplyMessage{Data: data}, nil
+}
+
+func (s *Service) requestError(log *slog.Logger, key string, err error) error {
+	if errors.Is(err, sp.ErrNoResponders) {
+		log.Warn("SubPub no responders", slog.String("subject", key))
+
+		return status.Error(codes.Unavailable, "no responders")
+	}
+
+	select {
+	case <-s.srvStop:
+		return status.Error(codes.Canceled, "Server stopping")
+	default:
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		log.Warn("SubPub Request interrupted by request context", sl.Err(err), slog.String("subject", key))
+
+		return status.FromContextError(err).Err()
+	}
+
+	return publishError(log, key, err)
+}
@@ -4,16 +4,23 @@ import (
 	"context"
 	"errors"
 	"log/slog"
+	"time"
+	"unicode/utf8"
 
 	"VK_task/internal/grpc/middleware/logger"
+	"VK_task/internal/grpc/middleware/ratelimit"
 	"VK_task/internal/pkg/logger/sl"
 	pb "VK_task/pkg/api/pubsub"
 	"VK_task/pkg/e"
 	sp "VK_task/pkg/subpub"
 
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
-	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 type Service struct {
@@ -33,65 +40,182 @@ func New(ps sp.SubPub, log *slog.Logger, stop <-chan struct{}) *Service {
 }
 
 func (s *Service) Subscribe(req *pb.SubscribeRequest, stream pb.PubSub_SubscribeServer) error {
-	log := s.log.With(
+	return subscribe(s.ps, s.log, s.srvStop, req, stream, newEvent)
+}
+
+// eventStream - поток Subscribe v1 и v2
+type eventStream[E any] interface {
+	Context() context.Context
+	Send(E) error
+	SendHeader(metadata.MD) error
+}
+
+/*
+subscribe
+
+Общая часть Subscribe v1 и v2, версии отличаются только типом Event.
+newEvent - Event для отправки, false - данные этой версии не передать,
+сообщение пропускается.
+*/
+func subscribe[E any](
+	ps sp.SubPub,
+	log *slog.Logger,
+	srvStop <-chan struct{},
+	req subscribeRequest,
+	stream eventStream[E],
+	newEvent func(*sp.Message) (E, bool),
+) error {
+	log = log.With(
 		slog.String("requestID", logger.GetRequestID(stream.Context())),
 	)
 
-	log.Debug("Conn data", slog.String("key", req.Key))
+	log.Debug("Conn data", slog.String("key", req.GetKey()))
 
-	if req.Key == "" {
+	if req.GetKey() == "" {
 		log.Warn("Req.Key is empty")
 
 		return status.Error(codes.InvalidArgument, "key required")
 	}
 
-	errCh := make(chan error)
-	defer close(errCh)
+	sendErrCh := make(chan error, 1)
 
 	handler := func(msg interface{}) {
-		data, ok := msg.(string)
+		m, ok := msg.(*sp.Message)
 		if !ok {
 			return
 		}
 
-		event := &pb.Event{
-			Data: data,
+		event, ok := newEvent(m)
+		if !ok {
+			return
 		}
 
 		if err := stream.Send(event); err != nil {
-			errCh <- err
+			select {
+			case sendErrCh <- err:
+			default:
+			}
 		}
 	}
 
-	sub, err := s.ps.Subscribe(req.Key, handler)
+	release, err := ratelimit.AcquireSubscription(stream.Context())
 	if err != nil {
-		log.Error("SubPub Subscribe operation failed", sl.Err(err))
+		return err
+	}
+	defer release()
 
-		return status.Error(codes.Internal, "failed to subscribe")
+	sub, err := ps.Subscribe(req.GetKey(), handler, subscribeOptions(stream.Context(), req)...)
+	if err != nil {
+		return subscribeError(log, req.GetKey(), err)
 	}
 	defer sub.Unsubscribe()
 
+	// Заголовки ответа - подписка создана: ошибки выше клиент получает без них.
+	// Ошибка - заголовки уже ушли с первым событием или поток разорван, об этом сообщит Send
+	_ = stream.SendHeader(nil)
+
 	select {
-	case err := <-errCh:
+	case err := <-sendErrCh:
 		log.Error("Send event to stream failed", sl.Err(err))
 
 		return status.Error(codes.Unavailable, e.String("failed to send event", err))
 
 	case <-stream.Context().Done():
-		// log.Info("Stream context is done")
-
 		return status.FromContextError(stream.Context().Err()).Err()
 
-	case <-s.srvStop:
+	case <-sub.Done():
+		return subscriptionClosed(log, sub)
+
+	case <-srvStop:
 		return status.Error(codes.Canceled, "Server stopping")
 	}
 }
 
-func (s *Service) Publish(ctx context.Context, req *pb.PublishRequest) (*emptypb.Empty, error) {
+func subscribeError(log *slog.Logger, key string, err error) error {
+	if errors.Is(err, sp.ErrInvalidSubject) {
+		log.Warn("SubPub invalid subject", slog.String("subject", key))
+
+		return status.Error(codes.InvalidArgument, "invalid key")
+	}
+	if errors.Is(err, sp.ErrLogDisabled) {
+		log.Warn("SubPub durable log is disabled")
+
+		return status.Error(codes.FailedPrecondition, "durable log is disabled")
+	}
+
+	log.Error("SubPub Subscribe operation failed", sl.Err(err))
+
+	return status.Error(codes.Internal, "failed to subscribe")
+}
+
+// subscriptionClosed - статус для подписки, закрытой со стороны subPub
+func subscriptionClosed(log *slog.Logger, sub sp.Subscription) error {
+	if errors.Is(sub.Err(), sp.ErrSlowConsumer) {
+		log.Warn("Subscription disconnected as slow consumer")
+
+		return status.Error(codes.ResourceExhausted, "slow consumer: subscription queue is full")
+	}
+	if errors.Is(sub.Err(), sp.ErrSubscriptionClosed) || errors.Is(sub.Err(), sp.ErrSubjectDrained) {
+		log.Info("Subscription closed by administrator", sl.Err(sub.Err()))
+
+		return status.Error(codes.Aborted, sub.Err().Error())
+	}
+
+	return status.Error(codes.Canceled, "Subscription closed")
+}
+
+// peerAddr - адрес клиента для статистики подписки
+func peerAddr(ctx context.Context) string {
+	if p, ok := peer.FromContext(ctx); ok {
+		return p.Addr.String()
+	}
+
+	return ""
+}
+
+// subscribeRequest - SubscribeRequest v1 и v2
+type subscribeRequest interface {
+	proto.Message
+	GetKey() string
+	GetGroup() string
+	GetStartOffset() uint64
+	GetStartEarliest() bool
+	GetStartTime() *timestamppb.Timestamp
+}
+
+// subscribeOptions - опции подписки по запросу v1 или v2
+func subscribeOptions(ctx context.Context, req subscribeRequest) []sp.SubscribeOption {
+	opts := []sp.SubscribeOption{sp.WithEnvelope(), sp.WithPeer(peerAddr(ctx))}
+
+	// Смещение 0 отличается от start, не заданного вовсе, только по наличию поля
+	msg := req.ProtoReflect()
+
+	switch {
+	case msg.Has(msg.Descriptor().Fields().ByName("start_offset")):
+		opts = append(opts, sp.StartAtOffset(req.GetStartOffset()))
+	case req.GetStartEarliest():
+		opts = append(opts, sp.StartAtEarliest())
+	case req.GetStartTime() != nil:
+		opts = append(opts, sp.StartAtTime(req.GetStartTime().AsTime()))
+	}
+
+	if req.GetGroup() != "" {
+		opts = append(opts, sp.WithQueueGroup(req.GetGroup()))
+	}
+
+	return opts
+}
+
+func (s *Service) Publish(ctx context.Context, req *pb.PublishRequest) (*pb.PublishResponse, error) {
 	log := s.log.With(
 		slog.String("requestID", logger.GetRequestID(ctx)),
 	)
 
+	return s.publish(ctx, log, req)
+}
+
+// publish - общая часть Publish и publish-запроса в Session
+func (s *Service) publish(ctx context.Context, log *slog.Logger, req *pb.PublishRequest) (*pb.PublishResponse, error) {
 	log.Debug("Request data",
 		slog.String("key", req.Key),
 		slog.String("data", req.Data),
@@ -102,28 +226,108 @@ func (s *Service) Publish(ctx context.Context, req *pb.PublishRequest) (*emptypb
 
 		return nil, status.Error(codes.InvalidArgument, "key required")
 	}
-	if req.Data == "" {
+	// Пустая retained публикация удаляет сохранённое значение
+	if req.Data == "" && !req.Retain {
 		log.Warn("Req.Data is empty")
 
 		return nil, status.Error(codes.InvalidArgument, "data required")
 	}
 
+	ttl, err := messageTTL(req.Ttl)
+	if err != nil {
+		log.Warn("Req.Ttl is invalid")
+
+		return nil, err
+	}
+
 	if err := ctx.Err(); err != nil {
 		log.Error("Request context is done")
 
 		return nil, status.FromContextError(err).Err()
 	}
 
-	if err := s.ps.Publish(req.Key, req.Data); err != nil {
-		if errors.Is(err, sp.ErrNoSuchSubject) {
-			log.Warn("SubPub no such subject", slog.String("subject", req.Key))
+	// Заполненная очередь subject ждёт не дольше дедлайна запроса
+	delivered, err := s.ps.PublishMsgContext(ctx, &sp.Message{
+		Subject: req.Key,
+		Headers: sp.WithTraceContext(ctx, nil),
+		Data:    req.Data,
+		TTL:     ttl,
+		Retain:  req.Retain,
+	})
+	if err != nil {
+		return nil, publishError(log, req.Key, err)
+	}
+
+	return &pb.PublishResponse{
+		Delivered: uint32(delivered),
+	}, nil
+}
 
-			return nil, status.Error(codes.InvalidArgument, "no such subject")
+// messageTTL - ttl запроса, nil или 0 - TTL subject из конфигурации
+func messageTTL(ttl *durationpb.Duration) (time.Duration, error) {
+	if ttl == nil {
+		return 0, nil
+	}
+	if err := ttl.CheckValid(); err != nil || ttl.AsDuration() < 0 {
+		return 0, status.Error(codes.InvalidArgument, "invalid ttl")
+	}
+
+	return ttl.AsDuration(), nil
+}
+
+func publishError(log *slog.Logger, key string, err error) error {
+	if errors.Is(err, sp.ErrInvalidSubject) {
+		log.Warn("SubPub invalid subject", slog.String("subject", key))
+
+		return status.Error(codes.InvalidArgument, "invalid key")
+	}
+	if errors.Is(err, sp.ErrNoSuchSubject) {
+		log.Warn("SubPub no such subject", slog.String("subject", key))
+
+		return status.Error(codes.InvalidArgument, "no such subject")
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		log.Warn("SubPub Publish interrupted by request context", sl.Err(err), slog.String("subject", key))
+
+		return status.FromContextError(err).Err()
+	}
+
+	log.Error("SubPub Publish operation failed", sl.Err(err))
+
+	return status.Error(codes.Internal, "failed to publish")
+}
+
+/*
+eventData
+
+Данные для v1 Event (proto string): string или []byte в UTF-8,
+например опубликованные через v2. Остальное v1 клиенту не отправить.
+*/
+func eventData(data interface{}) (string, bool) {
+	switch v := data.(type) {
+	case string:
+		return v, true
+	case []byte:
+		if utf8.Valid(v) {
+			return string(v), true
 		}
+	}
 
-		log.Error("SubPub Publish operation failed", sl.Err(err))
+	return "", false
+}
 
-		return nil, status.Error(codes.Internal, "failed to publish")
+// newEvent - v1 Event сообщения, false - данные v1 клиенту не передать
+func newEvent(m *sp.Message) (*pb.Event, bool) {
+	data, ok := eventData(m.Data)
+	if !ok {
+		return nil, false
 	}
-	return &emptypb.Empty{}, nil
+
+	return &pb.Event{
+		Data:     data,
+		Offset:   m.Offset,
+		Key:      m.Subject,
+		Retained: m.Retain,
+		ReplyTo:  m.ReplyTo(),
+	}, true
 }
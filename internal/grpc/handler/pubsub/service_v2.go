@@ -0,0 +1,160 @@
+package pubsub
+
+import (
+	"context"
+	"log/slog"
+
+	"VK_task/internal/grpc/middleware/logger"
+	pbv2 "VK_task/pkg/api/pubsub/v2"
+	sp "VK_task/pkg/subpub"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+/*
+ServiceV2
+
+pubsub.v2.PubSub: бинарные данные, заголовки, content-type,
+идентификатор и время публикации. Работает поверх того же SubPub, что и v1.
+*/
+type ServiceV2 struct {
+	pbv2.UnimplementedPubSubServer
+	ps  sp.SubPub
+	log *slog.Logger
+
+	srvStop <-chan struct{}
+}
+
+func NewV2(ps sp.SubPub, log *slog.Logger, stop <-chan struct{}) *ServiceV2 {
+	return &ServiceV2{
+		ps:      ps,
+		log:     log,
+		srvStop: stop,
+	}
+}
+
+func (s *ServiceV2) Subscribe(req *pbv2.SubscribeRequest, stream pbv2.PubSub_SubscribeServer) error {
+	return subscribe(s.ps, s.log, s.srvStop, req, stream, newEventV2)
+}
+
+func (s *ServiceV2) SubscribeAck(stream pbv2.PubSub_SubscribeAckServer) error {
+	return subscribeAck(s.ps, s.log, s.srvStop, stream, func(d *sp.Delivery) (*pbv2.Event, bool) {
+		event, ok := newEventV2(d.Message)
+		if !ok {
+			return nil, false
+		}
+		event.Tag = d.Tag
+		event.Attempt = uint32(d.Attempt)
+
+		return event, true
+	})
+}
+
+func (s *ServiceV2) Publish(ctx context.Context, req *pbv2.PublishRequest) (*pbv2.PublishResponse, error) {
+	log := s.log.With(
+		slog.String("requestID", logger.GetRequestID(ctx)),
+	)
+
+	log.Debug("Request data",
+		slog.String("key", req.Key),
+		slog.Int("size", len(req.Data)),
+		slog.String("content_type", req.ContentType),
+	)
+
+	if req.Key == "" {
+		log.Warn("Req.Key is empty")
+
+		return nil, status.Error(codes.InvalidArgument, "key required")
+	}
+
+	ttl, err := messageTTL(req.Ttl)
+	if err != nil {
+		log.Warn("Req.Ttl is invalid")
+
+		return nil, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		log.Error("Request context is done")
+
+		return nil, status.FromContextError(err).Err()
+	}
+
+	msg := &sp.Message{
+		Subject: req.Key,
+		Headers: sp.WithTraceContext(ctx, publishHeadersV2(req)),
+		Data:    req.Data,
+		TTL:     ttl,
+		Retain:  req.Retain,
+	}
+	if msg.Data == nil {
+		msg.Data = []byte{}
+	}
+
+	delivered, err := s.ps.PublishMsgContext(ctx, msg)
+	if err != nil {
+		return nil, publishError(log, req.Key, err)
+	}
+
+	return &pbv2.PublishResponse{
+		Delivered: uint32(delivered),
+		Id:        msg.ID,
+		Timestamp: timestamppb.New(msg.Timestamp),
+		Offset:    msg.Offset,
+	}, nil
+}
+
+// publishHeadersV2 - заголовки сообщения, content_type хранится в sp.HeaderContentType
+func publishHeadersV2(req *pbv2.PublishRequest) map[string]string {
+	if len(req.Headers) == 0 && req.ContentType == "" {
+		return nil
+	}
+
+	headers := make(map[string]string, len(req.Headers)+1)
+	for k, v := range req.Headers {
+		headers[k] = v
+	}
+	if req.ContentType != "" {
+		headers[sp.HeaderContentType] = req.ContentType
+	}
+
+	return headers
+}
+
+// newEventV2 - false, если данные сообщения не string и не []byte
+func newEventV2(m *sp.Message) (*pbv2.Event, bool) {
+	var data []byte
+
+	switch v := m.Data.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return nil, false
+	}
+
+	event := &pbv2.Event{
+		Id:          m.ID,
+		Key:         m.Subject,
+		Data:        data,
+		ContentType: m.Headers[sp.HeaderContentType],
+		Timestamp:   timestamppb.New(m.Timestamp),
+		Offset:      m.Offset,
+		Retained:    m.Retain,
+	}
+
+	for k, v := range m.Headers {
+		if k == sp.HeaderContentType {
+			continue
+		}
+		if event.Headers == nil {
+			event.Headers = make(map[string]string, len(m.Headers))
+		}
+		event.Headers[k] = v
+	}
+
+	return event, true
+}
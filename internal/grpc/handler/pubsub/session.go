@@ -0,0 +1,360 @@
+package pubsub
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+
+	"VK_task/internal/grpc/middleware/logger"
+	"VK_task/internal/grpc/middleware/ratelimit"
+	"VK_task/internal/pkg/logger/sl"
+	pb "VK_task/pkg/api/pubsub"
+	"VK_task/pkg/e"
+	sp "VK_task/pkg/subpub"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+/*
+Session
+
+Мультиплексированный поток: клиент присылает subscribe, unsubscribe,
+publish и ack, сервер - события с sid подписки и SessionReply на запросы
+(на успешный ack ответа нет). Все подписки сессии закрываются вместе с потоком.
+*/
+func (s *Service) Session(stream pb.PubSub_SessionServer) error {
+	log := s.log.With(
+		slog.String("requestID", logger.GetRequestID(stream.Context())),
+	)
+
+	sess := &session{
+		svc:       s,
+		stream:    stream,
+		log:       log,
+		subs:      make(map[string]*sessionSub),
+		sendErrCh: make(chan error, 1),
+	}
+	defer sess.close()
+
+	recvErrCh := make(chan error, 1)
+	go func() {
+		for {
+			req, err := stream.Recv()
+			if err != nil {
+				recvErrCh <- err
+				return
+			}
+
+			sess.handle(req)
+		}
+	}()
+
+	select {
+	case err := <-sess.sendErrCh:
+		log.Error("Send to session stream failed", sl.Err(err))
+
+		return status.Error(codes.Unavailable, e.String("failed to send event", err))
+
+	case err := <-recvErrCh:
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		return err
+
+	case <-stream.Context().Done():
+		return status.FromContextError(stream.Context().Err()).Err()
+
+	case <-s.srvStop:
+		return status.Error(codes.Canceled, "Server stopping")
+	}
+}
+
+type session struct {
+	svc    *Service
+	stream pb.PubSub_SessionServer
+	log    *slog.Logger
+
+	subs map[string]*sessionSub // по sid
+	mu   sync.Mutex
+
+	// Send из обработчиков подписок и из цикла запросов
+	sendMu    sync.Mutex
+	closed    bool // после выхода из Session отправлять нельзя
+	sendErrCh chan error
+}
+
+type sessionSub struct {
+	sub     sp.Subscription
+	acks    sp.AckSubscription // nil - подписка без подтверждений
+	release func()             // место подписки в rate limit
+	ready   chan struct{}      // закрыт после ответа на subscribe, события ждут его
+}
+
+func (ss *session) handle(req *pb.SessionRequest) {
+	var (
+		delivered uint32
+		err       error
+	)
+
+	switch frame := req.Frame.(type) {
+	case *pb.SessionRequest_Subscribe:
+		var entry *sessionSub
+		if entry, err = ss.subscribe(frame.Subscribe); entry != nil {
+			// События и закрытие подписки - только после SessionReply на subscribe
+			defer close(entry.ready)
+		}
+
+	case *pb.SessionRequest_Unsubscribe:
+		err = ss.unsubscribe(frame.Unsubscribe.GetSid())
+
+	case *pb.SessionRequest_Publish:
+		var resp *pb.PublishResponse
+
+		// Отказ лимита - ответ на этот publish, сессия и её подписки продолжают работать
+		if err = ratelimit.AllowPublish(ss.stream.Context(), frame.Publish); err != nil {
+			break
+		}
+
+		resp, err = ss.svc.publish(ss.stream.Context(), ss.log, frame.Publish)
+		if err == nil {
+			delivered = resp.Delivered
+		}
+
+	case *pb.SessionRequest_Ack:
+		if err = ss.ack(frame.Ack); err == nil {
+			return
+		}
+
+	default:
+		ss.log.Warn("Empty session request")
+
+		err = status.Error(codes.InvalidArgument, "empty request")
+	}
+
+	st := status.Convert(err)
+
+	ss.send(&pb.SessionResponse{
+		Frame: &pb.SessionResponse_Reply{Reply: &pb.SessionReply{
+			RequestId: req.RequestId,
+			Code:      uint32(st.Code()),
+			Message:   st.Message(),
+			Delivered: delivered,
+		}},
+	})
+}
+
+/*
+subscribe
+
+Подписка сессии. Обработчики новой подписки ждут entry.ready:
+клиент получает SessionReply раньше первого события по sid.
+*/
+func (ss *session) subscribe(req *pb.SessionSubscribe) (*sessionSub, error) {
+	if req.GetSid() == "" {
+		ss.log.Warn("Session sid is empty")
+
+		return nil, status.Error(codes.InvalidArgument, "sid required")
+	}
+	if req.Subscribe.GetKey() == "" {
+		ss.log.Warn("Req.Key is empty")
+
+		return nil, status.Error(codes.InvalidArgument, "key required")
+	}
+
+	sid, key := req.Sid, req.Subscribe.Key
+	log := ss.log.With(slog.String("sid", sid))
+
+	log.Debug("Session subscribe", slog.String("key", key), slog.Bool("ack", req.Ack))
+
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	if ss.subs == nil {
+		return nil, status.Error(codes.Canceled, "Session closed")
+	}
+	if _, ok := ss.subs[sid]; ok {
+		return nil, status.Error(codes.AlreadyExists, "sid already in use")
+	}
+
+	release, err := ratelimit.AcquireSubscription(ss.stream.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &sessionSub{release: release, ready: make(chan struct{})}
+	opts := subscribeOptions(ss.stream.Context(), req.Subscribe)
+
+	if req.Ack {
+		entry.acks, err = ss.svc.ps.SubscribeAck(key, ss.ackHandler(sid, entry.ready), append(opts, sp.WithManualAck())...)
+		entry.sub = entry.acks
+	} else {
+		entry.sub, err = ss.svc.ps.Subscribe(key, ss.handler(sid, entry.ready), opts...)
+	}
+	if err != nil {
+		release()
+
+		return nil, subscribeError(log, key, err)
+	}
+
+	ss.subs[sid] = entry
+	go ss.watch(sid, entry, log)
+
+	return entry, nil
+}
+
+func (ss *session) handler(sid string, ready <-chan struct{}) sp.MessageHandler {
+	return func(msg interface{}) {
+		<-ready
+
+		m, ok := msg.(*sp.Message)
+		if !ok {
+			return
+		}
+
+		event, ok := newEvent(m)
+		if !ok {
+			return
+		}
+
+		ss.sendEvent(sid, event)
+	}
+}
+
+func (ss *session) ackHandler(sid string, ready <-chan struct{}) sp.AckHandler {
+	return func(d *sp.Delivery) error {
+		<-ready
+
+		event, ok := newEvent(d.Message)
+		if !ok {
+			// Такие данные v1 клиенту не отправить, повторять бессмысленно
+			return d.Ack()
+		}
+		event.Tag = d.Tag
+		event.Attempt = uint32(d.Attempt)
+
+		return ss.sendEvent(sid, event)
+	}
+}
+
+func (ss *session) sendEvent(sid string, event *pb.Event) error {
+	return ss.send(&pb.SessionResponse{
+		Frame: &pb.SessionResponse_Event{Event: &pb.SessionEvent{
+			Sid:   sid,
+			Event: event,
+		}},
+	})
+}
+
+/*
+watch
+
+Освобождает место подписки после её закрытия и сообщает клиенту
+о подписке, закрытой со стороны subPub.
+*/
+func (ss *session) watch(sid string, entry *sessionSub, log *slog.Logger) {
+	<-entry.sub.Done()
+	entry.release()
+	<-entry.ready
+
+	ss.mu.Lock()
+	current, ok := ss.subs[sid]
+	if ok && current == entry {
+		delete(ss.subs, sid)
+	}
+	ss.mu.Unlock()
+
+	// Отписка клиентом или закрытие сессии
+	if !ok || current != entry {
+		return
+	}
+
+	st := status.Convert(subscriptionClosed(log, entry.sub))
+
+	ss.send(&pb.SessionResponse{
+		Frame: &pb.SessionResponse_Closed{Closed: &pb.SessionClosed{
+			Sid:     sid,
+			Code:    uint32(st.Code()),
+			Message: st.Message(),
+		}},
+	})
+}
+
+func (ss *session) unsubscribe(sid string) error {
+	ss.mu.Lock()
+	entry, ok := ss.subs[sid]
+	delete(ss.subs, sid)
+	ss.mu.Unlock()
+
+	if !ok {
+		return status.Error(codes.NotFound, "unknown sid")
+	}
+
+	entry.sub.Unsubscribe()
+
+	return nil
+}
+
+func (ss *session) ack(req *pb.SessionAck) error {
+	ss.mu.Lock()
+	entry, ok := ss.subs[req.Sid]
+	ss.mu.Unlock()
+
+	if !ok {
+		return status.Error(codes.NotFound, "unknown sid")
+	}
+	if entry.acks == nil {
+		return status.Error(codes.FailedPrecondition, "subscription without ack")
+	}
+
+	var err error
+	if req.Nack {
+		err = entry.acks.Nack(req.Tag)
+	} else {
+		err = entry.acks.Ack(req.Tag)
+	}
+
+	if err != nil {
+		ss.log.Warn("Ack failed", sl.Err(err), slog.Uint64("tag", req.Tag))
+
+		return status.Error(codes.NotFound, "unknown delivery tag")
+	}
+
+	return nil
+}
+
+func (ss *session) send(resp *pb.SessionResponse) error {
+	ss.sendMu.Lock()
+	defer ss.sendMu.Unlock()
+
+	if ss.closed {
+		return status.Error(codes.Canceled, "Session closed")
+	}
+
+	err := ss.stream.Send(resp)
+	if err != nil {
+		select {
+		case ss.sendErrCh <- err:
+		default:
+		}
+	}
+
+	return err
+}
+
+func (ss *session) close() {
+	ss.sendMu.Lock()
+	ss.closed = true
+	ss.sendMu.Unlock()
+
+	ss.mu.Lock()
+	subs := ss.subs
+	ss.subs = nil
+	ss.mu.Unlock()
+
+	for _, entry := range subs {
+		entry.sub.Unsubscribe()
+	}
+}
@@ -0,0 +1,182 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+
+	"VK_task/internal/grpc/middleware/logger"
+	"VK_task/internal/grpc/middleware/ratelimit"
+	"VK_task/internal/pkg/logger/sl"
+	pb "VK_task/pkg/api/pubsub"
+	"VK_task/pkg/e"
+	sp "VK_task/pkg/subpub"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+/*
+SubscribeAck
+
+Первое сообщение потока - subscribe, дальше клиент присылает ack/nack
+по tag полученных Event. Без ack сообщение будет доставлено повторно.
+*/
+func (s *Service) SubscribeAck(stream pb.PubSub_SubscribeAckServer) error {
+	return subscribeAck(s.ps, s.log, s.srvStop, stream, func(d *sp.Delivery) (*pb.Event, bool) {
+		event, ok := newEvent(d.Message)
+		if !ok {
+			return nil, false
+		}
+		event.Tag = d.Tag
+		event.Attempt = uint32(d.Attempt)
+
+		return event, true
+	})
+}
+
+// ackRequest - Ack v1 и v2
+type ackRequest interface {
+	proto.Message
+	GetTag() uint64
+	GetNack() bool
+}
+
+// ackStreamRequest - SubscribeAckRequest v1 и v2
+type ackStreamRequest[S subscribeRequest, A ackRequest] interface {
+	GetSubscribe() S
+	GetAck() A
+}
+
+// ackStream - поток SubscribeAck v1 и v2
+type ackStream[R, E any] interface {
+	Context() context.Context
+	Recv() (R, error)
+	Send(E) error
+}
+
+/*
+subscribeAck
+
+Общая часть SubscribeAck v1 и v2, версии отличаются только типами
+сообщений. newEvent - Event для доставки, false - данные этой версии
+не передать: доставка подтверждается, повторять бессмысленно.
+*/
+func subscribeAck[R ackStreamRequest[S, A], S subscribeRequest, A ackRequest, E any](
+	ps sp.SubPub,
+	log *slog.Logger,
+	srvStop <-chan struct{},
+	stream ackStream[R, E],
+	newEvent func(*sp.Delivery) (E, bool),
+) error {
+	log = log.With(
+		slog.String("requestID", logger.GetRequestID(stream.Context())),
+	)
+
+	first, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+
+	req := first.GetSubscribe()
+	if !req.ProtoReflect().IsValid() {
+		log.Warn("First request is not subscribe")
+
+		return status.Error(codes.InvalidArgument, "subscribe request required")
+	}
+
+	log.Debug("Conn data", slog.String("key", req.GetKey()))
+
+	if req.GetKey() == "" {
+		log.Warn("Req.Key is empty")
+
+		return status.Error(codes.InvalidArgument, "key required")
+	}
+
+	sendErrCh := make(chan error, 1)
+
+	handler := func(d *sp.Delivery) error {
+		event, ok := newEvent(d)
+		if !ok {
+			return d.Ack()
+		}
+
+		if err := stream.Send(event); err != nil {
+			select {
+			case sendErrCh <- err:
+			default:
+			}
+			return err
+		}
+
+		return nil
+	}
+
+	release, err := ratelimit.AcquireSubscription(stream.Context())
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	opts := append(subscribeOptions(stream.Context(), req), sp.WithManualAck())
+
+	sub, err := ps.SubscribeAck(req.GetKey(), handler, opts...)
+	if err != nil {
+		return subscribeError(log, req.GetKey(), err)
+	}
+	defer sub.Unsubscribe()
+
+	recvErrCh := make(chan error, 1)
+	go receiveAcks(stream, sub, log, recvErrCh)
+
+	select {
+	case err := <-sendErrCh:
+		log.Error("Send event to stream failed", sl.Err(err))
+
+		return status.Error(codes.Unavailable, e.String("failed to send event", err))
+
+	case err := <-recvErrCh:
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		return err
+
+	case <-stream.Context().Done():
+		return status.FromContextError(stream.Context().Err()).Err()
+
+	case <-sub.Done():
+		return subscriptionClosed(log, sub)
+
+	case <-srvStop:
+		return status.Error(codes.Canceled, "Server stopping")
+	}
+}
+
+func receiveAcks[R ackStreamRequest[S, A], S subscribeRequest, A ackRequest, E any](stream ackStream[R, E], sub sp.AckSubscription, log *slog.Logger, errCh chan<- error) {
+	for {
+		req, err := stream.Recv()
+		if err != nil {
+			errCh <- err
+			return
+		}
+
+		ack := req.GetAck()
+		if !ack.ProtoReflect().IsValid() {
+			log.Warn("Unexpected request in ack stream")
+			continue
+		}
+
+		if ack.GetNack() {
+			err = sub.Nack(ack.GetTag())
+		} else {
+			err = sub.Ack(ack.GetTag())
+		}
+
+		if err != nil {
+			log.Warn("Ack failed", sl.Err(err), slog.Uint64("tag", ack.GetTag()))
+		}
+	}
+}
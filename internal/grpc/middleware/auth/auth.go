@@ -0,0 +1,208 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"VK_task/internal/grpc/middleware/logger"
+	"VK_task/internal/pkg/logger/sl"
+	pb "VK_task/pkg/api/pubsub"
+	pbadmin "VK_task/pkg/api/pubsub/admin"
+	pbv2 "VK_task/pkg/api/pubsub/v2"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+type ctxKey struct{}
+
+// publicServices - доступны без учётных данных: пробы оркестратора
+var publicServices = []string{
+	healthpb.Health_ServiceDesc.ServiceName,
+}
+
+func isPublic(fullMethod string) bool {
+	for _, name := range publicServices {
+		if strings.HasPrefix(fullMethod, "/"+name+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetPrincipal - клиент запроса после аутентификации, пусто без auth interceptor
+func GetPrincipal(ctx context.Context) string {
+	principal, _ := ctx.Value(ctxKey{}).(string)
+	return principal
+}
+
+/*
+NewUnary
+
+Аутентифицирует клиента и проверяет publish/subscribe на subject запроса.
+Ставится после logger.NewUnary: решение пишется в лог с requestID.
+*/
+func NewUnary(policy *Policy, log *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if isPublic(info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		log := log.With(
+			slog.String("requestID", logger.GetRequestID(ctx)),
+			slog.String("method", info.FullMethod),
+		)
+
+		principal, err := authenticate(ctx, policy, log)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := authorize(policy, log, principal, req); err != nil {
+			return nil, err
+		}
+
+		return handler(context.WithValue(ctx, ctxKey{}, principal), req)
+	}
+}
+
+/*
+NewStream
+
+Клиент аутентифицируется при открытии потока, права проверяются
+на каждое входящее сообщение: в Session и PublishStream запрещённый
+subject завершает поток с PermissionDenied.
+*/
+func NewStream(policy *Policy, log *slog.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if isPublic(info.FullMethod) {
+			return handler(srv, ss)
+		}
+
+		ctx := ss.Context()
+
+		log := log.With(
+			slog.String("requestID", logger.GetRequestID(ctx)),
+			slog.String("method", info.FullMethod),
+		)
+
+		principal, err := authenticate(ctx, policy, log)
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, &wrapServerStream{
+			ServerStream: ss,
+			ctx:          context.WithValue(ctx, ctxKey{}, principal),
+			policy:       policy,
+			log:          log,
+			principal:    principal,
+		})
+	}
+}
+
+func authenticate(ctx context.Context, policy *Policy, log *slog.Logger) (string, error) {
+	principal, err := policy.authenticate(ctx)
+	if err != nil {
+		log.Warn("Authentication failed", sl.Err(err))
+
+		return "", status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	return principal, nil
+}
+
+func authorize(policy *Policy, log *slog.Logger, principal string, req interface{}) error {
+	for _, acc := range requested(req) {
+		if !policy.Allowed(principal, acc.action, acc.subject) {
+			log.Warn("Access denied",
+				slog.String("principal", principal),
+				slog.String("action", acc.action),
+				slog.String("subject", acc.subject),
+			)
+
+			return status.Error(codes.PermissionDenied, fmt.Sprintf("%s on %q is not allowed", acc.action, acc.subject))
+		}
+
+		log.Debug("Access granted",
+			slog.String("principal", principal),
+			slog.String("action", acc.action),
+			slog.String("subject", acc.subject),
+		)
+	}
+
+	return nil
+}
+
+type access struct {
+	action  string
+	subject string
+}
+
+// requested - subject, к которым обращается сообщение, пустой key проверяет обработчик
+func requested(req interface{}) []access {
+	var acc []access
+
+	add := func(action, subject string) {
+		if subject != "" {
+			acc = append(acc, access{action: action, subject: subject})
+		}
+	}
+
+	switch r := req.(type) {
+	case *pbadmin.ListSubjectsRequest, *pbadmin.ListSubscriptionsRequest,
+		*pbadmin.CloseSubscriptionRequest, *pbadmin.DrainSubjectRequest:
+		acc = append(acc, access{action: ActionAdmin})
+
+	case *pb.PublishRequest:
+		add(ActionPublish, r.GetKey())
+	case *pb.RequestMessage:
+		add(ActionPublish, r.GetKey())
+	case *pb.PublishBatchRequest:
+		for _, m := range r.GetMessages() {
+			add(ActionPublish, m.GetKey())
+		}
+	case *pb.SubscribeRequest:
+		add(ActionSubscribe, r.GetKey())
+	case *pb.SubscribeAckRequest:
+		add(ActionSubscribe, r.GetSubscribe().GetKey())
+	case *pb.SessionRequest:
+		add(ActionSubscribe, r.GetSubscribe().GetSubscribe().GetKey())
+		add(ActionPublish, r.GetPublish().GetKey())
+
+	case *pbv2.PublishRequest:
+		add(ActionPublish, r.GetKey())
+	case *pbv2.SubscribeRequest:
+		add(ActionSubscribe, r.GetKey())
+	case *pbv2.SubscribeAckRequest:
+		add(ActionSubscribe, r.GetSubscribe().GetKey())
+	}
+
+	return acc
+}
+
+type wrapServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+
+	policy    *Policy
+	log       *slog.Logger
+	principal string
+}
+
+func (w *wrapServerStream) Context() context.Context {
+	return w.ctx
+}
+
+func (w *wrapServerStream) RecvMsg(m interface{}) error {
+	if err := w.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+
+	return authorize(w.policy, w.log, w.principal, m)
+}
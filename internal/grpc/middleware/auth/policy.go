@@ -0,0 +1,148 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"VK_task/pkg/e"
+	sp "VK_task/pkg/subpub"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	ActionPublish   = "publish"
+	ActionSubscribe = "subscribe"
+	ActionAdmin     = "admin" // Admin сервис, subject не проверяется
+
+	// AnyPrincipal в правиле подходит для любого клиента
+	AnyPrincipal = "*"
+	// Anonymous - клиент без токена и сертификата при allow_anonymous
+	Anonymous = "anonymous"
+)
+
+var ErrInvalidPolicy = errors.New("invalid policy")
+
+/*
+Policy
+
+Файл политики доступа:
+
+	allow_anonymous: false
+	tokens:
+	  - principal: "orders-service"
+	    token: "secret"
+	jwt:
+	  secret: "hmac-secret"   # HS256, principal - claim sub
+	  issuer: ""
+	  audience: ""
+	rules:
+	  - principal: "orders-service"   # имя токена, JWT sub или CN сертификата
+	    publish: ["orders.>"]
+	    subscribe: ["orders.*.created"]
+	  - principal: "ops"
+	    admin: true                   # Admin сервис
+
+Разрешено только то, что подходит под шаблоны правил principal или "*".
+*/
+type Policy struct {
+	AllowAnonymous bool          `yaml:"allow_anonymous"`
+	Tokens         []StaticToken `yaml:"tokens"`
+	JWT            JWTConfig     `yaml:"jwt"`
+	Rules          []Rule        `yaml:"rules"`
+}
+
+type StaticToken struct {
+	Principal string `yaml:"principal"`
+	Token     string `yaml:"token"`
+}
+
+type JWTConfig struct {
+	Secret   string `yaml:"secret"` // пусто - JWT не принимаются
+	Issuer   string `yaml:"issuer"`
+	Audience string `yaml:"audience"`
+}
+
+type Rule struct {
+	Principal string   `yaml:"principal"`
+	Publish   []string `yaml:"publish"`
+	Subscribe []string `yaml:"subscribe"`
+	Admin     bool     `yaml:"admin"`
+}
+
+func LoadPolicy(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, e.Wrap("failed to read policy file", err)
+	}
+
+	var p Policy
+	if err := yaml.Unmarshal(data, &p); err != nil {
+		return nil, e.Wrap("failed to parse policy file", err)
+	}
+
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+func (p *Policy) validate() error {
+	for i, t := range p.Tokens {
+		if t.Principal == "" || t.Token == "" {
+			return fmt.Errorf("%w: token %d: principal and token required", ErrInvalidPolicy, i)
+		}
+	}
+
+	for i, r := range p.Rules {
+		if r.Principal == "" {
+			return fmt.Errorf("%w: rule %d: principal required", ErrInvalidPolicy, i)
+		}
+
+		for _, patterns := range [][]string{r.Publish, r.Subscribe} {
+			for _, pattern := range patterns {
+				if !sp.ValidPattern(pattern) {
+					return fmt.Errorf("%w: rule %d: invalid subject pattern %q", ErrInvalidPolicy, i, pattern)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+/*
+Allowed
+
+Для subscribe subject сам может быть шаблоном: разрешено,
+только если всё, что он покрывает, покрывает и шаблон правила.
+*/
+func (p *Policy) Allowed(principal, action, subject string) bool {
+	for _, r := range p.Rules {
+		if r.Principal != principal && r.Principal != AnyPrincipal {
+			continue
+		}
+
+		if action == ActionAdmin {
+			if r.Admin {
+				return true
+			}
+			continue
+		}
+
+		patterns := r.Publish
+		if action == ActionSubscribe {
+			patterns = r.Subscribe
+		}
+
+		for _, pattern := range patterns {
+			if sp.Covers(pattern, subject) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,161 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"VK_task/internal/grpc/middleware/identity"
+
+	"google.golang.org/grpc/metadata"
+)
+
+const authorizationKey = "authorization"
+
+var (
+	ErrNoCredentials = errors.New("no credentials")
+	ErrInvalidToken  = errors.New("invalid token")
+	ErrTokenExpired  = errors.New("token expired")
+)
+
+/*
+authenticate
+
+Principal клиента: bearer токен из metadata "authorization"
+(статический или JWT), иначе CN проверенного сертификата mTLS,
+иначе Anonymous, если политика это разрешает.
+*/
+func (p *Policy) authenticate(ctx context.Context) (string, error) {
+	if token, ok := bearerToken(ctx); ok {
+		return p.verifyToken(token)
+	}
+
+	if id, ok := identity.Get(ctx); ok && id.CommonName != "" {
+		return id.CommonName, nil
+	}
+
+	if p.AllowAnonymous {
+		return Anonymous, nil
+	}
+
+	return "", ErrNoCredentials
+}
+
+func bearerToken(ctx context.Context) (string, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", false
+	}
+
+	values := md.Get(authorizationKey)
+	if len(values) == 0 {
+		return "", false
+	}
+
+	scheme, token, ok := strings.Cut(values[0], " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+
+	return strings.TrimSpace(token), true
+}
+
+func (p *Policy) verifyToken(token string) (string, error) {
+	for _, t := range p.Tokens {
+		if subtle.ConstantTimeCompare([]byte(t.Token), []byte(token)) == 1 {
+			return t.Principal, nil
+		}
+	}
+
+	if p.JWT.Secret != "" && strings.Count(token, ".") == 2 {
+		return p.verifyJWT(token, time.Now())
+	}
+
+	return "", ErrInvalidToken
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+}
+
+type jwtClaims struct {
+	Subject   string          `json:"sub"`
+	Issuer    string          `json:"iss"`
+	Audience  json.RawMessage `json:"aud"` // строка или массив строк
+	ExpiresAt *int64          `json:"exp"`
+	NotBefore *int64          `json:"nbf"`
+}
+
+// verifyJWT проверяет HS256 подпись и claims, principal - sub
+func (p *Policy) verifyJWT(token string, now time.Time) (string, error) {
+	parts := strings.Split(token, ".")
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil || header.Alg != "HS256" {
+		return "", ErrInvalidToken
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", ErrInvalidToken
+	}
+
+	mac := hmac.New(sha256.New, []byte(p.JWT.Secret))
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return "", ErrInvalidToken
+	}
+
+	var claims jwtClaims
+	if err := decodeSegment(parts[1], &claims); err != nil || claims.Subject == "" {
+		return "", ErrInvalidToken
+	}
+
+	if claims.ExpiresAt != nil && now.Unix() >= *claims.ExpiresAt {
+		return "", ErrTokenExpired
+	}
+	if claims.NotBefore != nil && now.Unix() < *claims.NotBefore {
+		return "", ErrInvalidToken
+	}
+	if p.JWT.Issuer != "" && claims.Issuer != p.JWT.Issuer {
+		return "", ErrInvalidToken
+	}
+	if p.JWT.Audience != "" && !hasAudience(claims.Audience, p.JWT.Audience) {
+		return "", ErrInvalidToken
+	}
+
+	return claims.Subject, nil
+}
+
+func decodeSegment(seg string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+func hasAudience(raw json.RawMessage, audience string) bool {
+	var one string
+	if json.Unmarshal(raw, &one) == nil {
+		return one == audience
+	}
+
+	var many []string
+	if json.Unmarshal(raw, &many) == nil {
+		for _, aud := range many {
+			if aud == audience {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,80 @@
+package identity
+
+import (
+	"context"
+	"crypto/x509"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+type ctxKey struct{}
+
+// Identity - клиент по проверенному сертификату mTLS
+type Identity struct {
+	CommonName   string
+	Organization []string
+	DNSNames     []string
+	URIs         []string // например SPIFFE ID
+	SerialNumber string
+}
+
+// Get - false, если клиент подключился без проверенного сертификата
+func Get(ctx context.Context) (Identity, bool) {
+	id, ok := ctx.Value(ctxKey{}).(Identity)
+	return id, ok
+}
+
+func NewUnary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(withIdentity(ctx), req)
+	}
+}
+
+func NewStream() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &wrapServerStream{
+			ServerStream: ss,
+			ctx:          withIdentity(ss.Context()),
+		})
+	}
+}
+
+func withIdentity(ctx context.Context) context.Context {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ctx
+	}
+
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(tlsInfo.State.VerifiedChains) == 0 {
+		return ctx
+	}
+
+	return context.WithValue(ctx, ctxKey{}, fromCertificate(tlsInfo.State.VerifiedChains[0][0]))
+}
+
+func fromCertificate(cert *x509.Certificate) Identity {
+	id := Identity{
+		CommonName:   cert.Subject.CommonName,
+		Organization: cert.Subject.Organization,
+		DNSNames:     cert.DNSNames,
+		SerialNumber: cert.SerialNumber.String(),
+	}
+
+	for _, uri := range cert.URIs {
+		id.URIs = append(id.URIs, uri.String())
+	}
+
+	return id
+}
+
+type wrapServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (w *wrapServerStream) Context() context.Context {
+	return w.ctx
+}
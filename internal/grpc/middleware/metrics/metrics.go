@@ -0,0 +1,39 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Recorder - получатель метрик gRPC вызовов, например *internal/metrics.Metrics
+type Recorder interface {
+	GRPCHandled(method string, code codes.Code, d time.Duration)
+}
+
+func NewUnary(rec Recorder) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+
+		resp, err := handler(ctx, req)
+
+		rec.GRPCHandled(info.FullMethod, status.Code(err), time.Since(start))
+
+		return resp, err
+	}
+}
+
+func NewStream(rec Recorder) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+
+		err := handler(srv, ss)
+
+		rec.GRPCHandled(info.FullMethod, status.Code(err), time.Since(start))
+
+		return err
+	}
+}
@@ -0,0 +1,230 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	ScopeClient  = "client"
+	ScopeSubject = "subject"
+
+	ResourceMessages      = "messages"
+	ResourceBytes         = "bytes"
+	ResourceSubscriptions = "subscriptions"
+
+	defaultIdleTimeout = 10 * time.Minute
+)
+
+// Bucket - token bucket, Rate <= 0 - без ограничения
+type Bucket struct {
+	Rate  float64 // в секунду
+	Burst int     // <= 0 - равен Rate
+}
+
+type Limits struct {
+	Messages Bucket // публикаций в секунду
+	Bytes    Bucket // байт данных в секунду
+}
+
+type Config struct {
+	Client           Limits
+	Subject          Limits
+	MaxSubscriptions int           // одновременных подписок клиента, <= 0 - без ограничения
+	IdleTimeout      time.Duration // через сколько удаляются неиспользуемые bucket
+}
+
+/*
+Limiter
+
+Token bucket на публикации и байты для каждого клиента и каждого subject,
+счётчик одновременных подписок клиента.
+*/
+type Limiter struct {
+	cfg Config
+
+	mu        sync.Mutex
+	clients   map[string]*buckets
+	subjects  map[string]*buckets
+	subs      map[string]int // активных подписок по клиенту
+	lastSweep time.Time
+}
+
+type buckets struct {
+	messages *rate.Limiter // nil - без ограничения
+	bytes    *rate.Limiter
+	lastSeen time.Time
+}
+
+// Denial - причина отказа, RetryAfter 0 - повтор не поможет
+type Denial struct {
+	Scope      string
+	Resource   string
+	RetryAfter time.Duration
+}
+
+func New(cfg Config) *Limiter {
+	if cfg.IdleTimeout <= 0 {
+		cfg.IdleTimeout = defaultIdleTimeout
+	}
+
+	return &Limiter{
+		cfg:       cfg,
+		clients:   make(map[string]*buckets),
+		subjects:  make(map[string]*buckets),
+		subs:      make(map[string]int),
+		lastSweep: time.Now(),
+	}
+}
+
+/*
+allowPublish
+
+Списывает сообщения и байты с bucket каждого subject и клиента.
+Если хоть в одном bucket не хватает токенов, ничего не списывается.
+*/
+func (l *Limiter) allowPublish(client string, subjects map[string]publish) *Denial {
+	if len(subjects) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	var (
+		total    publish
+		reserved []*rate.Reservation
+	)
+
+	cancel := func() {
+		for _, r := range reserved {
+			r.CancelAt(now)
+		}
+	}
+
+	check := func(scope string, b *buckets, p publish) *Denial {
+		for _, c := range []struct {
+			lim      *rate.Limiter
+			n        int
+			resource string
+		}{
+			{b.messages, p.messages, ResourceMessages},
+			{b.bytes, p.bytes, ResourceBytes},
+		} {
+			if c.lim == nil || c.n == 0 {
+				continue
+			}
+
+			r := c.lim.ReserveN(now, c.n)
+			if !r.OK() {
+				return &Denial{Scope: scope, Resource: c.resource}
+			}
+
+			reserved = append(reserved, r)
+
+			if delay := r.DelayFrom(now); delay > 0 {
+				return &Denial{Scope: scope, Resource: c.resource, RetryAfter: delay}
+			}
+		}
+
+		return nil
+	}
+
+	for subject, p := range subjects {
+		total.messages += p.messages
+		total.bytes += p.bytes
+
+		if d := check(ScopeSubject, l.bucketsFor(l.subjects, l.cfg.Subject, subject, now), p); d != nil {
+			cancel()
+			return d
+		}
+	}
+
+	if d := check(ScopeClient, l.bucketsFor(l.clients, l.cfg.Client, client, now), total); d != nil {
+		cancel()
+		return d
+	}
+
+	return nil
+}
+
+// acquireSubscription - false, если у клиента уже MaxSubscriptions подписок
+func (l *Limiter) acquireSubscription(client string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.cfg.MaxSubscriptions > 0 && l.subs[client] >= l.cfg.MaxSubscriptions {
+		return false
+	}
+	l.subs[client]++
+
+	return true
+}
+
+func (l *Limiter) releaseSubscription(client string, n int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.subs[client] -= n
+	if l.subs[client] <= 0 {
+		delete(l.subs, client)
+	}
+}
+
+func (l *Limiter) bucketsFor(m map[string]*buckets, limits Limits, key string, now time.Time) *buckets {
+	b, ok := m[key]
+	if !ok {
+		b = &buckets{
+			messages: newRateLimiter(limits.Messages),
+			bytes:    newRateLimiter(limits.Bytes),
+		}
+		m[key] = b
+	}
+	b.lastSeen = now
+
+	return b
+}
+
+// sweep удаляет bucket, не использованные IdleTimeout, не чаще раза в IdleTimeout
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.cfg.IdleTimeout {
+		return
+	}
+	l.lastSweep = now
+
+	for _, m := range []map[string]*buckets{l.clients, l.subjects} {
+		for key, b := range m {
+			if now.Sub(b.lastSeen) >= l.cfg.IdleTimeout {
+				delete(m, key)
+			}
+		}
+	}
+}
+
+func newRateLimiter(b Bucket) *rate.Limiter {
+	if b.Rate <= 0 {
+		return nil
+	}
+
+	burst := b.Burst
+	if burst <= 0 {
+		burst = int(b.Rate)
+		if burst < 1 {
+			burst = 1
+		}
+	}
+
+	return rate.NewLimiter(rate.Limit(b.Rate), burst)
+}
+
+// publish - сколько сообщений и байт публикуется в subject
+type publish struct {
+	messages int
+	bytes    int
+}
@@ -0,0 +1,277 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"strconv"
+	"sync"
+
+	"VK_task/internal/grpc/middleware/auth"
+	"VK_task/internal/grpc/middleware/identity"
+	"VK_task/internal/grpc/middleware/logger"
+	pb "VK_task/pkg/api/pubsub"
+	pbv2 "VK_task/pkg/api/pubsub/v2"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// Trailer metadata отказа
+const (
+	RetryAfterKey = "retry-after-ms" // через сколько миллисекунд повторить, нет - повтор не поможет
+	ScopeKey      = "x-ratelimit-scope"
+	ResourceKey   = "x-ratelimit-resource"
+)
+
+/*
+NewUnary
+
+Ставится после auth: клиент - principal, CN сертификата или IP адрес.
+При превышении - ResourceExhausted, подсказки повтора в trailer.
+*/
+func NewUnary(l *Limiter, log *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		client := clientKey(ctx)
+
+		if d := l.allowPublish(client, published(req)); d != nil {
+			logDenial(log, ctx, info.FullMethod, client, d)
+
+			_ = grpc.SetTrailer(ctx, d.trailer())
+			return nil, d.err()
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+/*
+NewStream
+
+Публикации проверяются на каждое входящее сообщение, отказ завершает
+поток с ResourceExhausted, кроме publish в Session (см. AllowPublish). Места подписок в MaxSubscriptions занимает
+обработчик через AcquireSubscription, оставшиеся освобождаются
+вместе с потоком.
+*/
+func NewStream(l *Limiter, log *slog.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		w := &wrapServerStream{
+			ServerStream: ss,
+			limiter:      l,
+			log:          log,
+			method:       info.FullMethod,
+			client:       clientKey(ss.Context()),
+		}
+		w.ctx = context.WithValue(ss.Context(), ctxKey{}, w)
+
+		defer w.releaseAll()
+
+		return handler(srv, w)
+	}
+}
+
+type ctxKey struct{}
+
+type wrapServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+
+	limiter *Limiter
+	log     *slog.Logger
+	method  string
+	client  string
+
+	mu   sync.Mutex
+	subs int  // занятых мест подписок
+	done bool // поток завершён, места освобождены
+}
+
+func (w *wrapServerStream) Context() context.Context {
+	return w.ctx
+}
+
+func (w *wrapServerStream) RecvMsg(m interface{}) error {
+	if err := w.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+
+	if d := w.limiter.allowPublish(w.client, published(m)); d != nil {
+		return w.deny(d)
+	}
+
+	return nil
+}
+
+/*
+AllowPublish
+
+Проверка публикации, отказ в которой не должен завершать поток:
+Session отвечает на такой publish ResourceExhausted и продолжает
+работать. Без ratelimit interceptor у потока - всегда nil.
+*/
+func AllowPublish(ctx context.Context, req interface{}) error {
+	w, ok := ctx.Value(ctxKey{}).(*wrapServerStream)
+	if !ok {
+		return nil
+	}
+
+	if d := w.limiter.allowPublish(w.client, published(req)); d != nil {
+		logDenial(w.log, ctx, w.method, w.client, d)
+
+		return d.err()
+	}
+
+	return nil
+}
+
+/*
+AcquireSubscription
+
+Занимает место подписки клиента в MaxSubscriptions до вызова Subscribe.
+release освобождает его, когда подписка закрыта: отпиской, сервером
+или неудачным Subscribe, повторный вызов ничего не делает. Без
+ratelimit interceptor у потока - всегда успешно. При превышении -
+ResourceExhausted, подсказки в trailer потока.
+*/
+func AcquireSubscription(ctx context.Context) (release func(), err error) {
+	w, ok := ctx.Value(ctxKey{}).(*wrapServerStream)
+	if !ok {
+		return func() {}, nil
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.done {
+		return nil, status.Error(codes.Canceled, "stream closed")
+	}
+
+	if !w.limiter.acquireSubscription(w.client) {
+		return nil, w.deny(&Denial{Scope: ScopeClient, Resource: ResourceSubscriptions})
+	}
+	w.subs++
+
+	var once sync.Once
+
+	return func() {
+		once.Do(w.release)
+	}, nil
+}
+
+func (w *wrapServerStream) release() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.done {
+		return
+	}
+
+	w.limiter.releaseSubscription(w.client, 1)
+	w.subs--
+}
+
+// releaseAll - подписки потока закрываются вместе с ним
+func (w *wrapServerStream) releaseAll() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.done = true
+	if w.subs > 0 {
+		w.limiter.releaseSubscription(w.client, w.subs)
+		w.subs = 0
+	}
+}
+
+func (w *wrapServerStream) deny(d *Denial) error {
+	logDenial(w.log, w.Context(), w.method, w.client, d)
+
+	w.SetTrailer(d.trailer())
+	return d.err()
+}
+
+func (d *Denial) err() error {
+	return status.Error(codes.ResourceExhausted, fmt.Sprintf("%s %s limit exceeded", d.Scope, d.Resource))
+}
+
+func (d *Denial) trailer() metadata.MD {
+	md := metadata.Pairs(
+		ScopeKey, d.Scope,
+		ResourceKey, d.Resource,
+	)
+	if d.RetryAfter > 0 {
+		md.Set(RetryAfterKey, strconv.FormatInt(d.RetryAfter.Milliseconds()+1, 10))
+	}
+
+	return md
+}
+
+func logDenial(log *slog.Logger, ctx context.Context, method, client string, d *Denial) {
+	log.Warn("Rate limit exceeded",
+		slog.String("requestID", logger.GetRequestID(ctx)),
+		slog.String("method", method),
+		slog.String("client", client),
+		slog.String("scope", d.Scope),
+		slog.String("resource", d.Resource),
+		slog.Duration("retry_after", d.RetryAfter),
+	)
+}
+
+// clientKey - principal после auth, иначе CN сертификата, иначе IP адрес клиента
+func clientKey(ctx context.Context) string {
+	if principal := auth.GetPrincipal(ctx); principal != "" && principal != auth.Anonymous {
+		return principal
+	}
+
+	if id, ok := identity.Get(ctx); ok && id.CommonName != "" {
+		return id.CommonName
+	}
+
+	if p, ok := peer.FromContext(ctx); ok {
+		if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+			return host
+		}
+		return p.Addr.String()
+	}
+
+	return ""
+}
+
+// published - публикации сообщения по subject, пустой key проверяет обработчик.
+// Publish в Session проверяет обработчик через AllowPublish
+
+func published(req interface{}) map[string]publish {
+	var subjects map[string]publish
+
+	add := func(subject string, size int) {
+		if subject == "" {
+			return
+		}
+		if subjects == nil {
+			subjects = make(map[string]publish, 1)
+		}
+
+		p := subjects[subject]
+		p.messages++
+		p.bytes += size
+		subjects[subject] = p
+	}
+
+	switch r := req.(type) {
+	case *pb.PublishRequest:
+		add(r.GetKey(), len(r.GetData()))
+	case *pb.RequestMessage:
+		add(r.GetKey(), len(r.GetData()))
+	case *pb.PublishBatchRequest:
+		for _, m := range r.GetMessages() {
+			add(m.GetKey(), len(m.GetData()))
+		}
+	case *pbv2.PublishRequest:
+		add(r.GetKey(), len(r.GetData()))
+	}
+
+	return subjects
+}
@@ -0,0 +1,117 @@
+package tracing
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "VK_task/internal/grpc"
+
+var propagator = propagation.TraceContext{}
+
+/*
+NewUnary
+
+Server span на каждый вызов, например "pubsub.PubSub/Publish".
+Родитель - traceparent из metadata запроса, если клиент его передал.
+*/
+func NewUnary(tp trace.TracerProvider) grpc.UnaryServerInterceptor {
+	tracer := tp.Tracer(tracerName)
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, span := startSpan(ctx, tracer, info.FullMethod)
+
+		resp, err := handler(ctx, req)
+
+		endSpan(span, err)
+
+		return resp, err
+	}
+}
+
+func NewStream(tp trace.TracerProvider) grpc.StreamServerInterceptor {
+	tracer := tp.Tracer(tracerName)
+
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, span := startSpan(ss.Context(), tracer, info.FullMethod)
+
+		err := handler(srv, &wrapServerStream{
+			ServerStream: ss,
+			ctx:          ctx,
+		})
+
+		endSpan(span, err)
+
+		return err
+	}
+}
+
+func startSpan(ctx context.Context, tracer trace.Tracer, method string) (context.Context, trace.Span) {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		ctx = propagator.Extract(ctx, metadataCarrier(md))
+	}
+
+	name := method
+	if len(name) > 0 && name[0] == '/' {
+		name = name[1:]
+	}
+
+	return tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("rpc.system", "grpc"),
+			attribute.String("rpc.method", method),
+		),
+	)
+}
+
+func endSpan(span trace.Span, err error) {
+	code := status.Code(err)
+	span.SetAttributes(attribute.Int("rpc.grpc.status_code", int(code)))
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, code.String())
+	}
+	span.End()
+}
+
+// metadataCarrier - propagation.TextMapCarrier поверх входящей metadata
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	v := metadata.MD(c).Get(key)
+	if len(v) == 0 {
+		return ""
+	}
+	return v[0]
+}
+
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+type wrapServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (w *wrapServerStream) Context() context.Context {
+	return w.ctx
+}
@@ -0,0 +1,285 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	pb "VK_task/pkg/api/pubsub"
+
+	"github.com/gorilla/websocket"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+const maxBodySize = 4 << 20 // как максимальное сообщение gRPC по умолчанию
+
+/*
+Gateway
+
+HTTP/JSON доступ к v1 PubSub:
+
+	POST /v1/publish/{key}    - Service.Publish, тело - JSON {"data": "..."} или текст, ?ttl=30s&retain=true
+	GET  /v1/subscribe/{key}  - Server-Sent Events поверх Service.Subscribe
+	GET  /v1/ws               - WebSocket с JSON кадрами поверх Service.Session
+
+Вызовы проходят те же interceptor, что и gRPC (логи, метрики,
+авторизация, лимиты), HTTP заголовки передаются как metadata.
+Ошибки - статус gRPC, переведённый в HTTP код, и JSON {"code", "message"}.
+*/
+type Gateway struct {
+	service pb.PubSubServer
+	unary   grpc.UnaryServerInterceptor
+	stream  grpc.StreamServerInterceptor
+	log     *slog.Logger
+
+	mux      *http.ServeMux
+	upgrader websocket.Upgrader
+	conns    connections
+}
+
+// allowedOrigins - Origin, с которых разрешён WebSocket, кроме того же хоста
+func New(service pb.PubSubServer,
+	unary []grpc.UnaryServerInterceptor,
+	stream []grpc.StreamServerInterceptor,
+	allowedOrigins []string,
+	log *slog.Logger,
+) *Gateway {
+	g := &Gateway{
+		service: service,
+		unary:   chainUnary(unary),
+		stream:  chainStream(stream),
+		log:     log,
+		mux:     http.NewServeMux(),
+		upgrader: websocket.Upgrader{
+			CheckOrigin: checkOrigin(allowedOrigins),
+		},
+	}
+
+	g.mux.HandleFunc("POST /v1/publish/{key}", g.publish)
+	g.mux.HandleFunc("GET /v1/subscribe/{key}", g.subscribe)
+	g.mux.HandleFunc("GET /v1/ws", g.websocket)
+
+	return g
+}
+
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	g.mux.ServeHTTP(w, r)
+}
+
+// Wait ждёт закрытия WebSocket соединений после http.Server.Shutdown
+func (g *Gateway) Wait(ctx context.Context) error {
+	return g.conns.wait(ctx)
+}
+
+type publishBody struct {
+	Data string `json:"data"`
+}
+
+func (g *Gateway) publish(w http.ResponseWriter, r *http.Request) {
+	data, err := readData(w, r)
+	if err != nil {
+		writeError(w, err, nil)
+		return
+	}
+
+	ttl, err := parseTTL(r.URL.Query().Get("ttl"))
+	if err != nil {
+		writeError(w, err, nil)
+		return
+	}
+
+	var retain bool
+	if v := r.URL.Query().Get("retain"); v != "" {
+		if retain, err = strconv.ParseBool(v); err != nil {
+			writeError(w, status.Error(codes.InvalidArgument, "retain must be true or false"), nil)
+			return
+		}
+	}
+
+	ts := &transportStream{method: pb.PubSub_Publish_FullMethodName}
+	ctx := grpc.NewContextWithServerTransportStream(incomingContext(r), ts)
+
+	info := &grpc.UnaryServerInfo{
+		Server:     g.service,
+		FullMethod: pb.PubSub_Publish_FullMethodName,
+	}
+
+	resp, err := g.unary(ctx, &pb.PublishRequest{Key: r.PathValue("key"), Data: data, Ttl: ttl, Retain: retain}, info,
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			return g.service.Publish(ctx, req.(*pb.PublishRequest))
+		},
+	)
+	if err != nil {
+		writeError(w, err, ts.trailer)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp.(*pb.PublishResponse))
+}
+
+// readData - поле data JSON тела или всё тело как текст
+func readData(w http.ResponseWriter, r *http.Request) (string, error) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return "", status.Error(codes.ResourceExhausted, "request body too large")
+		}
+
+		return "", status.Error(codes.InvalidArgument, "failed to read request body")
+	}
+
+	data := string(body)
+
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "application/json" {
+		var b publishBody
+		if err := json.Unmarshal(body, &b); err != nil {
+			return "", status.Error(codes.InvalidArgument, "invalid JSON body")
+		}
+
+		data = b.Data
+	}
+
+	if !utf8.ValidString(data) {
+		return "", status.Error(codes.InvalidArgument, "data must be UTF-8 text")
+	}
+
+	return data, nil
+}
+
+func (g *Gateway) subscribe(w http.ResponseWriter, r *http.Request) {
+	req, err := subscribeRequest(r)
+	if err != nil {
+		writeError(w, err, nil)
+		return
+	}
+
+	ss, err := newEventStream(incomingContext(r), w, req)
+	if err != nil {
+		writeError(w, err, nil)
+		return
+	}
+	defer ss.close()
+
+	info := &grpc.StreamServerInfo{
+		FullMethod:     pb.PubSub_Subscribe_FullMethodName,
+		IsServerStream: true,
+	}
+
+	err = g.stream(g.service, ss, info, func(srv interface{}, stream grpc.ServerStream) error {
+		in := new(pb.SubscribeRequest)
+		if err := stream.RecvMsg(in); err != nil {
+			return err
+		}
+
+		return g.service.Subscribe(in, &subscribeServer{ServerStream: stream})
+	})
+
+	ss.fail(err)
+}
+
+/*
+subscribeRequest
+
+Query параметры: group - queue group, start - "earliest"
+или смещение в durable log.
+*/
+func subscribeRequest(r *http.Request) (*pb.SubscribeRequest, error) {
+	q := r.URL.Query()
+
+	req := &pb.SubscribeRequest{
+		Key:   r.PathValue("key"),
+		Group: q.Get("group"),
+	}
+
+	if err := setStart(req, q.Get("start")); err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// setStart - начало подписки: пусто - новые сообщения, "earliest" или смещение
+func setStart(req *pb.SubscribeRequest, start string) error {
+	switch start {
+	case "":
+	case "earliest":
+		req.Start = &pb.SubscribeRequest_StartEarliest{StartEarliest: true}
+	default:
+		offset, err := strconv.ParseUint(start, 10, 64)
+		if err != nil {
+			return status.Error(codes.InvalidArgument, "start must be \"earliest\" or offset")
+		}
+
+		req.Start = &pb.SubscribeRequest_StartOffset{StartOffset: offset}
+	}
+
+	return nil
+}
+
+// parseTTL - время жизни сообщения в формате time.ParseDuration, пусто - не задано
+func parseTTL(ttl string) (*durationpb.Duration, error) {
+	if ttl == "" {
+		return nil, nil
+	}
+
+	d, err := time.ParseDuration(ttl)
+	if err != nil || d < 0 {
+		return nil, status.Error(codes.InvalidArgument, "ttl must be a duration like \"30s\"")
+	}
+
+	return durationpb.New(d), nil
+}
+
+type subscribeServer struct {
+	grpc.ServerStream
+}
+
+func (s *subscribeServer) Send(event *pb.Event) error {
+	return s.ServerStream.SendMsg(event)
+}
+
+/*
+incomingContext
+
+HTTP заголовки как incoming metadata, адрес клиента и TLS
+соединение как peer: identity берёт CN проверенного сертификата.
+*/
+func incomingContext(r *http.Request) context.Context {
+	md := make(metadata.MD, len(r.Header))
+	for key, values := range r.Header {
+		md.Append(strings.ToLower(key), values...)
+	}
+
+	ctx := metadata.NewIncomingContext(r.Context(), md)
+
+	if addr, err := netip.ParseAddrPort(r.RemoteAddr); err == nil {
+		p := &peer.Peer{Addr: net.TCPAddrFromAddrPort(addr)}
+		if r.TLS != nil {
+			p.AuthInfo = credentials.TLSInfo{
+				State:          *r.TLS,
+				CommonAuthInfo: credentials.CommonAuthInfo{SecurityLevel: credentials.PrivacyAndIntegrity},
+			}
+		}
+
+		ctx = peer.NewContext(ctx, p)
+	}
+
+	return ctx
+}
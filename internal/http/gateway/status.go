@@ -0,0 +1,101 @@
+package gateway
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+
+	"VK_task/internal/grpc/middleware/ratelimit"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// statusClientClosed - клиент закрыл соединение (nginx 499), для codes.Canceled
+const statusClientClosed = 499
+
+// httpStatus - HTTP код для статуса gRPC, как в grpc-gateway
+func httpStatus(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return statusClientClosed
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+type errorResponse struct {
+	Code    string `json:"code"` // имя кода gRPC, например "InvalidArgument"
+	Message string `json:"message"`
+}
+
+func errorBody(err error) errorResponse {
+	st := status.Convert(err)
+
+	return errorResponse{
+		Code:    st.Code().String(),
+		Message: st.Message(),
+	}
+}
+
+// writeError - trailer metadata обработчика (например лимиты) уходит в заголовки
+func writeError(w http.ResponseWriter, err error, trailer metadata.MD) {
+	h := w.Header()
+	setMetadata(h, trailer)
+
+	// Retry-After в секундах для стандартных клиентов
+	if ms := trailer.Get(ratelimit.RetryAfterKey); len(ms) > 0 {
+		if n, err := strconv.ParseFloat(ms[0], 64); err == nil {
+			h.Set("Retry-After", strconv.Itoa(int(math.Ceil(n/1000))))
+		}
+	}
+
+	h.Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatus(status.Code(err)))
+
+	_ = json.NewEncoder(w).Encode(errorBody(err))
+}
+
+func writeJSON(w http.ResponseWriter, code int, m proto.Message) {
+	data, err := protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}.Marshal(m)
+	if err != nil {
+		writeError(w, status.Error(codes.Internal, "failed to encode response"), nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_, _ = w.Write(data)
+}
+
+func setMetadata(h http.Header, md metadata.MD) {
+	for key, values := range md {
+		for _, v := range values {
+			h.Add(key, v)
+		}
+	}
+}
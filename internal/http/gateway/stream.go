@@ -0,0 +1,241 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	pb "VK_task/pkg/api/pubsub"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const keepAliveInterval = 15 * time.Second
+
+var errStreamClosed = errors.New("event stream closed")
+
+/*
+eventStream
+
+grpc.ServerStream поверх HTTP ответа: RecvMsg один раз отдаёт запрос
+подписки, SendMsg пишет событие Server-Sent Events. Пока поток не открыт,
+ошибка возвращается обычным HTTP ответом, после - событием "error".
+*/
+type eventStream struct {
+	ctx     context.Context
+	w       http.ResponseWriter
+	flusher http.Flusher
+	req     *pb.SubscribeRequest
+
+	mu       sync.Mutex
+	received bool
+	opened   bool
+	closed   bool
+	header   metadata.MD
+	trailer  metadata.MD
+	done     chan struct{}
+}
+
+func newEventStream(ctx context.Context, w http.ResponseWriter, req *pb.SubscribeRequest) (*eventStream, error) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, status.Error(codes.Internal, "streaming is not supported")
+	}
+
+	return &eventStream{
+		ctx:     ctx,
+		w:       w,
+		flusher: flusher,
+		req:     req,
+		done:    make(chan struct{}),
+	}, nil
+}
+
+func (s *eventStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *eventStream) RecvMsg(m interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.received {
+		return io.EOF
+	}
+	s.received = true
+
+	proto.Merge(m.(proto.Message), s.req)
+
+	return nil
+}
+
+// open отправляет заголовки ответа при SendHeader после подписки или с первым событием,
+// дальше ошибки - только событиями
+func (s *eventStream) open() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.opened || s.closed {
+		return
+	}
+	s.opened = true
+
+	h := s.w.Header()
+	setMetadata(h, s.header)
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("X-Accel-Buffering", "no")
+
+	s.w.WriteHeader(http.StatusOK)
+	s.flusher.Flush()
+
+	go s.keepAlive()
+}
+
+func (s *eventStream) SendMsg(m interface{}) error {
+	data, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(m.(proto.Message))
+	if err != nil {
+		return err
+	}
+
+	s.open()
+
+	return s.write("event: message\ndata: %s\n\n", data)
+}
+
+// keepAlive - комментарий SSE, чтобы прокси не закрывали молчащий поток
+func (s *eventStream) keepAlive() {
+	ticker := time.NewTicker(keepAliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if s.write(": keep-alive\n\n") != nil {
+				return
+			}
+		case <-s.done:
+			return
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *eventStream) write(format string, args ...interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return errStreamClosed
+	}
+
+	if _, err := fmt.Fprintf(s.w, format, args...); err != nil {
+		return err
+	}
+	s.flusher.Flush()
+
+	return nil
+}
+
+// fail - итог обработчика: HTTP ошибка или событие "error", если поток уже открыт
+func (s *eventStream) fail(err error) {
+	if err == nil || s.ctx.Err() != nil {
+		return
+	}
+
+	s.mu.Lock()
+	opened := s.opened
+	trailer := s.trailer
+	s.mu.Unlock()
+
+	if !opened {
+		writeError(s.w, err, trailer)
+		return
+	}
+
+	data, _ := json.Marshal(errorBody(err))
+	_ = s.write("event: error\ndata: %s\n\n", data)
+}
+
+// close - после выхода из HTTP обработчика в ResponseWriter писать нельзя
+func (s *eventStream) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.closed {
+		s.closed = true
+		close(s.done)
+	}
+}
+
+func (s *eventStream) SetHeader(md metadata.MD) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.opened {
+		return errors.New("headers already sent")
+	}
+	s.header = metadata.Join(s.header, md)
+
+	return nil
+}
+
+func (s *eventStream) SendHeader(md metadata.MD) error {
+	if err := s.SetHeader(md); err != nil {
+		return err
+	}
+
+	s.open()
+
+	return nil
+}
+
+func (s *eventStream) SetTrailer(md metadata.MD) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.trailer = metadata.Join(s.trailer, md)
+}
+
+/*
+transportStream
+
+grpc.ServerTransportStream для unary вызова: grpc.SetHeader
+и grpc.SetTrailer в interceptor попадают в заголовки HTTP ответа.
+*/
+type transportStream struct {
+	method  string
+	mu      sync.Mutex
+	trailer metadata.MD
+}
+
+func (t *transportStream) Method() string {
+	return t.method
+}
+
+func (t *transportStream) SetHeader(md metadata.MD) error {
+	return t.SetTrailer(md)
+}
+
+func (t *transportStream) SendHeader(md metadata.MD) error {
+	return t.SetTrailer(md)
+}
+
+func (t *transportStream) SetTrailer(md metadata.MD) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.trailer = metadata.Join(t.trailer, md)
+
+	return nil
+}
@@ -0,0 +1,521 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"VK_task/internal/grpc/middleware/ratelimit"
+	pb "VK_task/pkg/api/pubsub"
+
+	"github.com/gorilla/websocket"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+const writeTimeout = 10 * time.Second
+
+// Типы кадров WebSocket протокола
+const (
+	frameSubscribe   = "subscribe"
+	frameUnsubscribe = "unsubscribe"
+	framePublish     = "publish"
+	frameEvent       = "event"
+	frameError       = "error"
+)
+
+/*
+frame - JSON кадр WebSocket, в обе стороны.
+
+Клиент:
+
+	{"type": "subscribe", "id": 1, "sid": "s1", "key": "orders.*", "group": "", "start": "earliest"}
+	{"type": "unsubscribe", "id": 2, "sid": "s1"}
+	{"type": "publish", "id": 3, "key": "orders.created", "data": "...", "ttl": "30s", "retain": true}
+
+id запроса обязателен и не должен совпадать с id запроса, ещё ждущего
+ответа. Сервер подтверждает запрос кадром того же типа с его id
+(publish - с delivered), события приходят кадром event с sid подписки:
+
+	{"type": "event", "sid": "s1", "key": "orders.created", "data": "...", "offset": 0}
+
+Сохранённое значение, отданное при подписке, - event с "retain": true.
+
+Ошибка запроса - кадр error с id, подписки, закрытой сервером, - с sid,
+без id и sid - соединение закрывается.
+*/
+type frame struct {
+	Type string `json:"type"`
+	ID   uint64 `json:"id,omitempty"`
+	Sid  string `json:"sid,omitempty"`
+
+	Key   string `json:"key,omitempty"`
+	Group string `json:"group,omitempty"`
+	Start string `json:"start,omitempty"`
+
+	Data      string  `json:"data,omitempty"`
+	TTL       string  `json:"ttl,omitempty"`
+	Retain    bool    `json:"retain,omitempty"` // publish - сохранить значение, event - сохранённое значение
+	Offset    uint64  `json:"offset,omitempty"`
+	Delivered *uint32 `json:"delivered,omitempty"`
+
+	Code         string `json:"code,omitempty"`
+	Message      string `json:"message,omitempty"`
+	RetryAfterMs string `json:"retry_after_ms,omitempty"`
+}
+
+/*
+websocket
+
+Соединение - Session поверх JSON кадров: подписки и публикации
+проходят те же interceptor и проверки на каждое сообщение, что и gRPC поток.
+Соединение открывается после проверок при открытии потока (аутентификация),
+до этого ошибка возвращается обычным HTTP ответом.
+*/
+func (g *Gateway) websocket(w http.ResponseWriter, r *http.Request) {
+	if !websocket.IsWebSocketUpgrade(r) {
+		writeError(w, status.Error(codes.InvalidArgument, "websocket upgrade required"), nil)
+		return
+	}
+
+	if !g.conns.add() {
+		writeError(w, status.Error(codes.Unavailable, "Server stopping"), nil)
+		return
+	}
+	defer g.conns.done()
+
+	ws := &wsStream{
+		ctx:      incomingContext(websocketRequest(r)),
+		w:        w,
+		r:        r,
+		upgrader: &g.upgrader,
+		pending:  make(map[uint64]string),
+		done:     make(chan struct{}),
+	}
+
+	info := &grpc.StreamServerInfo{
+		FullMethod:     pb.PubSub_Session_FullMethodName,
+		IsClientStream: true,
+		IsServerStream: true,
+	}
+
+	err := g.stream(g.service, ws, info, func(srv interface{}, stream grpc.ServerStream) error {
+		if err := ws.upgrade(); err != nil {
+			return err
+		}
+
+		return g.service.Session(&sessionServer{ServerStream: stream})
+	})
+
+	ws.close(err)
+}
+
+/*
+websocketRequest
+
+Браузер не может задать заголовки WebSocket запроса:
+токен из query access_token передаётся как Authorization.
+*/
+func websocketRequest(r *http.Request) *http.Request {
+	token := r.URL.Query().Get("access_token")
+	if token == "" || r.Header.Get("Authorization") != "" {
+		return r
+	}
+
+	r = r.Clone(r.Context())
+	r.Header.Set("Authorization", "Bearer "+token)
+
+	return r
+}
+
+// checkOrigin - тот же хост или Origin из списка, "*" - любой
+func checkOrigin(allowed []string) func(r *http.Request) bool {
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			return true
+		}
+
+		for _, o := range allowed {
+			if o == "*" || o == origin {
+				return true
+			}
+		}
+
+		u, err := url.Parse(origin)
+
+		return err == nil && u.Host == r.Host
+	}
+}
+
+type sessionServer struct {
+	grpc.ServerStream
+}
+
+func (s *sessionServer) Send(resp *pb.SessionResponse) error {
+	return s.ServerStream.SendMsg(resp)
+}
+
+func (s *sessionServer) Recv() (*pb.SessionRequest, error) {
+	req := new(pb.SessionRequest)
+	if err := s.ServerStream.RecvMsg(req); err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+/*
+wsStream
+
+grpc.ServerStream поверх WebSocket соединения: RecvMsg читает кадры
+клиента в SessionRequest, SendMsg пишет SessionResponse кадром.
+Некорректный кадр - ответ error, соединение остаётся открытым.
+*/
+type wsStream struct {
+	ctx      context.Context
+	w        http.ResponseWriter
+	r        *http.Request
+	upgrader *websocket.Upgrader
+	conn     *websocket.Conn // nil до upgrade
+
+	writeMu sync.Mutex
+
+	mu      sync.Mutex
+	failed  bool              // upgrade не удался, ответ уже записан
+	closed  bool              // после close писать нельзя
+	pending map[uint64]string // тип запроса по id до ответа
+	header  metadata.MD
+	trailer metadata.MD
+	done    chan struct{}
+}
+
+func (s *wsStream) upgrade() error {
+	s.mu.Lock()
+	h := make(http.Header)
+	setMetadata(h, s.header)
+	s.mu.Unlock()
+
+	conn, err := s.upgrader.Upgrade(s.w, s.r, h)
+	if err != nil {
+		s.mu.Lock()
+		s.failed = true
+		s.mu.Unlock()
+
+		return status.Error(codes.InvalidArgument, "websocket upgrade failed")
+	}
+
+	conn.SetReadLimit(maxBodySize)
+
+	s.mu.Lock()
+	s.conn = conn
+	s.mu.Unlock()
+
+	go s.keepAlive()
+
+	return nil
+}
+
+func (s *wsStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *wsStream) RecvMsg(m interface{}) error {
+	for {
+		msgType, data, err := s.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				return io.EOF
+			}
+
+			return status.Error(codes.Canceled, "connection closed")
+		}
+
+		if msgType != websocket.TextMessage {
+			s.writeFrame(frame{Type: frameError, Code: codes.InvalidArgument.String(), Message: "text frames only"})
+			continue
+		}
+
+		var f frame
+		if err := json.Unmarshal(data, &f); err != nil {
+			s.writeFrame(frame{Type: frameError, Code: codes.InvalidArgument.String(), Message: "invalid JSON frame"})
+			continue
+		}
+
+		// Ответ находится по id: без него или с id запроса, ещё ждущего ответа,
+		// кадр не принимается, иначе ответы перепутались бы
+		if f.ID == 0 {
+			s.writeFrame(frame{Type: frameError, Code: codes.InvalidArgument.String(), Message: "id required"})
+			continue
+		}
+
+		req, err := sessionRequest(f)
+		if err != nil {
+			st := status.Convert(err)
+			s.writeFrame(frame{Type: frameError, ID: f.ID, Code: st.Code().String(), Message: st.Message()})
+			continue
+		}
+
+		s.mu.Lock()
+		_, inUse := s.pending[f.ID]
+		if !inUse {
+			s.pending[f.ID] = f.Type
+		}
+		s.mu.Unlock()
+
+		if inUse {
+			s.writeFrame(frame{Type: frameError, ID: f.ID, Code: codes.InvalidArgument.String(), Message: "id already in use"})
+			continue
+		}
+
+		proto.Merge(m.(proto.Message), req)
+
+		return nil
+	}
+}
+
+// sessionRequest - кадр клиента как запрос Session
+func sessionRequest(f frame) (*pb.SessionRequest, error) {
+	req := &pb.SessionRequest{RequestId: f.ID}
+
+	switch f.Type {
+	case frameSubscribe:
+		sub := &pb.SubscribeRequest{Key: f.Key, Group: f.Group}
+		if err := setStart(sub, f.Start); err != nil {
+			return nil, err
+		}
+
+		req.Frame = &pb.SessionRequest_Subscribe{Subscribe: &pb.SessionSubscribe{
+			Sid:       f.Sid,
+			Subscribe: sub,
+		}}
+
+	case frameUnsubscribe:
+		req.Frame = &pb.SessionRequest_Unsubscribe{Unsubscribe: &pb.SessionUnsubscribe{Sid: f.Sid}}
+
+	case framePublish:
+		ttl, err := parseTTL(f.TTL)
+		if err != nil {
+			return nil, err
+		}
+
+		req.Frame = &pb.SessionRequest_Publish{Publish: &pb.PublishRequest{Key: f.Key, Data: f.Data, Ttl: ttl, Retain: f.Retain}}
+
+	default:
+		return nil, status.Error(codes.InvalidArgument, "unknown frame type")
+	}
+
+	return req, nil
+}
+
+func (s *wsStream) SendMsg(m interface{}) error {
+	switch resp := m.(*pb.SessionResponse).Frame.(type) {
+	case *pb.SessionResponse_Event:
+		return s.writeFrame(frame{
+			Type:   frameEvent,
+			Sid:    resp.Event.GetSid(),
+			Key:    resp.Event.GetEvent().GetKey(),
+			Data:   resp.Event.GetEvent().GetData(),
+			Offset: resp.Event.GetEvent().GetOffset(),
+			Retain: resp.Event.GetEvent().GetRetained(),
+		})
+
+	case *pb.SessionResponse_Reply:
+		reply := resp.Reply
+
+		s.mu.Lock()
+		reqType := s.pending[reply.RequestId]
+		delete(s.pending, reply.RequestId)
+		s.mu.Unlock()
+
+		if code := codes.Code(reply.Code); code != codes.OK {
+			return s.writeFrame(frame{Type: frameError, ID: reply.RequestId, Code: code.String(), Message: reply.Message})
+		}
+
+		f := frame{Type: reqType, ID: reply.RequestId}
+		if reqType == framePublish {
+			f.Delivered = &reply.Delivered
+		}
+
+		return s.writeFrame(f)
+
+	case *pb.SessionResponse_Closed:
+		closed := resp.Closed
+
+		return s.writeFrame(frame{
+			Type:    frameError,
+			Sid:     closed.Sid,
+			Code:    codes.Code(closed.Code).String(),
+			Message: closed.Message,
+		})
+	}
+
+	return nil
+}
+
+func (s *wsStream) writeFrame(f frame) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	s.mu.Lock()
+	closed := s.closed
+	s.mu.Unlock()
+
+	if closed {
+		return errStreamClosed
+	}
+
+	_ = s.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+
+	return s.conn.WriteJSON(f)
+}
+
+// keepAlive - ping, чтобы прокси не закрывали молчащее соединение
+func (s *wsStream) keepAlive() {
+	ticker := time.NewTicker(keepAliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if s.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeTimeout)) != nil {
+				return
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
+
+/*
+close - итог обработчика
+
+До upgrade - HTTP ошибка, после - кадр error и close frame:
+остановка сервера - 1001 (going away), отказ политики или лимита - 1008,
+прочее - 1011.
+*/
+func (s *wsStream) close(err error) {
+	s.mu.Lock()
+	conn, failed, trailer := s.conn, s.failed, s.trailer
+	s.mu.Unlock()
+
+	if conn == nil {
+		if !failed && err != nil {
+			writeError(s.w, err, trailer)
+		}
+
+		return
+	}
+
+	closeCode, reason := websocket.CloseNormalClosure, ""
+
+	if err != nil {
+		st := status.Convert(err)
+
+		f := frame{Type: frameError, Code: st.Code().String(), Message: st.Message()}
+		if ms := trailer.Get(ratelimit.RetryAfterKey); len(ms) > 0 {
+			f.RetryAfterMs = ms[0]
+		}
+		_ = s.writeFrame(f)
+
+		closeCode, reason = closeStatus(st.Code()), st.Message()
+	}
+
+	s.mu.Lock()
+	s.closed = true
+	close(s.done)
+	s.mu.Unlock()
+
+	_ = conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(closeCode, reason), time.Now().Add(writeTimeout))
+	_ = conn.Close()
+}
+
+func closeStatus(code codes.Code) int {
+	switch code {
+	case codes.Canceled, codes.Unavailable:
+		return websocket.CloseGoingAway
+	case codes.Unauthenticated, codes.PermissionDenied, codes.ResourceExhausted, codes.InvalidArgument:
+		return websocket.ClosePolicyViolation
+	default:
+		return websocket.CloseInternalServerErr
+	}
+}
+
+func (s *wsStream) SetHeader(md metadata.MD) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn != nil {
+		return errors.New("headers already sent")
+	}
+	s.header = metadata.Join(s.header, md)
+
+	return nil
+}
+
+func (s *wsStream) SendHeader(md metadata.MD) error {
+	return s.SetHeader(md)
+}
+
+func (s *wsStream) SetTrailer(md metadata.MD) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.trailer = metadata.Join(s.trailer, md)
+}
+
+/*
+connections
+
+Соединения WebSocket после upgrade не отслеживает http.Server.Shutdown:
+Wait ждёт их завершения, новые после начала остановки не принимаются.
+*/
+type connections struct {
+	mu      sync.Mutex
+	wg      sync.WaitGroup
+	closing bool
+}
+
+func (c *connections) add() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closing {
+		return false
+	}
+	c.wg.Add(1)
+
+	return true
+}
+
+func (c *connections) done() {
+	c.wg.Done()
+}
+
+func (c *connections) wait(ctx context.Context) error {
+	c.mu.Lock()
+	c.closing = true
+	c.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		c.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,168 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc/codes"
+)
+
+const namespace = "pubsub"
+
+// fillBuckets - доли заполненности очереди
+var fillBuckets = []float64{0, 0.1, 0.25, 0.5, 0.75, 0.9, 1}
+
+/*
+Metrics
+
+Prometheus метрики шины (реализует subpub.Metrics) и gRPC слоя.
+Каждый экземпляр со своим Registry, чтобы тесты могли создавать несколько.
+*/
+type Metrics struct {
+	registry *prometheus.Registry
+
+	published       *prometheus.CounterVec
+	delivered       *prometheus.CounterVec
+	dropped         *prometheus.CounterVec
+	handlerPanics   *prometheus.CounterVec
+	handlerDuration prometheus.Histogram
+	subjectFill     prometheus.Histogram
+	subscrFill      prometheus.Histogram
+	subscriptions   prometheus.Gauge
+
+	grpcHandled  *prometheus.CounterVec
+	grpcDuration *prometheus.HistogramVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+
+		published: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "published_total",
+			Help:      "Messages published, by subject.",
+		}, []string{"subject"}),
+
+		delivered: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "delivered_total",
+			Help:      "Messages passed to subscription handlers, by subject.",
+		}, []string{"subject"}),
+
+		dropped: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "dropped_total",
+			Help:      "Messages dropped, by subject and reason.",
+		}, []string{"subject", "reason"}),
+
+		handlerPanics: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "handler_panics_total",
+			Help:      "Panics recovered in subscription handlers, by subject.",
+		}, []string{"subject"}),
+
+		handlerDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "handler_duration_seconds",
+			Help:      "Subscription handler latency.",
+			Buckets:   prometheus.DefBuckets,
+		}),
+
+		subjectFill: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "subject_queue_fill_ratio",
+			Help:      "Subject queue fill level (len/cap) when a message is enqueued.",
+			Buckets:   fillBuckets,
+		}),
+
+		subscrFill: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "subscription_queue_fill_ratio",
+			Help:      "Subscription queue fill level (len/cap) when a message is enqueued.",
+			Buckets:   fillBuckets,
+		}),
+
+		subscriptions: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "subscriptions_active",
+			Help:      "Active subscriptions.",
+		}),
+
+		grpcHandled: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "grpc_handled_total",
+			Help:      "Completed gRPC calls, by method and status code.",
+		}, []string{"method", "code"}),
+
+		grpcDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "grpc_handling_seconds",
+			Help:      "gRPC call duration (stream lifetime for streaming calls), by method.",
+			Buckets:   []float64{.001, .005, .01, .05, .1, .5, 1, 5, 30, 60, 300, 1800},
+		}, []string{"method"}),
+	}
+
+	m.registry.MustRegister(
+		m.published,
+		m.delivered,
+		m.dropped,
+		m.handlerPanics,
+		m.handlerDuration,
+		m.subjectFill,
+		m.subscrFill,
+		m.subscriptions,
+		m.grpcHandled,
+		m.grpcDuration,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	return m
+}
+
+// Handler - HTTP обработчик /metrics
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+func (m *Metrics) Published(subject string) {
+	m.published.WithLabelValues(subject).Inc()
+}
+
+func (m *Metrics) Delivered(subject string) {
+	m.delivered.WithLabelValues(subject).Inc()
+}
+
+func (m *Metrics) Dropped(subject, reason string) {
+	m.dropped.WithLabelValues(subject, reason).Inc()
+}
+
+func (m *Metrics) HandlerPanic(subject string) {
+	m.handlerPanics.WithLabelValues(subject).Inc()
+}
+
+func (m *Metrics) HandlerDuration(d time.Duration) {
+	m.handlerDuration.Observe(d.Seconds())
+}
+
+func (m *Metrics) SubjectQueueFill(ratio float64) {
+	m.subjectFill.Observe(ratio)
+}
+
+func (m *Metrics) SubscriptionQueueFill(ratio float64) {
+	m.subscrFill.Observe(ratio)
+}
+
+func (m *Metrics) Subscriptions(delta int) {
+	m.subscriptions.Add(float64(delta))
+}
+
+// GRPCHandled - завершение gRPC вызова
+func (m *Metrics) GRPCHandled(method string, code codes.Code, d time.Duration) {
+	m.grpcHandled.WithLabelValues(method, code.String()).Inc()
+	m.grpcDuration.WithLabelValues(method).Observe(d.Seconds())
+}
@@ -0,0 +1,112 @@
+package tests
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	pb "VK_task/pkg/api/pubsub"
+	pbadmin "VK_task/pkg/api/pubsub/admin"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// WARN: Автоматический старт сервера!
+func TestAdmin(t *testing.T) {
+	_, port, appStop := startTestApp(devConfigPath) // ⚠️
+	defer appStop()
+
+	client, cleanup := newPubSubClient(t, grpcHost, port)
+	defer cleanup()
+
+	cc, err := grpc.NewClient(
+		net.JoinHostPort(grpcHost, strconv.Itoa(port)),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	defer cc.Close()
+
+	admin := pbadmin.NewAdminClient(cc)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	orders, err := client.Subscribe(ctx, &pb.SubscribeRequest{Key: "admin.orders"})
+	require.NoError(t, err)
+
+	payments, err := client.Subscribe(ctx, &pb.SubscribeRequest{Key: "admin.payments", Group: "workers"})
+	require.NoError(t, err)
+
+	time.Sleep(100 * time.Millisecond)
+
+	t.Run("List subjects", func(t *testing.T) {
+		resp, err := admin.ListSubjects(ctx, &pbadmin.ListSubjectsRequest{})
+		require.NoError(t, err)
+
+		subjects := make(map[string]*pbadmin.Subject)
+		for _, s := range resp.Subjects {
+			subjects[s.Key] = s
+		}
+
+		require.Contains(t, subjects, "admin.orders")
+		assert.Equal(t, uint32(1), subjects["admin.orders"].Subscribers)
+		assert.NotZero(t, subjects["admin.orders"].QueueCapacity)
+
+		require.Contains(t, subjects, "admin.payments")
+		assert.Equal(t, uint32(1), subjects["admin.payments"].Groups)
+	})
+
+	var orderSubID string
+
+	t.Run("List subscriptions", func(t *testing.T) {
+		_, err := client.Publish(ctx, &pb.PublishRequest{Key: "admin.orders", Data: "data"})
+		require.NoError(t, err)
+
+		event, err := orders.Recv()
+		require.NoError(t, err)
+		assert.Equal(t, "data", event.Data)
+
+		resp, err := admin.ListSubscriptions(ctx, &pbadmin.ListSubscriptionsRequest{Key: "admin.orders"})
+		require.NoError(t, err)
+		require.Len(t, resp.Subscriptions, 1)
+
+		sub := resp.Subscriptions[0]
+		assert.NotEmpty(t, sub.Id)
+		assert.Equal(t, "admin.orders", sub.Key)
+		assert.Contains(t, sub.Peer, "127.0.0.1:")
+		assert.Equal(t, uint64(1), sub.Delivered)
+		assert.Zero(t, sub.Dropped)
+
+		orderSubID = sub.Id
+	})
+
+	t.Run("Close subscription", func(t *testing.T) {
+		_, err := admin.CloseSubscription(ctx, &pbadmin.CloseSubscriptionRequest{Id: "unknown"})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+
+		_, err = admin.CloseSubscription(ctx, &pbadmin.CloseSubscriptionRequest{Id: orderSubID})
+		require.NoError(t, err)
+
+		_, err = orders.Recv()
+		assert.Equal(t, codes.Aborted, status.Code(err))
+	})
+
+	t.Run("Drain subject", func(t *testing.T) {
+		resp, err := admin.DrainSubject(ctx, &pbadmin.DrainSubjectRequest{Key: "admin.payments"})
+		require.NoError(t, err)
+		assert.Equal(t, uint32(1), resp.Closed)
+
+		_, err = payments.Recv()
+		assert.Equal(t, codes.Aborted, status.Code(err))
+
+		_, err = admin.DrainSubject(ctx, &pbadmin.DrainSubjectRequest{Key: "admin.payments"})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+}
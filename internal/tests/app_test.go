@@ -2,8 +2,10 @@ package tests
 
 import (
 	"context"
+	"io"
 	"log/slog"
 	"net"
+	"net/http"
 	"strconv"
 	"testing"
 	"time"
@@ -12,6 +14,7 @@ import (
 	"VK_task/internal/config"
 	"VK_task/internal/pkg/logger"
 	pb "VK_task/pkg/api/pubsub"
+	pbv2 "VK_task/pkg/api/pubsub/v2"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -19,6 +22,7 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
 )
 
 const (
@@ -26,6 +30,8 @@ const (
 
 	grpcHost = "localhost"
 	grpcPort = 8082
+
+	metricsURL = "http://localhost:9090/metrics"
 )
 
 // Для теста нужен запущенный сервер
@@ -95,6 +101,90 @@ func TestPubSubIntegration(t *testing.T) {
 		assert.Equal(t, "second message", event.Data)
 	})
 
+	t.Run("Publish with ttl", func(t *testing.T) {
+		subCtx, subCancel := context.WithCancel(ctx)
+		defer subCancel()
+
+		stream, err := client.Subscribe(subCtx, &pb.SubscribeRequest{Key: "ttl"})
+		require.NoError(t, err)
+
+		// Wait to goroutine start
+		time.Sleep(100 * time.Millisecond)
+
+		_, err = client.Publish(ctx, &pb.PublishRequest{Key: "ttl", Data: "data", Ttl: durationpb.New(-time.Second)})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+		_, err = client.Publish(ctx, &pb.PublishRequest{Key: "ttl", Data: "data", Ttl: durationpb.New(time.Minute)})
+		require.NoError(t, err)
+
+		event, err := stream.Recv()
+		require.NoError(t, err)
+		assert.Equal(t, "data", event.Data)
+	})
+
+	t.Run("Retained message", func(t *testing.T) {
+		// Сервер общий для прогонов: значение с прошлого запуска удаляется
+		_, err := client.Publish(ctx, &pb.PublishRequest{Key: "retained.status", Retain: true})
+		require.NoError(t, err)
+
+		resp, err := client.Publish(ctx, &pb.PublishRequest{Key: "retained.status", Data: "ready", Retain: true})
+		require.NoError(t, err)
+		assert.Equal(t, uint32(0), resp.Delivered)
+
+		subCtx, subCancel := context.WithCancel(ctx)
+		defer subCancel()
+
+		stream, err := client.Subscribe(subCtx, &pb.SubscribeRequest{Key: "retained.*"})
+		require.NoError(t, err)
+
+		event, err := stream.Recv()
+		require.NoError(t, err)
+		assert.Equal(t, "ready", event.Data)
+		assert.True(t, event.Retained)
+
+		_, err = client.Publish(ctx, &pb.PublishRequest{Key: "retained.status", Retain: true})
+		require.NoError(t, err)
+
+		emptyCtx, emptyCancel := context.WithTimeout(ctx, 200*time.Millisecond)
+		defer emptyCancel()
+
+		empty, err := client.Subscribe(emptyCtx, &pb.SubscribeRequest{Key: "retained.*"})
+		require.NoError(t, err)
+
+		_, err = empty.Recv()
+		assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+	})
+
+	t.Run("Request and reply", func(t *testing.T) {
+		subCtx, subCancel := context.WithCancel(ctx)
+		defer subCancel()
+
+		stream, err := client.Subscribe(subCtx, &pb.SubscribeRequest{Key: "rpc.echo"})
+		require.NoError(t, err)
+
+		go func() {
+			event, err := stream.Recv()
+			if err != nil {
+				return
+			}
+
+			_, _ = client.Publish(subCtx, &pb.PublishRequest{Key: event.ReplyTo, Data: "echo: " + event.Data})
+		}()
+
+		// Подписка регистрируется на сервере асинхронно
+		time.Sleep(100 * time.Millisecond)
+
+		reqCtx, reqCancel := context.WithTimeout(ctx, time.Second)
+		defer reqCancel()
+
+		reply, err := client.Request(reqCtx, &pb.RequestMessage{Key: "rpc.echo", Data: "ping"})
+		require.NoError(t, err)
+		assert.Equal(t, "echo: ping", reply.Data)
+
+		_, err = client.Request(reqCtx, &pb.RequestMessage{Key: "rpc.nobody", Data: "ping"})
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+	})
+
 	t.Run("Subscribe and cancel context", func(t *testing.T) {
 		subCtx, subCancel := context.WithCancel(ctx)
 		stream, err := client.Subscribe(subCtx, &pb.SubscribeRequest{Key: "test"})
@@ -108,6 +198,45 @@ func TestPubSubIntegration(t *testing.T) {
 		assert.Equal(t, codes.Canceled, status.Code(err))
 	})
 
+	t.Run("SubscribeAck redelivers after nack", func(t *testing.T) {
+		subCtx, subCancel := context.WithCancel(ctx)
+		defer subCancel()
+
+		stream, err := client.SubscribeAck(subCtx)
+		require.NoError(t, err)
+
+		err = stream.Send(&pb.SubscribeAckRequest{
+			Request: &pb.SubscribeAckRequest_Subscribe{Subscribe: &pb.SubscribeRequest{Key: "ack"}},
+		})
+		require.NoError(t, err)
+
+		// Wait to goroutine start
+		time.Sleep(100 * time.Millisecond)
+
+		_, err = client.Publish(ctx, &pb.PublishRequest{Key: "ack", Data: "ack message"})
+		require.NoError(t, err)
+
+		event, err := stream.Recv()
+		require.NoError(t, err)
+		assert.Equal(t, "ack message", event.Data)
+		assert.Equal(t, uint32(1), event.Attempt)
+
+		err = stream.Send(&pb.SubscribeAckRequest{
+			Request: &pb.SubscribeAckRequest_Ack{Ack: &pb.Ack{Tag: event.Tag, Nack: true}},
+		})
+		require.NoError(t, err)
+
+		redelivered, err := stream.Recv()
+		require.NoError(t, err)
+		assert.Equal(t, event.Tag, redelivered.Tag)
+		assert.Equal(t, uint32(2), redelivered.Attempt)
+
+		err = stream.Send(&pb.SubscribeAckRequest{
+			Request: &pb.SubscribeAckRequest_Ack{Ack: &pb.Ack{Tag: redelivered.Tag}},
+		})
+		require.NoError(t, err)
+	})
+
 	t.Run("Publish to non-existent subject", func(t *testing.T) {
 		_, err := client.Publish(ctx, &pb.PublishRequest{Key: "nonexistent", Data: "data"})
 		assert.Error(t, err)
@@ -159,6 +288,366 @@ func TestMultipleSubscribers(t *testing.T) {
 	assert.Equal(t, "broadcast", event2.Data)
 }
 
+// Для теста нужен запущенный сервер
+func TestQueueGroupSubscribers(t *testing.T) {
+	client, cleanup := newPubSubClient(t, grpcHost, grpcPort)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	events := make(chan *pb.Event, 4)
+
+	// Two members of the same group
+	for i := 0; i < 2; i++ {
+		stream, err := client.Subscribe(ctx, &pb.SubscribeRequest{Key: "jobs", Group: "workers"})
+		require.NoError(t, err)
+
+		go func() {
+			for {
+				event, err := stream.Recv()
+				if err != nil {
+					return
+				}
+				events <- event
+			}
+		}()
+	}
+
+	// Wait to goroutine start
+	time.Sleep(100 * time.Millisecond)
+
+	resp, err := client.Publish(ctx, &pb.PublishRequest{Key: "jobs", Data: "job"})
+	require.NoError(t, err)
+	assert.Equal(t, uint32(1), resp.Delivered)
+
+	event := <-events
+	assert.Equal(t, "job", event.Data)
+
+	select {
+	case <-events:
+		t.Fatal("message delivered to more than one group member")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+// Для теста нужен запущенный сервер
+func TestPubSubV2(t *testing.T) {
+	client, cleanup := newPubSubClient(t, grpcHost, grpcPort)
+	defer cleanup()
+
+	clientV2, cleanupV2 := newPubSubV2Client(t, grpcHost, grpcPort)
+	defer cleanupV2()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	t.Run("Binary payload with headers", func(t *testing.T) {
+		subCtx, subCancel := context.WithCancel(ctx)
+		defer subCancel()
+
+		stream, err := clientV2.Subscribe(subCtx, &pbv2.SubscribeRequest{Key: "v2.binary"})
+		require.NoError(t, err)
+
+		// Wait to goroutine start
+		time.Sleep(100 * time.Millisecond)
+
+		resp, err := clientV2.Publish(ctx, &pbv2.PublishRequest{
+			Key:         "v2.binary",
+			Data:        []byte{0, 0xff, 1},
+			Headers:     map[string]string{"trace": "abc"},
+			ContentType: "application/octet-stream",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, uint32(1), resp.Delivered)
+		assert.NotEmpty(t, resp.Id)
+
+		event, err := stream.Recv()
+		require.NoError(t, err)
+		assert.Equal(t, resp.Id, event.Id)
+		assert.Equal(t, "v2.binary", event.Key)
+		assert.Equal(t, []byte{0, 0xff, 1}, event.Data)
+		assert.Equal(t, map[string]string{"trace": "abc"}, event.Headers)
+		assert.Equal(t, "application/octet-stream", event.ContentType)
+		assert.True(t, resp.Timestamp.AsTime().Equal(event.Timestamp.AsTime()))
+	})
+
+	t.Run("v1 publish to v2 subscriber", func(t *testing.T) {
+		subCtx, subCancel := context.WithCancel(ctx)
+		defer subCancel()
+
+		stream, err := clientV2.Subscribe(subCtx, &pbv2.SubscribeRequest{Key: "v2.compat"})
+		require.NoError(t, err)
+
+		// Wait to goroutine start
+		time.Sleep(100 * time.Millisecond)
+
+		_, err = client.Publish(ctx, &pb.PublishRequest{Key: "v2.compat", Data: "text"})
+		require.NoError(t, err)
+
+		event, err := stream.Recv()
+		require.NoError(t, err)
+		assert.Equal(t, []byte("text"), event.Data)
+		assert.NotEmpty(t, event.Id)
+	})
+
+	t.Run("v2 publish to v1 subscriber", func(t *testing.T) {
+		subCtx, subCancel := context.WithCancel(ctx)
+		defer subCancel()
+
+		stream, err := client.Subscribe(subCtx, &pb.SubscribeRequest{Key: "v2.compat"})
+		require.NoError(t, err)
+
+		// Wait to goroutine start
+		time.Sleep(100 * time.Millisecond)
+
+		_, err = clientV2.Publish(ctx, &pbv2.PublishRequest{Key: "v2.compat", Data: []byte("text")})
+		require.NoError(t, err)
+
+		event, err := stream.Recv()
+		require.NoError(t, err)
+		assert.Equal(t, "text", event.Data)
+	})
+
+	t.Run("Publish with empty key", func(t *testing.T) {
+		_, err := clientV2.Publish(ctx, &pbv2.PublishRequest{Data: []byte("data")})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
+// Для теста нужен запущенный сервер
+func TestSession(t *testing.T) {
+	client, cleanup := newPubSubClient(t, grpcHost, grpcPort)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := client.Session(ctx)
+	require.NoError(t, err)
+
+	var requestID uint64
+	request := func(req *pb.SessionRequest) uint64 {
+		requestID++
+		req.RequestId = requestID
+		require.NoError(t, stream.Send(req))
+		return requestID
+	}
+
+	// Events may arrive before the reply
+	var events []*pb.SessionEvent
+	reply := func(id uint64) *pb.SessionReply {
+		for {
+			resp, err := stream.Recv()
+			require.NoError(t, err)
+
+			if event := resp.GetEvent(); event != nil {
+				events = append(events, event)
+				continue
+			}
+			if r := resp.GetReply(); r != nil && r.RequestId == id {
+				return r
+			}
+		}
+	}
+	event := func() *pb.SessionEvent {
+		if len(events) > 0 {
+			event := events[0]
+			events = events[1:]
+			return event
+		}
+
+		resp, err := stream.Recv()
+		require.NoError(t, err)
+		require.NotNil(t, resp.GetEvent())
+		return resp.GetEvent()
+	}
+	subscribe := func(sid, key string) *pb.SessionReply {
+		return reply(request(&pb.SessionRequest{Frame: &pb.SessionRequest_Subscribe{
+			Subscribe: &pb.SessionSubscribe{Sid: sid, Subscribe: &pb.SubscribeRequest{Key: key}},
+		}}))
+	}
+	publish := func(key, data string) *pb.SessionReply {
+		return reply(request(&pb.SessionRequest{Frame: &pb.SessionRequest_Publish{
+			Publish: &pb.PublishRequest{Key: key, Data: data},
+		}}))
+	}
+
+	t.Run("Subscribe and publish", func(t *testing.T) {
+		assert.Equal(t, uint32(codes.OK), subscribe("a", "session.a").Code)
+		assert.Equal(t, uint32(codes.OK), subscribe("b", "session.*").Code)
+
+		r := publish("session.a", "hello")
+		assert.Equal(t, uint32(codes.OK), r.Code)
+		assert.Equal(t, uint32(2), r.Delivered)
+
+		sids := map[string]string{}
+		for i := 0; i < 2; i++ {
+			e := event()
+			sids[e.Sid] = e.Event.Data
+		}
+		assert.Equal(t, map[string]string{"a": "hello", "b": "hello"}, sids)
+	})
+
+	t.Run("Duplicate sid", func(t *testing.T) {
+		assert.Equal(t, uint32(codes.AlreadyExists), subscribe("a", "session.c").Code)
+	})
+
+	t.Run("Unsubscribe", func(t *testing.T) {
+		r := reply(request(&pb.SessionRequest{Frame: &pb.SessionRequest_Unsubscribe{
+			Unsubscribe: &pb.SessionUnsubscribe{Sid: "a"},
+		}}))
+		assert.Equal(t, uint32(codes.OK), r.Code)
+
+		r = publish("session.a", "second")
+		assert.Equal(t, uint32(1), r.Delivered)
+
+		e := event()
+		assert.Equal(t, "b", e.Sid)
+		assert.Equal(t, "second", e.Event.Data)
+	})
+
+	t.Run("Invalid publish", func(t *testing.T) {
+		assert.Equal(t, uint32(codes.InvalidArgument), publish("", "data").Code)
+	})
+
+	t.Run("Subscribe reply before events", func(t *testing.T) {
+		_, err := client.Publish(ctx, &pb.PublishRequest{Key: "session.retained", Data: "ready", Retain: true})
+		require.NoError(t, err)
+		defer client.Publish(ctx, &pb.PublishRequest{Key: "session.retained", Retain: true})
+
+		// Retained событие готово сразу, но приходит только после ответа на subscribe
+		for i := 0; i < 20; i++ {
+			sid := "retained-" + strconv.Itoa(i)
+			id := request(&pb.SessionRequest{Frame: &pb.SessionRequest_Subscribe{
+				Subscribe: &pb.SessionSubscribe{Sid: sid, Subscribe: &pb.SubscribeRequest{Key: "session.retained"}},
+			}})
+
+			for replied := false; !replied; {
+				resp, err := stream.Recv()
+				require.NoError(t, err)
+
+				if e := resp.GetEvent(); e != nil {
+					require.NotEqual(t, sid, e.Sid, "event before subscribe reply")
+					continue
+				}
+				if r := resp.GetReply(); r != nil && r.RequestId == id {
+					require.Equal(t, uint32(codes.OK), r.Code)
+					replied = true
+				}
+			}
+
+			e := event()
+			assert.Equal(t, sid, e.Sid)
+			assert.Equal(t, "ready", e.Event.Data)
+
+			r := reply(request(&pb.SessionRequest{Frame: &pb.SessionRequest_Unsubscribe{
+				Unsubscribe: &pb.SessionUnsubscribe{Sid: sid},
+			}}))
+			assert.Equal(t, uint32(codes.OK), r.Code)
+		}
+	})
+}
+
+// Для теста нужен запущенный сервер
+func TestPublishBatch(t *testing.T) {
+	client, cleanup := newPubSubClient(t, grpcHost, grpcPort)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	subCtx, subCancel := context.WithCancel(ctx)
+	defer subCancel()
+
+	stream, err := client.Subscribe(subCtx, &pb.SubscribeRequest{Key: "batch"})
+	require.NoError(t, err)
+
+	// Wait to goroutine start
+	time.Sleep(100 * time.Millisecond)
+
+	messages := []*pb.PublishRequest{
+		{Key: "batch", Data: "first"},
+		{Key: "batch.none", Data: "skip"},
+		{Key: "", Data: "skip"},
+		{Key: "batch", Data: ""},
+		{Key: "batch", Data: "second"},
+	}
+	statuses := []pb.PublishStatus{
+		pb.PublishStatus_PUBLISH_OK,
+		pb.PublishStatus_PUBLISH_NO_SUBSCRIBERS,
+		pb.PublishStatus_PUBLISH_INVALID,
+		pb.PublishStatus_PUBLISH_INVALID,
+		pb.PublishStatus_PUBLISH_OK,
+	}
+
+	t.Run("PublishBatch", func(t *testing.T) {
+		resp, err := client.PublishBatch(ctx, &pb.PublishBatchRequest{Messages: messages})
+		require.NoError(t, err)
+		require.Len(t, resp.Results, len(messages))
+
+		for i, res := range resp.Results {
+			assert.Equal(t, statuses[i], res.Status, i)
+		}
+		assert.Equal(t, uint32(1), resp.Results[0].Delivered)
+
+		for _, data := range []string{"first", "second"} {
+			event, err := stream.Recv()
+			require.NoError(t, err)
+			assert.Equal(t, data, event.Data)
+		}
+	})
+
+	t.Run("PublishStream", func(t *testing.T) {
+		pubStream, err := client.PublishStream(ctx)
+		require.NoError(t, err)
+
+		for _, req := range messages {
+			require.NoError(t, pubStream.Send(req))
+		}
+
+		resp, err := pubStream.CloseAndRecv()
+		require.NoError(t, err)
+		require.Len(t, resp.Results, len(messages))
+
+		for i, res := range resp.Results {
+			assert.Equal(t, statuses[i], res.Status, i)
+		}
+
+		for _, data := range []string{"first", "second"} {
+			event, err := stream.Recv()
+			require.NoError(t, err)
+			assert.Equal(t, data, event.Data)
+		}
+	})
+}
+
+// Для теста нужен запущенный сервер с включёнными метриками
+func TestMetricsEndpoint(t *testing.T) {
+	client, cleanup := newPubSubClient(t, grpcHost, grpcPort)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Any call is counted, even a failed one
+	_, err := client.Publish(ctx, &pb.PublishRequest{Key: "metrics", Data: "data"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	resp, err := http.Get(metricsURL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	assert.Contains(t, string(body), `pubsub_published_total{subject="metrics"}`)
+	assert.Contains(t, string(body), `pubsub_grpc_handled_total{code="InvalidArgument",method="/PubSub/Publish"}`)
+	assert.Contains(t, string(body), "pubsub_subscriptions_active")
+}
+
 // WARN: Автоматический старт сервера!
 func TestServerStopDuringSubscription(t *testing.T) {
 	_, port, appStop := startTestApp(devConfigPath) // ⚠️
@@ -202,6 +691,21 @@ func newPubSubClient(t *testing.T, host string, port int) (pb.PubSubClient, func
 	}
 }
 
+func newPubSubV2Client(t *testing.T, host string, port int) (pbv2.PubSubClient, func()) {
+	addr := net.JoinHostPort(host, strconv.Itoa(port))
+
+	cc, err := grpc.Dial(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("failed connect to grpc server: %v", err)
+	}
+
+	client := pbv2.NewPubSubClient(cc)
+
+	return client, func() {
+		cc.Close()
+	}
+}
+
 func startTestApp(path string) (string, int, func() error) {
 	cfg := config.MustLoad(path)
 
@@ -210,7 +714,7 @@ func startTestApp(path string) (string, int, func() error) {
 	log.Debug("Config", slog.Any("data", cfg))
 
 	// App
-	application := app.New(log, cfg.GRPC.Addr, cfg.GRPC.Port, cfg.SubPub.SubjectBuffer, cfg.SubPub.SubscriptionBuffer)
+	application := app.MustNew(log, cfg.GRPC, cfg.SubPub, cfg.Metrics, cfg.Tracing, cfg.Gateway)
 
 	go application.MustRun()
 
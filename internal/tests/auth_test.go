@@ -0,0 +1,191 @@
+package tests
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"VK_task/internal/app"
+	"VK_task/internal/config"
+	"VK_task/internal/pkg/logger"
+	pb "VK_task/pkg/api/pubsub"
+	pbadmin "VK_task/pkg/api/pubsub/admin"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	authTestPort = 8085
+	jwtSecret    = "test-secret"
+)
+
+const testPolicy = `
+tokens:
+  - principal: "orders-service"
+    token: "orders-token"
+jwt:
+  secret: "` + jwtSecret + `"
+  issuer: "test"
+rules:
+  - principal: "orders-service"
+    publish: ["orders.>"]
+    subscribe: ["orders.*"]
+  - principal: "reporter"
+    subscribe: ["reports.>"]
+  - principal: "ops"
+    admin: true
+`
+
+// WARN: Автоматический старт сервера!
+func TestAuthorization(t *testing.T) {
+	cfg := config.MustLoad(devConfigPath)
+	cfg.GRPC.Port = authTestPort
+	cfg.GRPC.Auth = config.Auth{
+		Enabled:    true,
+		PolicyFile: writePolicy(t, t.TempDir(), testPolicy),
+	}
+
+	log := logger.MustSetup(cfg.SLOG.Env, cfg.SLOG.File)
+
+	application := app.MustNew(log, cfg.GRPC, cfg.SubPub, cfg.Metrics, cfg.Tracing, cfg.Gateway)
+	go application.MustRun()
+	defer application.Stop(cfg.SubPub.CloseTimeout)
+
+	time.Sleep(100 * time.Millisecond)
+
+	client, cleanup := newPubSubClient(t, grpcHost, authTestPort)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	withToken := func(token string) context.Context {
+		return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+	}
+
+	t.Run("No credentials", func(t *testing.T) {
+		_, err := client.Publish(ctx, &pb.PublishRequest{Key: "orders.1", Data: "data"})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("Unknown token", func(t *testing.T) {
+		_, err := client.Publish(withToken("wrong"), &pb.PublishRequest{Key: "orders.1", Data: "data"})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("Static token publish", func(t *testing.T) {
+		// Вызов дошёл до обработчика: подписчиков нет
+		_, err := client.Publish(withToken("orders-token"), &pb.PublishRequest{Key: "orders.1", Data: "data"})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+		_, err = client.Publish(withToken("orders-token"), &pb.PublishRequest{Key: "billing.1", Data: "data"})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+
+	t.Run("Subscribe pattern must be covered", func(t *testing.T) {
+		subCtx, subCancel := context.WithCancel(withToken("orders-token"))
+		defer subCancel()
+
+		stream, err := client.Subscribe(subCtx, &pb.SubscribeRequest{Key: "orders.>"})
+		require.NoError(t, err)
+
+		_, err = stream.Recv()
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+
+	t.Run("Batch with denied subject", func(t *testing.T) {
+		_, err := client.PublishBatch(withToken("orders-token"), &pb.PublishBatchRequest{
+			Messages: []*pb.PublishRequest{
+				{Key: "orders.1", Data: "data"},
+				{Key: "billing.1", Data: "data"},
+			},
+		})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+
+	t.Run("JWT", func(t *testing.T) {
+		valid := signJWT(t, map[string]interface{}{
+			"sub": "reporter",
+			"iss": "test",
+			"exp": time.Now().Add(time.Minute).Unix(),
+		})
+
+		subCtx, subCancel := context.WithCancel(withToken(valid))
+		defer subCancel()
+
+		stream, err := client.Subscribe(subCtx, &pb.SubscribeRequest{Key: "reports.daily"})
+		require.NoError(t, err)
+
+		_, err = client.Publish(withToken(valid), &pb.PublishRequest{Key: "reports.daily", Data: "data"})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+		// Подписка разрешена: поток закрывается только отменой
+		subCancel()
+		_, err = stream.Recv()
+		assert.Equal(t, codes.Canceled, status.Code(err))
+	})
+
+	t.Run("Expired JWT", func(t *testing.T) {
+		expired := signJWT(t, map[string]interface{}{
+			"sub": "reporter",
+			"iss": "test",
+			"exp": time.Now().Add(-time.Minute).Unix(),
+		})
+
+		_, err := client.Publish(withToken(expired), &pb.PublishRequest{Key: "reports.daily", Data: "data"})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("Admin requires admin rule", func(t *testing.T) {
+		cc, err := grpc.NewClient(
+			net.JoinHostPort(grpcHost, strconv.Itoa(authTestPort)),
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+		)
+		require.NoError(t, err)
+		defer cc.Close()
+
+		admin := pbadmin.NewAdminClient(cc)
+
+		_, err = admin.ListSubjects(withToken("orders-token"), &pbadmin.ListSubjectsRequest{})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+		ops := signJWT(t, map[string]interface{}{"sub": "ops", "iss": "test"})
+
+		_, err = admin.ListSubjects(withToken(ops), &pbadmin.ListSubjectsRequest{})
+		assert.NoError(t, err)
+	})
+}
+
+func writePolicy(t *testing.T, dir, policy string) string {
+	path := filepath.Join(dir, "policy.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(policy), 0o600))
+
+	return path
+}
+
+func signJWT(t *testing.T, claims map[string]interface{}) string {
+	payload, err := json.Marshal(claims)
+	require.NoError(t, err)
+
+	enc := base64.RawURLEncoding
+	unsigned := enc.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`)) + "." + enc.EncodeToString(payload)
+
+	mac := hmac.New(sha256.New, []byte(jwtSecret))
+	mac.Write([]byte(unsigned))
+
+	return unsigned + "." + enc.EncodeToString(mac.Sum(nil))
+}
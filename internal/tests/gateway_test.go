@@ -0,0 +1,319 @@
+package tests
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"VK_task/internal/app"
+	"VK_task/internal/config"
+	"VK_task/internal/pkg/logger"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	gatewayTestGRPCPort = 8088
+	gatewayTestPort     = 8090
+)
+
+type gatewayError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// WARN: Автоматический старт сервера!
+func TestGateway(t *testing.T) {
+	cfg := config.MustLoad(devConfigPath)
+	cfg.GRPC.Port = gatewayTestGRPCPort
+	cfg.Gateway = config.Gateway{
+		Enabled: true,
+		Addr:    grpcHost,
+		Port:    gatewayTestPort,
+	}
+	cfg.GRPC.Auth = config.Auth{
+		Enabled:    true,
+		PolicyFile: writePolicy(t, t.TempDir(), testPolicy),
+	}
+
+	log := logger.MustSetup(cfg.SLOG.Env, cfg.SLOG.File)
+
+	application := app.MustNew(log, cfg.GRPC, cfg.SubPub, cfg.Metrics, cfg.Tracing, cfg.Gateway)
+	go application.MustRun()
+
+	stopped := false
+	defer func() {
+		if !stopped {
+			application.Stop(cfg.SubPub.CloseTimeout)
+		}
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+
+	baseURL := fmt.Sprintf("http://%s:%d", grpcHost, gatewayTestPort)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	do := func(t *testing.T, ctx context.Context, method, path, token, contentType, body string) *http.Response {
+		req, err := http.NewRequestWithContext(ctx, method, baseURL+path, strings.NewReader(body))
+		require.NoError(t, err)
+
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+
+		return resp
+	}
+
+	decodeError := func(t *testing.T, resp *http.Response) gatewayError {
+		defer resp.Body.Close()
+
+		var body gatewayError
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+
+		return body
+	}
+
+	t.Run("Publish and subscribe", func(t *testing.T) {
+		subCtx, subCancel := context.WithCancel(ctx)
+		defer subCancel()
+
+		resp := do(t, subCtx, http.MethodGet, "/v1/subscribe/orders.created", "orders-token", "", "")
+		defer resp.Body.Close()
+
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+		time.Sleep(100 * time.Millisecond)
+
+		pub := do(t, ctx, http.MethodPost, "/v1/publish/orders.created", "orders-token", "application/json", `{"data": "json"}`)
+		pub.Body.Close()
+		require.Equal(t, http.StatusOK, pub.StatusCode)
+
+		pub = do(t, ctx, http.MethodPost, "/v1/publish/orders.created", "orders-token", "text/plain", "text")
+		pub.Body.Close()
+		require.Equal(t, http.StatusOK, pub.StatusCode)
+
+		reader := bufio.NewReader(resp.Body)
+
+		for _, want := range []string{"json", "text"} {
+			event, data := readEvent(t, reader)
+			assert.Equal(t, "message", event)
+
+			var got struct {
+				Data string `json:"data"`
+				Key  string `json:"key"`
+			}
+			require.NoError(t, json.Unmarshal([]byte(data), &got))
+			assert.Equal(t, want, got.Data)
+			assert.Equal(t, "orders.created", got.Key)
+		}
+	})
+
+	t.Run("Publish without subscribers", func(t *testing.T) {
+		resp := do(t, ctx, http.MethodPost, "/v1/publish/orders.empty", "orders-token", "text/plain", "data")
+
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Equal(t, "InvalidArgument", decodeError(t, resp).Code)
+	})
+
+	t.Run("Invalid request", func(t *testing.T) {
+		resp := do(t, ctx, http.MethodPost, "/v1/publish/orders.created", "orders-token", "application/json", "{")
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Equal(t, "InvalidArgument", decodeError(t, resp).Code)
+
+		resp = do(t, ctx, http.MethodPost, "/v1/publish/orders.created?ttl=soon", "orders-token", "text/plain", "data")
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Equal(t, "InvalidArgument", decodeError(t, resp).Code)
+
+		resp = do(t, ctx, http.MethodGet, "/v1/subscribe/orders.created?start=latest", "orders-token", "", "")
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Equal(t, "InvalidArgument", decodeError(t, resp).Code)
+
+		resp = do(t, ctx, http.MethodGet, "/v1/publish/orders.created", "orders-token", "", "")
+		resp.Body.Close()
+		assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+	})
+
+	t.Run("Subscribe error before stream", func(t *testing.T) {
+		// Durable log выключен: ошибка Subscribe - HTTP ответ, а не событие error
+		resp := do(t, ctx, http.MethodGet, "/v1/subscribe/orders.created?start=earliest", "orders-token", "", "")
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Equal(t, "FailedPrecondition", decodeError(t, resp).Code)
+	})
+
+	t.Run("Authorization", func(t *testing.T) {
+		resp := do(t, ctx, http.MethodPost, "/v1/publish/orders.created", "", "text/plain", "data")
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+		assert.Equal(t, "Unauthenticated", decodeError(t, resp).Code)
+
+		resp = do(t, ctx, http.MethodPost, "/v1/publish/payments.created", "orders-token", "text/plain", "data")
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+		assert.Equal(t, "PermissionDenied", decodeError(t, resp).Code)
+
+		resp = do(t, ctx, http.MethodGet, "/v1/subscribe/orders.>", "orders-token", "", "")
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+		assert.Equal(t, "PermissionDenied", decodeError(t, resp).Code)
+	})
+
+	wsURL := fmt.Sprintf("ws://%s:%d/v1/ws", grpcHost, gatewayTestPort)
+
+	t.Run("WebSocket", func(t *testing.T) {
+		conn, _, err := websocket.DefaultDialer.DialContext(ctx, wsURL+"?access_token=orders-token", nil)
+		require.NoError(t, err)
+		defer conn.Close()
+
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+
+		require.NoError(t, conn.WriteJSON(wsFrame{Type: "subscribe", ID: 1, Sid: "s1", Key: "orders.*"}))
+
+		reply := readFrame(t, conn)
+		assert.Equal(t, wsFrame{Type: "subscribe", ID: 1}, reply)
+
+		pub := do(t, ctx, http.MethodPost, "/v1/publish/orders.paid", "orders-token", "text/plain", "http")
+		pub.Body.Close()
+		require.Equal(t, http.StatusOK, pub.StatusCode)
+
+		event := readFrame(t, conn)
+		assert.Equal(t, wsFrame{Type: "event", Sid: "s1", Key: "orders.paid", Data: "http"}, event)
+
+		require.NoError(t, conn.WriteJSON(wsFrame{Type: "publish", ID: 2, Key: "orders.paid", Data: "ws"}))
+
+		// Ответ на publish и событие подписки - в любом порядке
+		frames := map[string]wsFrame{}
+		for range 2 {
+			f := readFrame(t, conn)
+			frames[f.Type] = f
+		}
+
+		require.NotNil(t, frames["publish"].Delivered)
+		assert.Equal(t, uint32(1), *frames["publish"].Delivered)
+		assert.Equal(t, "ws", frames["event"].Data)
+
+		require.NoError(t, conn.WriteJSON(wsFrame{Type: "unknown", ID: 3}))
+
+		f := readFrame(t, conn)
+		assert.Equal(t, "error", f.Type)
+		assert.Equal(t, uint64(3), f.ID)
+		assert.Equal(t, "InvalidArgument", f.Code)
+
+		require.NoError(t, conn.WriteJSON(wsFrame{Type: "publish", Key: "orders.paid", Data: "no id"}))
+
+		f = readFrame(t, conn)
+		assert.Equal(t, wsFrame{Type: "error", Code: "InvalidArgument", Message: "id required"}, f)
+
+		require.NoError(t, conn.WriteJSON(wsFrame{Type: "unsubscribe", ID: 4, Sid: "s1"}))
+		assert.Equal(t, wsFrame{Type: "unsubscribe", ID: 4}, readFrame(t, conn))
+
+		require.NoError(t, conn.WriteJSON(wsFrame{Type: "unsubscribe", ID: 5, Sid: "s1"}))
+
+		f = readFrame(t, conn)
+		assert.Equal(t, "error", f.Type)
+		assert.Equal(t, "NotFound", f.Code)
+	})
+
+	t.Run("WebSocket authorization", func(t *testing.T) {
+		_, resp, err := websocket.DefaultDialer.DialContext(ctx, wsURL, nil)
+		require.ErrorIs(t, err, websocket.ErrBadHandshake)
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+		assert.Equal(t, "Unauthenticated", decodeError(t, resp).Code)
+
+		conn, _, err := websocket.DefaultDialer.DialContext(ctx, wsURL,
+			http.Header{"Authorization": {"Bearer orders-token"}})
+		require.NoError(t, err)
+		defer conn.Close()
+
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+
+		// Запрещённый subject завершает соединение, как Session
+		require.NoError(t, conn.WriteJSON(wsFrame{Type: "publish", ID: 1, Key: "payments.created", Data: "data"}))
+
+		f := readFrame(t, conn)
+		assert.Equal(t, "error", f.Type)
+		assert.Equal(t, "PermissionDenied", f.Code)
+
+		_, _, err = conn.ReadMessage()
+		assert.True(t, websocket.IsCloseError(err, websocket.ClosePolicyViolation), err)
+	})
+
+	t.Run("WebSocket server stop", func(t *testing.T) {
+		conn, _, err := websocket.DefaultDialer.DialContext(ctx, wsURL+"?access_token=orders-token", nil)
+		require.NoError(t, err)
+		defer conn.Close()
+
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+
+		require.NoError(t, conn.WriteJSON(wsFrame{Type: "subscribe", ID: 1, Sid: "s1", Key: "orders.*"}))
+		readFrame(t, conn)
+
+		// Неиспользованные соединения http клиента Shutdown ждёт 5 секунд
+		http.DefaultClient.CloseIdleConnections()
+
+		stopped = true
+		require.NoError(t, application.Stop(cfg.SubPub.CloseTimeout))
+
+		f := readFrame(t, conn)
+		assert.Equal(t, "error", f.Type)
+		assert.Equal(t, "Canceled", f.Code)
+
+		_, _, err = conn.ReadMessage()
+		assert.True(t, websocket.IsCloseError(err, websocket.CloseGoingAway), err)
+	})
+}
+
+type wsFrame struct {
+	Type      string  `json:"type"`
+	ID        uint64  `json:"id,omitempty"`
+	Sid       string  `json:"sid,omitempty"`
+	Key       string  `json:"key,omitempty"`
+	Data      string  `json:"data,omitempty"`
+	Delivered *uint32 `json:"delivered,omitempty"`
+	Code      string  `json:"code,omitempty"`
+	Message   string  `json:"message,omitempty"`
+}
+
+func readFrame(t *testing.T, conn *websocket.Conn) wsFrame {
+	var f wsFrame
+	require.NoError(t, conn.ReadJSON(&f))
+
+	return f
+}
+
+// readEvent читает одно событие SSE, пропуская комментарии
+func readEvent(t *testing.T, r *bufio.Reader) (string, string) {
+	var event, data string
+
+	for {
+		line, err := r.ReadString('\n')
+		if err == io.EOF {
+			t.Fatal("event stream closed")
+		}
+		require.NoError(t, err)
+
+		line = strings.TrimRight(line, "\n")
+
+		switch {
+		case line == "" && event != "":
+			return event, data
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
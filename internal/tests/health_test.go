@@ -0,0 +1,135 @@
+package tests
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"VK_task/internal/app"
+	"VK_task/internal/config"
+	"VK_task/internal/pkg/logger"
+	pb "VK_task/pkg/api/pubsub"
+	pbadmin "VK_task/pkg/api/pubsub/admin"
+	pbv2 "VK_task/pkg/api/pubsub/v2"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+)
+
+const healthTestPort = 8087
+
+// WARN: Автоматический старт сервера!
+func TestHealthAndReflection(t *testing.T) {
+	cfg := config.MustLoad(devConfigPath)
+	cfg.GRPC.Port = healthTestPort
+	cfg.GRPC.Reflection = true
+	cfg.GRPC.Admin.Enabled = true
+	cfg.GRPC.Auth = config.Auth{
+		Enabled:    true,
+		PolicyFile: writePolicy(t, t.TempDir(), testPolicy),
+	}
+
+	log := logger.MustSetup(cfg.SLOG.Env, cfg.SLOG.File)
+
+	application := app.MustNew(log, cfg.GRPC, cfg.SubPub, cfg.Metrics, cfg.Tracing, cfg.Gateway)
+	go application.MustRun()
+
+	stopped := make(chan struct{})
+	defer func() {
+		select {
+		case <-stopped:
+		default:
+			application.Stop(cfg.SubPub.CloseTimeout)
+		}
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+
+	cc, err := grpc.NewClient(
+		net.JoinHostPort(grpcHost, strconv.Itoa(healthTestPort)),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	defer cc.Close()
+
+	health := healthpb.NewHealthClient(cc)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	t.Run("Serving without credentials", func(t *testing.T) {
+		services := []string{
+			"",
+			pb.PubSub_ServiceDesc.ServiceName,
+			pbv2.PubSub_ServiceDesc.ServiceName,
+			pbadmin.Admin_ServiceDesc.ServiceName,
+		}
+
+		for _, service := range services {
+			resp, err := health.Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+			require.NoError(t, err, service)
+			assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.GetStatus(), service)
+		}
+	})
+
+	t.Run("Reflection lists services", func(t *testing.T) {
+		authCtx := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer orders-token")
+
+		stream, err := reflectionpb.NewServerReflectionClient(cc).ServerReflectionInfo(authCtx)
+		require.NoError(t, err)
+
+		require.NoError(t, stream.Send(&reflectionpb.ServerReflectionRequest{
+			MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+		}))
+
+		resp, err := stream.Recv()
+		require.NoError(t, err)
+		require.NoError(t, stream.CloseSend())
+
+		var names []string
+		for _, s := range resp.GetListServicesResponse().GetService() {
+			names = append(names, s.GetName())
+		}
+
+		assert.Contains(t, names, pb.PubSub_ServiceDesc.ServiceName)
+		assert.Contains(t, names, pbv2.PubSub_ServiceDesc.ServiceName)
+		assert.Contains(t, names, healthpb.Health_ServiceDesc.ServiceName)
+	})
+
+	t.Run("Not serving while draining", func(t *testing.T) {
+		watchCtx, watchCancel := context.WithCancel(ctx)
+		defer watchCancel()
+
+		watch, err := health.Watch(watchCtx, &healthpb.HealthCheckRequest{})
+		require.NoError(t, err)
+
+		resp, err := watch.Recv()
+		require.NoError(t, err)
+		require.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.GetStatus())
+
+		// Открытый Watch держит GracefulStop до отмены
+		go func() {
+			application.Stop(cfg.SubPub.CloseTimeout)
+			close(stopped)
+		}()
+
+		resp, err = watch.Recv()
+		require.NoError(t, err)
+		assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, resp.GetStatus())
+
+		watchCancel()
+
+		select {
+		case <-stopped:
+		case <-time.After(5 * time.Second):
+			t.Fatal("server did not stop")
+		}
+	})
+}
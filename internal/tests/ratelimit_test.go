@@ -0,0 +1,236 @@
+package tests
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"VK_task/internal/app"
+	"VK_task/internal/config"
+	"VK_task/internal/grpc/middleware/ratelimit"
+	"VK_task/internal/pkg/logger"
+	pb "VK_task/pkg/api/pubsub"
+	pbadmin "VK_task/pkg/api/pubsub/admin"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const rateLimitTestPort = 8086
+
+// WARN: Автоматический старт сервера!
+func TestRateLimit(t *testing.T) {
+	cfg := config.MustLoad(devConfigPath)
+	cfg.GRPC.Port = rateLimitTestPort
+	cfg.GRPC.RateLimit = config.RateLimit{
+		Enabled:          true,
+		Client:           config.RateLimits{PublishRate: 1, PublishBurst: 2},
+		Subject:          config.RateLimits{BytesRate: 16, BytesBurst: 16},
+		MaxSubscriptions: 1,
+	}
+
+	log := logger.MustSetup(cfg.SLOG.Env, cfg.SLOG.File)
+
+	application := app.MustNew(log, cfg.GRPC, cfg.SubPub, cfg.Metrics, cfg.Tracing, cfg.Gateway)
+	go application.MustRun()
+	defer application.Stop(cfg.SubPub.CloseTimeout)
+
+	time.Sleep(100 * time.Millisecond)
+
+	client, cleanup := newPubSubClient(t, grpcHost, rateLimitTestPort)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	t.Run("Message larger than subject burst", func(t *testing.T) {
+		var trailer metadata.MD
+
+		_, err := client.Publish(ctx, &pb.PublishRequest{Key: "big", Data: strings.Repeat("x", 20)}, grpc.Trailer(&trailer))
+		assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+		assert.Equal(t, []string{ratelimit.ScopeSubject}, trailer.Get(ratelimit.ScopeKey))
+		assert.Equal(t, []string{ratelimit.ResourceBytes}, trailer.Get(ratelimit.ResourceKey))
+		assert.Empty(t, trailer.Get(ratelimit.RetryAfterKey))
+	})
+
+	t.Run("Client publish rate", func(t *testing.T) {
+		// Burst 2: подписчиков нет, но вызов дошёл до обработчика
+		for i := 0; i < 2; i++ {
+			_, err := client.Publish(ctx, &pb.PublishRequest{Key: "rate", Data: "data"})
+			assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		}
+
+		var trailer metadata.MD
+
+		_, err := client.Publish(ctx, &pb.PublishRequest{Key: "rate", Data: "data"}, grpc.Trailer(&trailer))
+		assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+		assert.Equal(t, []string{ratelimit.ScopeClient}, trailer.Get(ratelimit.ScopeKey))
+		assert.Equal(t, []string{ratelimit.ResourceMessages}, trailer.Get(ratelimit.ResourceKey))
+
+		retry := trailer.Get(ratelimit.RetryAfterKey)
+		require.Len(t, retry, 1)
+
+		ms, err := strconv.Atoi(retry[0])
+		require.NoError(t, err)
+		assert.InDelta(t, 1000, ms, 100)
+	})
+
+	t.Run("Concurrent subscriptions", func(t *testing.T) {
+		firstCtx, firstCancel := context.WithCancel(ctx)
+		defer firstCancel()
+
+		first, err := client.Subscribe(firstCtx, &pb.SubscribeRequest{Key: "subs"})
+		require.NoError(t, err)
+
+		// Ждём, пока первая подписка займёт место
+		time.Sleep(50 * time.Millisecond)
+
+		second, err := client.Subscribe(ctx, &pb.SubscribeRequest{Key: "subs"})
+		require.NoError(t, err)
+
+		_, err = second.Recv()
+		assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+		assert.Equal(t, []string{ratelimit.ResourceSubscriptions}, second.Trailer().Get(ratelimit.ResourceKey))
+
+		firstCancel()
+		_, err = first.Recv()
+		assert.Equal(t, codes.Canceled, status.Code(err))
+
+		require.Eventually(t, func() bool {
+			thirdCtx, thirdCancel := context.WithTimeout(ctx, 100*time.Millisecond)
+			defer thirdCancel()
+
+			third, err := client.Subscribe(thirdCtx, &pb.SubscribeRequest{Key: "subs"})
+			require.NoError(t, err)
+
+			// Разрешённая подписка закрывается только по таймауту
+			_, err = third.Recv()
+			return status.Code(err) == codes.DeadlineExceeded
+		}, 2*time.Second, 50*time.Millisecond)
+	})
+
+	t.Run("Session subscriptions", func(t *testing.T) {
+		cc, err := grpc.NewClient(
+			net.JoinHostPort(grpcHost, strconv.Itoa(rateLimitTestPort)),
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+		)
+		require.NoError(t, err)
+		defer cc.Close()
+
+		admin := pbadmin.NewAdminClient(cc)
+
+		sessCtx, sessCancel := context.WithCancel(ctx)
+		defer sessCancel()
+
+		stream, err := client.Session(sessCtx)
+		require.NoError(t, err)
+
+		var requestID uint64
+		recv := func() *pb.SessionResponse {
+			resp, err := stream.Recv()
+			require.NoError(t, err)
+			return resp
+		}
+		request := func(req *pb.SessionRequest) uint32 {
+			requestID++
+			req.RequestId = requestID
+			require.NoError(t, stream.Send(req))
+
+			for {
+				if r := recv().GetReply(); r != nil && r.RequestId == requestID {
+					return r.Code
+				}
+			}
+		}
+		subscribe := func(sid, key string) uint32 {
+			return request(&pb.SessionRequest{Frame: &pb.SessionRequest_Subscribe{
+				Subscribe: &pb.SessionSubscribe{Sid: sid, Subscribe: &pb.SubscribeRequest{Key: key}},
+			}})
+		}
+		unsubscribe := func(sid string) uint32 {
+			return request(&pb.SessionRequest{Frame: &pb.SessionRequest_Unsubscribe{
+				Unsubscribe: &pb.SessionUnsubscribe{Sid: sid},
+			}})
+		}
+
+		// Отказ в подписке не занимает место
+		assert.Equal(t, uint32(codes.InvalidArgument), subscribe("bad", "limit..session"))
+		require.Eventually(t, func() bool {
+			return subscribe("a", "limit.session") == uint32(codes.OK)
+		}, 2*time.Second, 50*time.Millisecond)
+
+		// Превышение отклоняет запрос, сессия продолжает работать
+		assert.Equal(t, uint32(codes.ResourceExhausted), subscribe("b", "limit.session"))
+
+		// Отписка от неизвестного sid не освобождает место
+		assert.Equal(t, uint32(codes.NotFound), unsubscribe("unknown"))
+		assert.Equal(t, uint32(codes.ResourceExhausted), subscribe("b", "limit.session"))
+
+		assert.Equal(t, uint32(codes.OK), unsubscribe("a"))
+		assert.Equal(t, uint32(codes.OK), subscribe("b", "limit.session"))
+
+		// Подписка, закрытая сервером, освобождает место
+		subs, err := admin.ListSubscriptions(ctx, &pbadmin.ListSubscriptionsRequest{Key: "limit.session"})
+		require.NoError(t, err)
+		require.Len(t, subs.GetSubscriptions(), 1)
+
+		_, err = admin.CloseSubscription(ctx, &pbadmin.CloseSubscriptionRequest{Id: subs.GetSubscriptions()[0].GetId()})
+		require.NoError(t, err)
+
+		for recv().GetClosed() == nil {
+		}
+
+		require.Eventually(t, func() bool {
+			return subscribe("c", "limit.session") == uint32(codes.OK)
+		}, 2*time.Second, 50*time.Millisecond)
+	})
+
+	t.Run("Session publish limit", func(t *testing.T) {
+		sessCtx, sessCancel := context.WithCancel(ctx)
+		defer sessCancel()
+
+		stream, err := client.Session(sessCtx)
+		require.NoError(t, err)
+
+		var requestID uint64
+		request := func(req *pb.SessionRequest) uint32 {
+			requestID++
+			req.RequestId = requestID
+			require.NoError(t, stream.Send(req))
+
+			for {
+				resp, err := stream.Recv()
+				require.NoError(t, err)
+
+				if r := resp.GetReply(); r != nil && r.RequestId == requestID {
+					return r.Code
+				}
+			}
+		}
+		publish := func() uint32 {
+			return request(&pb.SessionRequest{Frame: &pb.SessionRequest_Publish{
+				Publish: &pb.PublishRequest{Key: "limit.publish", Data: "data"},
+			}})
+		}
+
+		// Burst клиента израсходован: отказ - ответ на publish, а не конец сессии
+		limited := false
+		for i := 0; i < 4 && !limited; i++ {
+			limited = publish() == uint32(codes.ResourceExhausted)
+		}
+		assert.True(t, limited)
+
+		assert.Equal(t, uint32(codes.NotFound), request(&pb.SessionRequest{Frame: &pb.SessionRequest_Unsubscribe{
+			Unsubscribe: &pb.SessionUnsubscribe{Sid: "unknown"},
+		}}))
+	})
+
+}
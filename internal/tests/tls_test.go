@@ -0,0 +1,279 @@
+package tests
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"VK_task/internal/app"
+	"VK_task/internal/config"
+	"VK_task/internal/pkg/logger"
+	pb "VK_task/pkg/api/pubsub"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	tlsTestPort        = 8084
+	tlsGatewayTestPort = 8091
+)
+
+// WARN: Автоматический старт сервера!
+func TestMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+
+	ca := newTestCA(t)
+	writeCert(t, ca.issue(t, "server", 1), dir, "server")
+	writeCert(t, ca.issue(t, "client-1", 2), dir, "client")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "ca.pem"), ca.certPEM, 0o600))
+
+	cfg := config.MustLoad(devConfigPath)
+	cfg.GRPC.Port = tlsTestPort
+	cfg.GRPC.TLS = config.TLS{
+		Enabled:           true,
+		CertFile:          filepath.Join(dir, "server.pem"),
+		KeyFile:           filepath.Join(dir, "server-key.pem"),
+		ClientCAFile:      filepath.Join(dir, "ca.pem"),
+		RequireClientCert: true,
+		ReloadInterval:    50 * time.Millisecond,
+	}
+	cfg.GRPC.Auth = config.Auth{
+		Enabled:    true,
+		PolicyFile: writePolicy(t, dir, "rules:\n  - principal: client-1\n    publish: [\"tls\"]\n"),
+	}
+	cfg.Gateway = config.Gateway{
+		Enabled: true,
+		Addr:    grpcHost,
+		Port:    tlsGatewayTestPort,
+	}
+
+	log := logger.MustSetup(cfg.SLOG.Env, cfg.SLOG.File)
+
+	application := app.MustNew(log, cfg.GRPC, cfg.SubPub, cfg.Metrics, cfg.Tracing, cfg.Gateway)
+	go application.MustRun()
+	defer application.Stop(cfg.SubPub.CloseTimeout)
+
+	time.Sleep(100 * time.Millisecond)
+
+	clientCert, err := tls.LoadX509KeyPair(filepath.Join(dir, "client.pem"), filepath.Join(dir, "client-key.pem"))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	t.Run("Client with certificate", func(t *testing.T) {
+		client, serial, cleanup := newTLSClient(t, ca.pool, []tls.Certificate{clientCert})
+		defer cleanup()
+
+		// Вызов дошёл до обработчика: подписчиков нет
+		_, err := client.Publish(ctx, &pb.PublishRequest{Key: "tls", Data: "data"})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		assert.Equal(t, "1", serial())
+	})
+
+	t.Run("Subject not allowed for certificate", func(t *testing.T) {
+		client, _, cleanup := newTLSClient(t, ca.pool, []tls.Certificate{clientCert})
+		defer cleanup()
+
+		_, err := client.Publish(ctx, &pb.PublishRequest{Key: "other", Data: "data"})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+
+	t.Run("Client without certificate", func(t *testing.T) {
+		client, _, cleanup := newTLSClient(t, ca.pool, nil)
+		defer cleanup()
+
+		_, err := client.Publish(ctx, &pb.PublishRequest{Key: "tls", Data: "data"})
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+	})
+
+	gatewayURL := fmt.Sprintf("https://localhost:%d/v1/publish/", tlsGatewayTestPort)
+
+	gatewayPublish := func(t *testing.T, certs []tls.Certificate, key string) (*http.Response, error) {
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+			RootCAs:      ca.pool,
+			Certificates: certs,
+		}}}
+		defer client.CloseIdleConnections()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, gatewayURL+key, strings.NewReader("data"))
+		require.NoError(t, err)
+
+		return client.Do(req)
+	}
+
+	t.Run("Gateway client with certificate", func(t *testing.T) {
+		// Principal - CN сертификата, вызов дошёл до обработчика: подписчиков нет
+		resp, err := gatewayPublish(t, []tls.Certificate{clientCert}, "tls")
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		resp, err = gatewayPublish(t, []tls.Certificate{clientCert}, "other")
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	})
+
+	t.Run("Gateway client without certificate", func(t *testing.T) {
+		resp, err := gatewayPublish(t, nil, "tls")
+		if err == nil {
+			resp.Body.Close()
+		}
+		assert.Error(t, err)
+	})
+
+	t.Run("Gateway without TLS", func(t *testing.T) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+			fmt.Sprintf("http://localhost:%d/v1/publish/tls", tlsGatewayTestPort), strings.NewReader("data"))
+		require.NoError(t, err)
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		// Ответ http.Server на HTTP запрос в TLS порт
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("Gateway WebSocket with certificate", func(t *testing.T) {
+		dialer := websocket.Dialer{TLSClientConfig: &tls.Config{
+			RootCAs:      ca.pool,
+			Certificates: []tls.Certificate{clientCert},
+		}}
+
+		conn, _, err := dialer.DialContext(ctx, fmt.Sprintf("wss://localhost:%d/v1/ws", tlsGatewayTestPort), nil)
+		require.NoError(t, err)
+		conn.Close()
+	})
+
+	t.Run("Certificate reload", func(t *testing.T) {
+		writeCert(t, ca.issue(t, "server", 3), dir, "server")
+
+		require.Eventually(t, func() bool {
+			client, serial, cleanup := newTLSClient(t, ca.pool, []tls.Certificate{clientCert})
+			defer cleanup()
+
+			_, err := client.Publish(ctx, &pb.PublishRequest{Key: "tls", Data: "data"})
+			return status.Code(err) == codes.InvalidArgument && serial() == "3"
+		}, 2*time.Second, 50*time.Millisecond)
+	})
+}
+
+// newTLSClient - serial возвращает серийный номер сертификата сервера последнего соединения
+func newTLSClient(t *testing.T, roots *x509.CertPool, certs []tls.Certificate) (pb.PubSubClient, func() string, func()) {
+	var serial string
+
+	tlsCfg := &tls.Config{
+		RootCAs:      roots,
+		Certificates: certs,
+		ServerName:   "localhost",
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			serial = cs.PeerCertificates[0].SerialNumber.String()
+			return nil
+		},
+	}
+
+	addr := net.JoinHostPort(grpcHost, strconv.Itoa(tlsTestPort))
+
+	cc, err := grpc.NewClient(addr, grpc.WithTransportCredentials(credentials.NewTLS(tlsCfg)))
+	require.NoError(t, err)
+
+	return pb.NewPubSubClient(cc), func() string { return serial }, func() { cc.Close() }
+}
+
+type testCA struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	pool    *x509.CertPool
+}
+
+type testCert struct {
+	certPEM []byte
+	keyPEM  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(100),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	return &testCA{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pool:    pool,
+	}
+}
+
+func (ca *testCA) issue(t *testing.T, cn string, serial int64) testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: cn},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	return testCert{
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+func writeCert(t *testing.T, c testCert, dir, name string) {
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name+"-key.pem"), c.keyPEM, 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name+".pem"), c.certPEM, 0o600))
+}
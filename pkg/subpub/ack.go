@@ -0,0 +1,313 @@
+package subpub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"VK_task/internal/pkg/logger/sl"
+)
+
+var ErrUnknownDelivery = errors.New("unknown or already acknowledged delivery")
+
+// minAckTimeout - нижняя граница AckTimeout, watchTimeouts проверяет доставки раз в timeout/2
+const minAckTimeout = 10 * time.Millisecond
+
+/*
+AckHandler
+
+Обработчик подписки с подтверждениями (SubscribeAck).
+Ошибка или паника - Nack. nil - Ack, если подписка не WithManualAck;
+при WithManualAck сообщение ждёт Ack/Nack до истечения AckTimeout.
+*/
+type AckHandler func(d *Delivery) error
+
+// Delivery - одна попытка доставки сообщения в подписку с подтверждениями
+type Delivery struct {
+	*Message
+	Tag     uint64 // номер доставки внутри подписки, не меняется при повторах
+	Attempt int    // номер попытки, с 1
+
+	acks *ackTracker
+}
+
+func (d *Delivery) Ack() error {
+	return d.acks.ack(d.Tag)
+}
+
+// Nack - сообщение будет доставлено повторно или уйдёт в dead-letter subject
+func (d *Delivery) Nack() error {
+	return d.acks.nack(d.Tag)
+}
+
+// AckSubscription - подписка с подтверждениями, Ack/Nack по Delivery.Tag
+type AckSubscription interface {
+	Subscription
+	Ack(tag uint64) error
+	Nack(tag uint64) error
+}
+
+type inflight struct {
+	msg      *message
+	tag      uint64
+	attempt  int
+	deadline time.Time
+}
+
+/*
+ackTracker
+
+Неподтверждённые доставки подписки. Истёкшие по AckTimeout и Nack
+попадают в ready и доставляются повторно из dispatchMessages,
+после MaxAttempts попыток сообщение уходит в dead-letter subject.
+*/
+type ackTracker struct {
+	cb          AckHandler
+	manual      bool
+	timeout     time.Duration
+	maxAttempts int
+	deadLetter  string // пусто - Config.DeadLetterPrefix + subject сообщения
+	dlqPrefix   string
+
+	nextTag  uint64
+	inflight map[uint64]*inflight
+	ready    []*inflight
+	notify   chan struct{} // сигнал dispatchMessages, что ready не пуст
+	mu       sync.Mutex
+
+	sub *subscription
+}
+
+func newAckTracker(sub *subscription, cb AckHandler, opts *subscribeOptions) *ackTracker {
+	cfg := sub.sp.cfg
+
+	t := &ackTracker{
+		cb:          cb,
+		manual:      opts.manualAck,
+		timeout:     cfg.AckTimeout,
+		maxAttempts: cfg.MaxAttempts,
+		deadLetter:  opts.deadLetter,
+		dlqPrefix:   cfg.DeadLetterPrefix,
+		inflight:    make(map[uint64]*inflight),
+		notify:      make(chan struct{}, 1),
+		sub:         sub,
+	}
+
+	if opts.ackTimeout > 0 {
+		t.timeout = opts.ackTimeout
+	}
+	if opts.maxAttempts > 0 {
+		t.maxAttempts = opts.maxAttempts
+	}
+	if t.timeout < minAckTimeout {
+		t.timeout = minAckTimeout
+	}
+
+	return t
+}
+
+// first - первая доставка нового сообщения
+func (t *ackTracker) first(msg *message) {
+	t.mu.Lock()
+	t.nextTag++
+	f := &inflight{msg: msg, tag: t.nextTag}
+	t.mu.Unlock()
+
+	t.deliver(f)
+}
+
+func (t *ackTracker) deliver(f *inflight) {
+	t.mu.Lock()
+	f.attempt++
+	f.deadline = time.Now().Add(t.timeout)
+	t.inflight[f.tag] = f
+	t.mu.Unlock()
+
+	d := &Delivery{
+		Message: f.msg.export(),
+		Tag:     f.tag,
+		Attempt: f.attempt,
+		acks:    t,
+	}
+
+	t.sub.sp.metrics.Delivered(f.msg.subject)
+	t.sub.delivered.Add(1)
+
+	span, headers := t.sub.startDeliverSpan(f.msg, f.attempt)
+	d.Headers = headers
+
+	start := time.Now()
+	err := t.call(d)
+	t.sub.sp.metrics.HandlerDuration(time.Since(start))
+	endSpan(span, err)
+
+	switch {
+	case err != nil:
+		t.sub.sp.log.Warn("Message handler failed, message will be redelivered",
+			sl.Err(err),
+			slog.String("id", t.sub.id),
+			slog.String("subject", t.sub.subject),
+			slog.Uint64("tag", f.tag),
+			slog.Int("attempt", f.attempt),
+		)
+		_ = t.nack(f.tag)
+
+	case !t.manual:
+		_ = t.ack(f.tag)
+	}
+}
+
+func (t *ackTracker) call(d *Delivery) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			t.sub.sp.metrics.HandlerPanic(d.Subject)
+			t.sub.sp.log.Error("Panic in message handler",
+				slog.Any("panic", r),
+				slog.String("stack", string(debug.Stack())),
+				slog.String("id", t.sub.id),
+				slog.String("subject", t.sub.subject),
+			)
+
+			err = fmt.Errorf("panic in message handler: %v", r)
+		}
+	}()
+
+	return t.cb(d)
+}
+
+func (t *ackTracker) ack(tag uint64) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, ok := t.inflight[tag]; !ok {
+		return ErrUnknownDelivery
+	}
+	delete(t.inflight, tag)
+
+	return nil
+}
+
+func (t *ackTracker) nack(tag uint64) error {
+	t.mu.Lock()
+	f, ok := t.inflight[tag]
+	if !ok {
+		t.mu.Unlock()
+		return ErrUnknownDelivery
+	}
+	delete(t.inflight, tag)
+	t.mu.Unlock()
+
+	t.retry(f)
+
+	return nil
+}
+
+func (t *ackTracker) retry(f *inflight) {
+	if f.attempt >= t.maxAttempts {
+		t.toDeadLetter(f)
+		return
+	}
+
+	t.mu.Lock()
+	t.ready = append(t.ready, f)
+	t.mu.Unlock()
+
+	select {
+	case t.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (t *ackTracker) toDeadLetter(f *inflight) {
+	log := t.sub.sp.log.With(
+		slog.String("id", t.sub.id),
+		slog.String("subject", f.msg.subject),
+		slog.Uint64("tag", f.tag),
+		slog.Int("attempts", f.attempt),
+	)
+
+	deadLetter := t.deadLetter
+	if deadLetter == "" && t.dlqPrefix != "" {
+		deadLetter = t.dlqPrefix + tokenSeparator + f.msg.subject
+	}
+
+	if deadLetter == "" {
+		t.sub.sp.metrics.Dropped(f.msg.subject, DropMaxAttempts)
+		t.sub.dropped.Add(1)
+		log.Warn("Message dropped after max delivery attempts")
+		return
+	}
+
+	// Без подписчиков dead-letter сообщение сохраняется при любой NoSubscribersPolicy, см. route.
+	// Подписке с шаблоном, под который попадает и dead-letter subject, оно не доставляется:
+	// иначе она ждала бы места в собственной очереди и порождала dlq.dlq...
+	dead := newMessage(deadLetter, f.msg.data)
+	dead.headers = f.msg.headers
+	dead.deadLetter = true
+	dead.origin = t.sub
+
+	if _, err := t.sub.sp.publish(context.Background(), dead); err != nil {
+		t.sub.sp.metrics.Dropped(f.msg.subject, DropMaxAttempts)
+		t.sub.dropped.Add(1)
+		log.Error("Dead-letter publish failed, message dropped",
+			sl.Err(err),
+			slog.String("dead_letter", deadLetter),
+		)
+		return
+	}
+
+	log.Warn("Message moved to dead-letter subject", slog.String("dead_letter", deadLetter))
+}
+
+// takeReady забирает доставки, ожидающие повтора
+func (t *ackTracker) takeReady() []*inflight {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	ready := t.ready
+	t.ready = nil
+
+	return ready
+}
+
+// watchTimeouts возвращает в повтор доставки без Ack/Nack за AckTimeout
+func (t *ackTracker) watchTimeouts() {
+	ticker := time.NewTicker(t.timeout / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			t.mu.Lock()
+			var expired []*inflight
+			for tag, f := range t.inflight {
+				if now.After(f.deadline) {
+					expired = append(expired, f)
+					delete(t.inflight, tag)
+				}
+			}
+			t.mu.Unlock()
+
+			for _, f := range expired {
+				t.retry(f)
+			}
+
+		case <-t.sub.done:
+			return
+		case <-t.sub.sp.closeChan:
+			return
+		}
+	}
+}
+
+// unacked - доставок, ожидающих Ack или повтора
+func (t *ackTracker) unacked() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return len(t.inflight) + len(t.ready)
+}
@@ -0,0 +1,247 @@
+package subpub
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+const drainPollInterval = 10 * time.Millisecond
+
+// Stats - снимок состояния шины, subject отсортированы по имени
+type Stats struct {
+	Subjects []SubjectStats
+}
+
+type SubjectStats struct {
+	Subject       string // subject или шаблон, на который подписаны
+	Subscribers   int
+	Groups        int // queue group с подписчиками
+	QueueDepth    int // сообщений в очереди subject
+	QueueCapacity int
+	Subscriptions []SubscriptionStats // в порядке подписки
+}
+
+type SubscriptionStats struct {
+	ID            string
+	Subject       string
+	Group         string
+	Peer          string // WithPeer, пусто - не задан
+	Ack           bool   // подписка SubscribeAck
+	CreatedAt     time.Time
+	QueueDepth    int
+	QueueCapacity int
+	Delivered     uint64 // попыток доставки в обработчик, с повторами
+	Dropped       uint64 // потеряно при переполнении очереди и после MaxAttempts
+	Unacked       int    // доставок, ожидающих Ack
+}
+
+func (sp *subPub) Stats() Stats {
+	sp.mu.RLock()
+	subjs := make(map[string]*subject, len(sp.subjects))
+	for name, subj := range sp.subjects {
+		subjs[name] = subj
+	}
+	sp.mu.RUnlock()
+
+	stats := Stats{Subjects: make([]SubjectStats, 0, len(subjs))}
+	for name, subj := range subjs {
+		stats.Subjects = append(stats.Subjects, subj.stats(name))
+	}
+
+	sort.Slice(stats.Subjects, func(i, j int) bool {
+		return stats.Subjects[i].Subject < stats.Subjects[j].Subject
+	})
+
+	return stats
+}
+
+func (s *subject) stats(name string) SubjectStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	st := SubjectStats{
+		Subject:       name,
+		Subscribers:   len(s.subscribers),
+		Groups:        len(s.groups),
+		QueueDepth:    len(s.queue),
+		QueueCapacity: cap(s.queue),
+		Subscriptions: make([]SubscriptionStats, 0, len(s.subscribers)),
+	}
+
+	for _, sub := range s.subscribers {
+		st.Subscriptions = append(st.Subscriptions, sub.Stats())
+	}
+
+	sort.Slice(st.Subscriptions, func(i, j int) bool {
+		a, b := st.Subscriptions[i], st.Subscriptions[j]
+		if a.CreatedAt.Equal(b.CreatedAt) {
+			return a.ID < b.ID
+		}
+		return a.CreatedAt.Before(b.CreatedAt)
+	})
+
+	return st
+}
+
+func (sub *subscription) Stats() SubscriptionStats {
+	st := SubscriptionStats{
+		ID:            sub.id,
+		Subject:       sub.subject,
+		Group:         sub.group,
+		Peer:          sub.peer,
+		Ack:           sub.acks != nil,
+		CreatedAt:     sub.createdAt,
+		QueueDepth:    len(sub.queue),
+		QueueCapacity: cap(sub.queue),
+		Delivered:     sub.delivered.Load(),
+		Dropped:       sub.dropped.Load(),
+	}
+
+	if sub.acks != nil {
+		st.Unacked = sub.acks.unacked()
+	}
+
+	return st
+}
+
+/*
+CloseSubscription
+
+Принудительно закрывает подписку по ID: Done закрывается,
+Err возвращает ErrSubscriptionClosed.
+*/
+func (sp *subPub) CloseSubscription(id string) error {
+	sp.mu.RLock()
+	if sp.closed {
+		sp.mu.RUnlock()
+		return ErrSubPubClosed
+	}
+
+	var sub *subscription
+	for _, subj := range sp.subjects {
+		if sub = subj.subscriber(id); sub != nil {
+			break
+		}
+	}
+	sp.mu.RUnlock()
+
+	if sub == nil {
+		return ErrNoSuchSubscription
+	}
+
+	sub.close(ErrSubscriptionClosed)
+
+	return nil
+}
+
+func (s *subject) subscriber(id string) *subscription {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.subscribers[id]
+}
+
+/*
+DrainSubject
+
+Отключает subject (точное имя или шаблон подписки) от публикаций,
+ждёт, пока очереди subject и его подписок опустеют, и закрывает
+подписки с ErrSubjectDrained. Новые Subscribe на тот же subject
+создают новый subject и сообщения после начала drain получают.
+
+Если ctx завершится раньше, подписки закрываются сразу, оставшиеся
+в очередях сообщения теряются, возвращается ошибка ctx.
+Close во время drain закрывает подписки сам, возвращается ErrSubPubClosed.
+Возвращает число закрытых подписок.
+*/
+func (sp *subPub) DrainSubject(ctx context.Context, subject string) (int, error) {
+	sp.mu.Lock()
+	if sp.closed {
+		sp.mu.Unlock()
+		return 0, ErrSubPubClosed
+	}
+
+	subj, ok := sp.subjects[subject]
+	if !ok {
+		sp.mu.Unlock()
+		return 0, ErrNoSuchSubject
+	}
+
+	delete(sp.subjects, subject)
+	sp.sublist.remove(subject)
+
+	// Close закрывает подписки отключённого subject, пока drain их ждёт
+	sp.draining[subj] = struct{}{}
+	sp.mu.Unlock()
+
+	defer func() {
+		sp.mu.Lock()
+		delete(sp.draining, subj)
+		sp.mu.Unlock()
+	}()
+
+	err := subj.waitEmpty(ctx, sp.closeChan)
+
+	// Подписки мог закрыть Close, очистив subject раньше проверки closeChan
+	select {
+	case <-sp.closeChan:
+		err = ErrSubPubClosed
+	default:
+	}
+
+	subs := subj.subscriptions()
+	for _, sub := range subs {
+		sub.close(ErrSubjectDrained)
+	}
+
+	return len(subs), err
+}
+
+// waitEmpty ждёт, пока опустеют очереди subject и всех его подписок
+func (s *subject) waitEmpty(ctx context.Context, closeChan <-chan struct{}) error {
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+
+	for !s.empty() {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-closeChan:
+			return ErrSubPubClosed
+		}
+	}
+
+	return nil
+}
+
+// empty - под блокировкой, чтобы не попасть в середину deliverMessage
+func (s *subject) empty() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.queue) > 0 {
+		return false
+	}
+
+	for _, sub := range s.subscribers {
+		if len(sub.queue) > 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (s *subject) subscriptions() []*subscription {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	subs := make([]*subscription, 0, len(s.subscribers))
+	for _, sub := range s.subscribers {
+		subs = append(subs, sub)
+	}
+
+	return subs
+}
@@ -0,0 +1,67 @@
+package subpub
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Codec - сериализация значений Topic[T] в данные сообщения
+type Codec[T any] interface {
+	Encode(v T) ([]byte, error)
+	Decode(data []byte) (T, error)
+}
+
+// JSONCodec - encoding/json, читается клиентами на других языках
+type JSONCodec[T any] struct{}
+
+func (JSONCodec[T]) Encode(v T) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (JSONCodec[T]) Decode(data []byte) (T, error) {
+	var v T
+	err := json.Unmarshal(data, &v)
+	return v, err
+}
+
+// GobCodec - encoding/gob, только для Go на обеих сторонах
+type GobCodec[T any] struct{}
+
+func (GobCodec[T]) Encode(v T) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (GobCodec[T]) Decode(data []byte) (T, error) {
+	var v T
+	err := gob.NewDecoder(bytes.NewReader(data)).Decode(&v)
+	return v, err
+}
+
+// ProtoCodec - бинарный protobuf, T - указатель на сгенерированное сообщение
+type ProtoCodec[T proto.Message] struct{}
+
+func (ProtoCodec[T]) Encode(v T) ([]byte, error) {
+	return proto.Marshal(v)
+}
+
+func (ProtoCodec[T]) Decode(data []byte) (T, error) {
+	var zero T
+
+	v, ok := zero.ProtoReflect().New().Interface().(T)
+	if !ok {
+		return zero, fmt.Errorf("%w: %T is not a protobuf message", ErrTypeMismatch, zero)
+	}
+
+	if err := proto.Unmarshal(data, v); err != nil {
+		return zero, err
+	}
+	return v, nil
+}
@@ -0,0 +1,57 @@
+package subpub
+
+// queueGroup - подписки subject с одним именем группы, сообщение получает одна из них
+type queueGroup struct {
+	members []*subscription
+	next    int // с кого начинать round-robin
+}
+
+func (g *queueGroup) add(sub *subscription) {
+	g.members = append(g.members, sub)
+}
+
+// remove возвращает true, если группа опустела
+func (g *queueGroup) remove(id string) bool {
+	for i, sub := range g.members {
+		if sub.id == id {
+			g.members = append(g.members[:i], g.members[i+1:]...)
+			break
+		}
+	}
+
+	if g.next >= len(g.members) {
+		g.next = 0
+	}
+
+	return len(g.members) == 0
+}
+
+/*
+pick
+
+Round-robin, но подписка с заполненной очередью пропускается,
+если в группе есть свободная. Если заполнены все, сообщение
+получает очередная по кругу, и дальше работает её OverflowPolicy.
+skip не получает сообщение никогда, nil - в группе больше никого.
+*/
+func (g *queueGroup) pick(skip *subscription) *subscription {
+	n := len(g.members)
+	start := g.next
+	g.next = (g.next + 1) % n
+
+	var full *subscription
+	for i := 0; i < n; i++ {
+		sub := g.members[(start+i)%n]
+		if sub == skip {
+			continue
+		}
+		if len(sub.queue) < cap(sub.queue) {
+			return sub
+		}
+		if full == nil {
+			full = sub
+		}
+	}
+
+	return full
+}
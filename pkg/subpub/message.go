@@ -0,0 +1,62 @@
+package subpub
+
+import (
+	"time"
+
+	fast_id "VK_task/pkg/fast-id"
+)
+
+// HeaderContentType - заголовок с типом содержимого сообщения
+const HeaderContentType = "Content-Type"
+
+// message - конверт сообщения в очередях subject и subscription
+type message struct {
+	id        string
+	subject   string // конкретный subject, в который опубликовано сообщение
+	offset    uint64 // смещение в durable log, 0 - сообщение не записано
+	timestamp time.Time
+	expires   time.Time // zero - без TTL
+	headers   map[string]string
+	data      interface{}
+
+	retain   bool // опубликовано с Message.Retain
+	retained bool // копия из retainedStore для новой подписки
+
+	deadLetter bool          // перенесено в dead-letter subject после MaxAttempts
+	origin     *subscription // подписка, исчерпавшая попытки: ей dead letter не доставляется
+
+	request bool // опубликовано RequestMsg, ответ ждут только до конца Request
+}
+
+func newMessage(subject string, data interface{}) *message {
+	return &message{
+		id:        fast_id.New(),
+		subject:   subject,
+		timestamp: time.Now(),
+		data:      data,
+	}
+}
+
+// expired - TTL истёк, сообщение не доставляется
+func (m *message) expired(now time.Time) bool {
+	return !m.expires.IsZero() && now.After(m.expires)
+}
+
+// setTTL - время жизни от момента публикации, ttl <= 0 - без TTL
+func (m *message) setTTL(ttl time.Duration) {
+	if ttl > 0 {
+		m.expires = m.timestamp.Add(ttl)
+	}
+}
+
+func (m *message) export() *Message {
+	return &Message{
+		ID:        m.id,
+		Subject:   m.subject,
+		Offset:    m.offset,
+		Timestamp: m.timestamp,
+		Headers:   m.headers,
+		Data:      m.data,
+		Retain:    m.retained,
+	}
+}
@@ -0,0 +1,55 @@
+package subpub
+
+import "time"
+
+// Причины потери сообщения для Metrics.Dropped
+const (
+	DropQueueFull     = "queue_full"     // OverflowDropNewest
+	DropOldest        = "drop_oldest"    // вытеснено OverflowDropOldest
+	DropBlockTimeout  = "block_timeout"  // OverflowBlock
+	DropSlowConsumer  = "slow_consumer"  // OverflowDisconnect
+	DropNoSubscribers = "no_subscribers" // NoSubscribersDrop
+	DropMaxAttempts   = "max_attempts"   // SubscribeAck без dead-letter subject
+	DropDecode        = "decode"         // данные не того типа для Topic[T] или Subscribe[T]
+	DropExpired       = "expired"        // истёк TTL сообщения
+)
+
+/*
+Metrics
+
+Наблюдение за шиной, задаётся в Config.Metrics. Методы вызываются
+на горячем пути доставки и должны быть быстрыми и потокобезопасными.
+subject - конкретный subject сообщения, а не шаблон подписки.
+*/
+type Metrics interface {
+	Published(subject string)
+	Delivered(subject string)
+	Dropped(subject, reason string)
+	HandlerPanic(subject string)
+	HandlerDuration(d time.Duration)
+
+	// Заполненность очередей (len/cap) в момент постановки сообщения
+	SubjectQueueFill(ratio float64)
+	SubscriptionQueueFill(ratio float64)
+
+	// Изменение числа активных подписок: +1 при Subscribe, -1 при закрытии
+	Subscriptions(delta int)
+}
+
+type noopMetrics struct{}
+
+func (noopMetrics) Published(string)              {}
+func (noopMetrics) Delivered(string)              {}
+func (noopMetrics) Dropped(string, string)        {}
+func (noopMetrics) HandlerPanic(string)           {}
+func (noopMetrics) HandlerDuration(time.Duration) {}
+func (noopMetrics) SubjectQueueFill(float64)      {}
+func (noopMetrics) SubscriptionQueueFill(float64) {}
+func (noopMetrics) Subscriptions(int)             {}
+
+func fillRatio(n, capacity int) float64 {
+	if capacity == 0 {
+		return 1
+	}
+	return float64(n) / float64(capacity)
+}
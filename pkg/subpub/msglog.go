@@ -0,0 +1,207 @@
+package subpub
+
+import (
+	"container/list"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"VK_task/pkg/e"
+)
+
+/*
+msgLog
+
+Durable log: для каждого subject append-only лог из сегментов на диске.
+Каждое записанное сообщение получает монотонное смещение (с 1) внутри своего subject.
+
+Структура каталога: <dir>/<subject>/<base offset>.log
+
+Открытыми на запись держатся сегменты не больше MaxOpenFiles subject,
+в которые писали последними, остальные закрываются и открываются снова
+при следующей записи.
+*/
+type msgLog struct {
+	cfg    LogConfig
+	logs   map[string]*subjectLog
+	closed bool
+	mu     sync.RWMutex
+
+	recent   *list.List // *subjectLog с открытым сегментом, последние записи в начале
+	recentOf map[*subjectLog]*list.Element
+	recentMu sync.Mutex
+}
+
+func openMsgLog(cfg LogConfig) (*msgLog, error) {
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return nil, e.Wrap("can't create log dir", err)
+	}
+
+	entries, err := os.ReadDir(cfg.Dir)
+	if err != nil {
+		return nil, e.Wrap("can't read log dir", err)
+	}
+
+	l := &msgLog{
+		cfg:      cfg,
+		logs:     make(map[string]*subjectLog, len(entries)),
+		recent:   list.New(),
+		recentOf: make(map[*subjectLog]*list.Element),
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		subject, err := url.PathUnescape(entry.Name())
+		if err != nil || !validLiteral(subject) {
+			continue
+		}
+
+		sl, err := openSubjectLog(filepath.Join(cfg.Dir, entry.Name()), cfg)
+		if err != nil {
+			l.close()
+			return nil, e.Wrap("can't recover log of subject "+subject, err)
+		}
+
+		l.logs[subject] = sl
+	}
+
+	return l, nil
+}
+
+// append записывает сообщение и присваивает ему смещение
+func (l *msgLog) append(m *message) error {
+	l.mu.RLock()
+	sl, exists := l.logs[m.subject]
+	l.mu.RUnlock()
+
+	if !exists {
+		var err error
+
+		sl, err = l.create(m.subject)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := sl.append(m); err != nil {
+		return err
+	}
+
+	l.touch(sl)
+
+	return nil
+}
+
+// touch отмечает запись в sl и закрывает сегменты сверх MaxOpenFiles
+func (l *msgLog) touch(sl *subjectLog) {
+	l.recentMu.Lock()
+
+	if elem, ok := l.recentOf[sl]; ok {
+		l.recent.MoveToFront(elem)
+	} else {
+		l.recentOf[sl] = l.recent.PushFront(sl)
+	}
+
+	var evicted []*subjectLog
+	for l.recent.Len() > l.cfg.MaxOpenFiles {
+		victim := l.recent.Remove(l.recent.Back()).(*subjectLog)
+		delete(l.recentOf, victim)
+		evicted = append(evicted, victim)
+	}
+
+	l.recentMu.Unlock()
+
+	// Вне recentMu: release ждёт запись, идущую в тот же subject.
+	// Ошибка закрытия не теряет данные - записи уже переданы в файл
+	for _, victim := range evicted {
+		_ = victim.release()
+	}
+}
+
+func (l *msgLog) create(subject string) (*subjectLog, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return nil, ErrSubPubClosed
+	}
+	if sl, exists := l.logs[subject]; exists {
+		return sl, nil
+	}
+
+	sl, err := openSubjectLog(filepath.Join(l.cfg.Dir, url.PathEscape(subject)), l.cfg)
+	if err != nil {
+		return nil, e.Wrap("can't create log of subject "+subject, err)
+	}
+
+	l.logs[subject] = sl
+
+	return sl, nil
+}
+
+/*
+cursors
+
+Для каждого записанного subject, подходящего под pattern,
+фиксирует границу чтения: сообщения от start до текущего конца лога.
+Всё, что будет записано позже, подписка получит через очередь.
+*/
+func (l *msgLog) cursors(pattern string, start *startPosition) []*logCursor {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	subjects := make([]string, 0, len(l.logs))
+	for subject := range l.logs {
+		if matchPattern(pattern, subject) {
+			subjects = append(subjects, subject)
+		}
+	}
+	sort.Strings(subjects)
+
+	cursors := make([]*logCursor, 0, len(subjects))
+	for _, subject := range subjects {
+		sl := l.logs[subject]
+
+		cursors = append(cursors, &logCursor{
+			subject: subject,
+			log:     sl,
+			start:   *start,
+			until:   sl.nextOffset(),
+		})
+	}
+
+	return cursors
+}
+
+func (l *msgLog) close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.closed = true
+
+	var firstErr error
+	for _, sl := range l.logs {
+		if err := sl.close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+// logCursor - позиция воспроизведения лога одного subject для подписки
+type logCursor struct {
+	subject string
+	log     *subjectLog
+	start   startPosition
+	until   uint64 // первое смещение, которое придёт через очередь
+}
+
+func (c *logCursor) replay(fn func(m *message) bool) error {
+	return c.log.replay(c.start, c.until, c.subject, fn)
+}
@@ -0,0 +1,135 @@
+package subpub
+
+import "time"
+
+type SubscribeOption func(*subscribeOptions)
+
+type subscribeOptions struct {
+	start    *startPosition // nil - только новые сообщения
+	envelope bool
+	group    string
+	peer     string
+	decode   decodeFunc // nil - данные передаются обработчику как есть
+
+	overflow     *OverflowPolicy // nil - Config.Overflow
+	blockTimeout time.Duration   // 0 - Config.BlockTimeout
+
+	// SubscribeAck
+	manualAck   bool
+	ackTimeout  time.Duration // 0 - Config.AckTimeout
+	maxAttempts int           // 0 - Config.MaxAttempts
+	deadLetter  string        // пусто - Config.DeadLetterPrefix + subject
+}
+
+// startPosition - откуда начать чтение durable log
+type startPosition struct {
+	offset uint64    // 0 - с самого раннего сообщения
+	time   time.Time // если задано, с первого сообщения не раньше time
+}
+
+func newSubscribeOptions(opts []SubscribeOption) *subscribeOptions {
+	o := &subscribeOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// StartAtOffset - воспроизвести сообщения из durable log начиная со смещения offset
+func StartAtOffset(offset uint64) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.start = &startPosition{offset: offset}
+	}
+}
+
+// StartAtEarliest - воспроизвести все сообщения, сохранённые в durable log
+func StartAtEarliest() SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.start = &startPosition{}
+	}
+}
+
+// StartAtTime - воспроизвести сообщения из durable log, опубликованные не раньше t
+func StartAtTime(t time.Time) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.start = &startPosition{time: t}
+	}
+}
+
+/*
+WithEnvelope
+
+MessageHandler получает *Message (subject, offset, время публикации)
+вместо самих данных сообщения.
+*/
+func WithEnvelope() SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.envelope = true
+	}
+}
+
+/*
+WithQueueGroup
+
+Подписки на один subject с одной группой делят сообщения между собой:
+каждое получает только одна из них.
+*/
+func WithQueueGroup(group string) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.group = group
+	}
+}
+
+// WithPeer - адрес или имя клиента подписки для Stats
+func WithPeer(peer string) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.peer = peer
+	}
+}
+
+// WithOverflow - политика переполнения очереди подписки вместо Config.Overflow
+func WithOverflow(policy OverflowPolicy) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.overflow = &policy
+	}
+}
+
+// WithBlockTimeout - сколько ждать места в очереди при OverflowBlock вместо Config.BlockTimeout
+func WithBlockTimeout(timeout time.Duration) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.blockTimeout = timeout
+	}
+}
+
+/*
+WithManualAck
+
+Для SubscribeAck: возврат nil из AckHandler не подтверждает сообщение,
+нужен явный Ack/Nack (например, после ответа клиента).
+*/
+func WithManualAck() SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.manualAck = true
+	}
+}
+
+// WithAckTimeout - для SubscribeAck: время ожидания Ack вместо Config.AckTimeout
+func WithAckTimeout(timeout time.Duration) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.ackTimeout = timeout
+	}
+}
+
+// WithMaxAttempts - для SubscribeAck: число попыток доставки вместо Config.MaxAttempts
+func WithMaxAttempts(attempts int) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.maxAttempts = attempts
+	}
+}
+
+// WithDeadLetter - для SubscribeAck: subject для сообщений, исчерпавших попытки
+func WithDeadLetter(subject string) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.deadLetter = subject
+	}
+}
@@ -0,0 +1,111 @@
+package subpub
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"VK_task/internal/pkg/logger/sl"
+)
+
+// OverflowPolicy - что делать, если очередь подписки заполнена
+type OverflowPolicy int
+
+const (
+	OverflowDropNewest OverflowPolicy = iota // отбросить новое сообщение
+	OverflowDropOldest                       // вытеснить самое старое сообщение из очереди
+	OverflowBlock                            // ждать места в очереди не дольше BlockTimeout
+	OverflowDisconnect                       // отключить подписку с ErrSlowConsumer
+)
+
+func ParseOverflowPolicy(s string) (OverflowPolicy, error) {
+	switch s {
+	case "", "drop_newest":
+		return OverflowDropNewest, nil
+	case "drop_oldest":
+		return OverflowDropOldest, nil
+	case "block":
+		return OverflowBlock, nil
+	case "disconnect":
+		return OverflowDisconnect, nil
+	default:
+		return 0, fmt.Errorf("unknown overflow policy %q", s)
+	}
+}
+
+/*
+overflow
+
+Вызывается из deliver, когда очередь подписки заполнена.
+Блокирует dispatcher subject (а значит и публикующих) только при OverflowBlock.
+*/
+func (sub *subscription) overflow(msg *message) {
+	switch sub.policy {
+	case OverflowDropOldest:
+		for {
+			select {
+			case sub.queue <- msg:
+				return
+			default:
+			}
+
+			select {
+			case old := <-sub.queue:
+				sub.warnDropped(old, DropOldest, "Subscription queue is full, oldest message dropped")
+			default:
+			}
+		}
+
+	case OverflowBlock:
+		timer := time.NewTimer(sub.blockTimeout)
+		defer timer.Stop()
+
+		select {
+		case sub.queue <- msg:
+		case <-timer.C:
+			sub.warnDropped(msg, DropBlockTimeout, "Subscription queue is full, block timeout expired")
+		case <-sub.done:
+		case <-sub.sp.closeChan:
+		}
+
+	case OverflowDisconnect:
+		sub.sp.metrics.Dropped(msg.subject, DropSlowConsumer)
+		sub.dropped.Add(1)
+		sub.sp.log.Warn("Subscription queue is full, slow consumer disconnected",
+			slog.String("id", sub.id),
+			slog.String("subject", sub.subject),
+		)
+
+		// deliver вызывается под блокировкой subject, отписка - отдельно
+		go sub.close(ErrSlowConsumer)
+
+	default:
+		sub.warnDropped(msg, DropQueueFull, "Subscription queue is full")
+	}
+}
+
+// rejected - типизированная подписка не смогла получить T из данных сообщения
+func (sub *subscription) rejected(msg *message, err error) {
+	sub.sp.metrics.Dropped(msg.subject, DropDecode)
+	sub.dropped.Add(1)
+	sub.sp.log.Warn("Message rejected by typed subscription",
+		sl.Err(err),
+		slog.String("id", sub.id),
+		slog.String("subject", msg.subject),
+	)
+}
+
+// expired - TTL сообщения истёк в очереди, без предупреждения в лог на каждое сообщение
+func (sub *subscription) expired(msg *message) {
+	sub.sp.metrics.Dropped(msg.subject, DropExpired)
+	sub.dropped.Add(1)
+}
+
+func (sub *subscription) warnDropped(msg *message, reason, text string) {
+	sub.sp.metrics.Dropped(msg.subject, reason)
+	sub.dropped.Add(1)
+	sub.sp.log.Warn(text,
+		slog.String("id", sub.id),
+		slog.String("subject", sub.subject),
+	)
+}
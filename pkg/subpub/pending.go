@@ -0,0 +1,87 @@
+package subpub
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// NoSubscribersPolicy - что делать с сообщением, если под subject нет ни одной подписки
+type NoSubscribersPolicy int
+
+const (
+	NoSubscribersError  NoSubscribersPolicy = iota // вернуть ErrNoSuchSubject
+	NoSubscribersDrop                              // молча отбросить
+	NoSubscribersRetain                            // сохранить для первых подписчиков
+)
+
+func ParseNoSubscribersPolicy(s string) (NoSubscribersPolicy, error) {
+	switch s {
+	case "", "error":
+		return NoSubscribersError, nil
+	case "drop":
+		return NoSubscribersDrop, nil
+	case "retain":
+		return NoSubscribersRetain, nil
+	default:
+		return 0, fmt.Errorf("unknown no subscribers policy %q", s)
+	}
+}
+
+/*
+pendingBuffer
+
+Сообщения, опубликованные без подписчиков при NoSubscribersRetain,
+и dead-letter сообщения без подписчиков при остальных политиках.
+Для каждого subject хранится не больше size последних сообщений,
+их забирает первая подходящая подписка.
+*/
+type pendingBuffer struct {
+	size     int
+	messages map[string][]*message
+	mu       sync.Mutex
+}
+
+func newPendingBuffer(size int) *pendingBuffer {
+	return &pendingBuffer{
+		size:     size,
+		messages: make(map[string][]*message),
+	}
+}
+
+func (b *pendingBuffer) add(m *message) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	queue := append(b.messages[m.subject], m)
+	if len(queue) > b.size {
+		// Вытесняется самое старое сообщение
+		queue = queue[len(queue)-b.size:]
+	}
+
+	b.messages[m.subject] = queue
+}
+
+// take забирает сообщения всех subject, подходящих под pattern
+func (b *pendingBuffer) take(pattern string) []*message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var taken []*message
+	for subject, queue := range b.messages {
+		if matchPattern(pattern, subject) {
+			taken = append(taken, queue...)
+			delete(b.messages, subject)
+		}
+	}
+
+	return sortByTimestamp(taken)
+}
+
+func sortByTimestamp(messages []*message) []*message {
+	sort.SliceStable(messages, func(i, j int) bool {
+		return messages[i].timestamp.Before(messages[j].timestamp)
+	})
+
+	return messages
+}
@@ -0,0 +1,187 @@
+package subpub
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"maps"
+	"strings"
+	"time"
+)
+
+// HeaderReplyTo - заголовок запроса с subject для ответа, см. Request и Respond
+const HeaderReplyTo = "Reply-To"
+
+// InboxPrefix - префикс временных subject для ответов на Request
+const InboxPrefix = "_INBOX"
+
+// inboxMetricsSubject - все временные subject в метриках под одним значением
+const inboxMetricsSubject = InboxPrefix + tokenSeparator + singleWildcard
+
+var (
+	ErrNoResponders = errors.New("no responders")
+	ErrNoReplyTo    = errors.New("message has no reply subject")
+)
+
+/*
+Request
+
+Запрос-ответ поверх подписок: создаёт временный subject _INBOX.<id>,
+публикует msg в subject с заголовком Reply-To и ждёт первый ответ
+до отмены ctx. Без подписчиков на subject возвращает ErrNoResponders.
+*/
+func (sp *subPub) Request(ctx context.Context, subject string, msg interface{}) (*Message, error) {
+	return sp.RequestMsg(ctx, &Message{Subject: subject, Data: msg})
+}
+
+/*
+RequestMsg
+
+Request с заголовками: msg.Headers копируются и дополняются Reply-To.
+Без msg.TTL запрос живёт до дедлайна ctx - ответ на него уже не ждут.
+Как и ответы, запрос не пишется в durable log и не сохраняется
+в pending и retained (msg.Retain игнорируется), msg.Offset не заполняется.
+*/
+func (sp *subPub) RequestMsg(ctx context.Context, msg *Message) (*Message, error) {
+	if msg == nil {
+		return nil, ErrInvalidArgument
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	inbox := newInbox()
+	replies := make(chan *Message, 1)
+
+	sub, err := sp.subscribe(inbox, func(reply interface{}) {
+		// Нужен только первый ответ, остальные отбрасываются
+		select {
+		case replies <- reply.(*Message):
+		default:
+		}
+	}, nil, []SubscribeOption{WithEnvelope()})
+	if err != nil {
+		return nil, err
+	}
+	defer sub.Unsubscribe()
+
+	req := newMessage(msg.Subject, msg.Data)
+	req.headers = make(map[string]string, len(msg.Headers)+1)
+	maps.Copy(req.headers, msg.Headers)
+	req.headers[HeaderReplyTo] = inbox
+	req.request = true
+
+	ttl := msg.TTL
+	if deadline, ok := ctx.Deadline(); ok && ttl == 0 {
+		ttl = time.Until(deadline)
+	}
+	req.setTTL(ttl)
+
+	delivered, err := sp.publish(ctx, req)
+	if errors.Is(err, ErrNoSuchSubject) || err == nil && delivered == 0 {
+		return nil, ErrNoResponders
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	msg.ID, msg.Timestamp = req.id, req.timestamp
+
+	select {
+	case reply := <-replies:
+		return reply, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-sub.Done():
+		if err := sub.Err(); err != nil {
+			return nil, err
+		}
+		return nil, ErrSubPubClosed
+	}
+}
+
+/*
+newInbox
+
+Временный subject со случайным токеном: ответить может только тот,
+кто получил запрос, право publish на _INBOX.> не позволяет подменить
+ответ на чужой Request.
+*/
+func newInbox() string {
+	token := make([]byte, 16)
+	_, _ = rand.Read(token) // crypto/rand.Read не возвращает ошибок
+
+	return InboxPrefix + tokenSeparator + base64.RawURLEncoding.EncodeToString(token)
+}
+
+/*
+isInbox
+
+Временный subject Request. Ответы в него не пишутся в durable log
+и не сохраняются в pending и retained: без ожидающего Request
+они никому не нужны, а каждый subject занял бы место навсегда.
+*/
+func isInbox(subject string) bool {
+	return strings.HasPrefix(subject, InboxPrefix+tokenSeparator)
+}
+
+// ephemeral - запрос Request или ответ на него: нужен только ожидающему Request
+func (m *message) ephemeral() bool {
+	return m.request || isInbox(m.subject)
+}
+
+// ReplyTo - subject для ответа на сообщение, пусто - ответ не ожидается
+func (m *Message) ReplyTo() string {
+	return m.Headers[HeaderReplyTo]
+}
+
+/*
+Respond
+
+Публикует ответ на сообщение, полученное с WithEnvelope.
+Если запрос уже завершён по ctx, временный subject удалён
+и возвращается ошибка публикации, например ErrNoSuchSubject.
+*/
+func Respond(ps SubPub, req *Message, data interface{}) error {
+	if req == nil {
+		return ErrInvalidArgument
+	}
+
+	replyTo := req.ReplyTo()
+	if replyTo == "" {
+		return ErrNoReplyTo
+	}
+
+	_, err := ps.PublishMsg(&Message{Subject: replyTo, Data: data})
+
+	return err
+}
+
+// inboxMetrics - временные subject Request не создают отдельных серий в метриках
+type inboxMetrics struct {
+	Metrics
+}
+
+func metricsSubject(subject string) string {
+	if isInbox(subject) {
+		return inboxMetricsSubject
+	}
+	return subject
+}
+
+func (m inboxMetrics) Published(subject string) {
+	m.Metrics.Published(metricsSubject(subject))
+}
+
+func (m inboxMetrics) Delivered(subject string) {
+	m.Metrics.Delivered(metricsSubject(subject))
+}
+
+func (m inboxMetrics) Dropped(subject, reason string) {
+	m.Metrics.Dropped(metricsSubject(subject), reason)
+}
+
+func (m inboxMetrics) HandlerPanic(subject string) {
+	m.Metrics.HandlerPanic(metricsSubject(subject))
+}
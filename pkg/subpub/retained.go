@@ -0,0 +1,95 @@
+package subpub
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+/*
+retainedStore
+
+Последнее сообщение, опубликованное с Message.Retain, по каждому subject.
+Хранится отдельно от subject подписок: те удаляются вместе с последней
+подпиской, а retained значение должно дождаться следующих подписчиков.
+*/
+type retainedStore struct {
+	messages map[string]*message
+	mu       sync.RWMutex
+}
+
+func newRetainedStore() *retainedStore {
+	return &retainedStore{messages: make(map[string]*message)}
+}
+
+/*
+set
+
+Пустые данные удаляют значение subject. Хранится копия: m после
+route ещё меняет enqueue (заголовки трассировки) вне блокировки store.
+*/
+func (r *retainedStore) set(m *message) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if isEmpty(m.data) {
+		delete(r.messages, m.subject)
+		return
+	}
+
+	stored := *m
+	r.messages[m.subject] = &stored
+}
+
+/*
+match
+
+Значения всех subject, подходящих под pattern, от старых к новым.
+Копии отмечены retained для обработчика, истёкшие по TTL удаляются.
+*/
+func (r *retainedStore) match(pattern string) []*message {
+	now := time.Now()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var matched []*message
+	for subject, m := range r.messages {
+		if m.expired(now) {
+			delete(r.messages, subject)
+			continue
+		}
+		if matchPattern(pattern, subject) {
+			retained := *m
+			retained.retained = true
+			matched = append(matched, &retained)
+		}
+	}
+
+	sort.Slice(matched, func(i, j int) bool {
+		return matched[i].timestamp.Before(matched[j].timestamp)
+	})
+
+	return matched
+}
+
+func (r *retainedStore) len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return len(r.messages)
+}
+
+// isEmpty - данные retained публикации, которая очищает значение subject
+func isEmpty(data interface{}) bool {
+	switch v := data.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case []byte:
+		return len(v) == 0
+	}
+
+	return false
+}
@@ -0,0 +1,522 @@
+package subpub
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"VK_task/pkg/e"
+)
+
+const (
+	segmentExt = ".log"
+
+	// offset(8) | timestamp(8) | kind(1) | payload len(4)
+	recordHeaderSize = 21
+	recordCRCSize    = 4
+
+	payloadString byte = 1
+	payloadBytes  byte = 2
+
+	// Флаг в kind: перед данными записаны id и заголовки сообщения.
+	// Записи без флага (старый формат) читаются с пустыми id и заголовками.
+	payloadMeta byte = 0x80
+	// Флаг в kind: первым в payload записано время истечения TTL (unix ns)
+	payloadExpires byte = 0x40
+
+	maxPayloadSize = 64 << 20
+)
+
+var (
+	ErrUnsupportedMessage = errors.New("message type is not supported by durable log")
+	ErrMessageTooLarge    = errors.New("message is too large for durable log")
+
+	errCorruptedRecord = errors.New("corrupted record")
+)
+
+// subjectLog - лог одного subject, набор сегментов с возрастающими смещениями
+type subjectLog struct {
+	dir string
+	cfg LogConfig
+
+	segments   []uint64 // base offset каждого сегмента, по возрастанию
+	active     *os.File // последний сегмент, nil - не открыт, см. release
+	activeSize int64
+	next       uint64 // смещение следующей записи
+	closed     bool
+
+	mu sync.Mutex
+}
+
+func openSubjectLog(dir string, cfg LogConfig) (*subjectLog, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, e.Wrap("can't create subject dir", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, e.Wrap("can't read subject dir", err)
+	}
+
+	sl := &subjectLog{
+		dir:  dir,
+		cfg:  cfg,
+		next: 1,
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, segmentExt) {
+			continue
+		}
+
+		base, err := strconv.ParseUint(strings.TrimSuffix(name, segmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+
+		sl.segments = append(sl.segments, base)
+	}
+	sort.Slice(sl.segments, func(i, j int) bool { return sl.segments[i] < sl.segments[j] })
+
+	if len(sl.segments) == 0 {
+		if err := sl.roll(); err != nil {
+			return nil, err
+		}
+
+		return sl, nil
+	}
+
+	if err := sl.recover(); err != nil {
+		return nil, err
+	}
+
+	return sl, nil
+}
+
+/*
+recover
+
+Открывает последний сегмент на запись, находит следующее смещение
+и обрезает недописанную запись в конце (например, после падения процесса).
+*/
+func (sl *subjectLog) recover() error {
+	base := sl.segments[len(sl.segments)-1]
+
+	f, err := os.OpenFile(sl.segmentPath(base), os.O_RDWR, 0o644)
+	if err != nil {
+		return e.Wrap("can't open segment", err)
+	}
+
+	sl.next = base
+
+	var size int64
+	r := bufio.NewReader(f)
+	for {
+		m, n, err := readRecord(r, "")
+		if err != nil {
+			break
+		}
+
+		size += n
+		sl.next = m.offset + 1
+	}
+
+	if sl.next == 0 {
+		sl.next = 1
+	}
+
+	if err := f.Truncate(size); err != nil {
+		f.Close()
+		return e.Wrap("can't truncate segment", err)
+	}
+
+	// Сегмент откроется на запись при первом append, см. msgLog.touch
+	if err := f.Close(); err != nil {
+		return e.Wrap("can't close segment", err)
+	}
+
+	sl.activeSize = size
+
+	return nil
+}
+
+// open открывает последний сегмент на дозапись после recover или release
+func (sl *subjectLog) open() error {
+	f, err := os.OpenFile(sl.segmentPath(sl.segments[len(sl.segments)-1]), os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return e.Wrap("can't open segment", err)
+	}
+
+	sl.active = f
+
+	return nil
+}
+
+func (sl *subjectLog) append(m *message) error {
+	kind, payload, err := encodePayload(m)
+	if err != nil {
+		return err
+	}
+
+	sl.mu.Lock()
+	defer sl.mu.Unlock()
+
+	if sl.closed {
+		return ErrSubPubClosed
+	}
+	if sl.active == nil {
+		if err := sl.open(); err != nil {
+			return err
+		}
+	}
+
+	if sl.activeSize > 0 && sl.activeSize >= sl.cfg.SegmentSize {
+		if err := sl.roll(); err != nil {
+			return err
+		}
+	}
+
+	record := encodeRecord(sl.next, m.timestamp, kind, payload)
+
+	if _, err := sl.active.Write(record); err != nil {
+		return e.Wrap("can't write record", err)
+	}
+	if sl.cfg.Sync {
+		if err := sl.active.Sync(); err != nil {
+			return e.Wrap("can't sync segment", err)
+		}
+	}
+
+	sl.activeSize += int64(len(record))
+	m.offset = sl.next
+	sl.next++
+
+	return nil
+}
+
+// roll закрывает текущий сегмент и начинает новый с base offset = next
+func (sl *subjectLog) roll() error {
+	f, err := os.OpenFile(sl.segmentPath(sl.next), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return e.Wrap("can't create segment", err)
+	}
+
+	if sl.active != nil {
+		if err := sl.active.Close(); err != nil {
+			f.Close()
+			return e.Wrap("can't close segment", err)
+		}
+	}
+
+	if n := len(sl.segments); n == 0 || sl.segments[n-1] != sl.next {
+		sl.segments = append(sl.segments, sl.next)
+	}
+	sl.active = f
+	sl.activeSize = 0
+
+	return nil
+}
+
+func (sl *subjectLog) nextOffset() uint64 {
+	sl.mu.Lock()
+	defer sl.mu.Unlock()
+
+	return sl.next
+}
+
+/*
+replay
+
+Читает сообщения начиная со start и до смещения until (не включая).
+Чтение прекращается, если fn вернул false.
+*/
+func (sl *subjectLog) replay(start startPosition, until uint64, subject string, fn func(m *message) bool) error {
+	sl.mu.Lock()
+	segments := make([]uint64, len(sl.segments))
+	copy(segments, sl.segments)
+	sl.mu.Unlock()
+
+	// Первый сегмент, который может содержать start.offset
+	first := 0
+	if start.offset > 0 {
+		first = sort.Search(len(segments), func(i int) bool { return segments[i] > start.offset }) - 1
+		if first < 0 {
+			first = 0
+		}
+	}
+
+	for _, base := range segments[first:] {
+		if base >= until {
+			return nil
+		}
+
+		more, err := sl.replaySegment(base, start, until, subject, fn)
+		if err != nil {
+			return err
+		}
+		if !more {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+func (sl *subjectLog) replaySegment(base uint64, start startPosition, until uint64, subject string, fn func(m *message) bool) (bool, error) {
+	f, err := os.Open(sl.segmentPath(base))
+	if err != nil {
+		return false, e.Wrap("can't open segment", err)
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	for {
+		m, _, err := readRecord(r, subject)
+		if err != nil {
+			// Конец сегмента или запись, которая ещё дописывается
+			return true, nil
+		}
+
+		if m.offset >= until {
+			return false, nil
+		}
+		if m.offset < start.offset || m.timestamp.Before(start.time) {
+			continue
+		}
+
+		if !fn(m) {
+			return false, nil
+		}
+	}
+}
+
+// release закрывает файл сегмента, следующий append откроет его снова
+func (sl *subjectLog) release() error {
+	sl.mu.Lock()
+	defer sl.mu.Unlock()
+
+	return sl.closeActive()
+}
+
+func (sl *subjectLog) close() error {
+	sl.mu.Lock()
+	defer sl.mu.Unlock()
+
+	sl.closed = true
+
+	return sl.closeActive()
+}
+
+func (sl *subjectLog) closeActive() error {
+	if sl.active == nil {
+		return nil
+	}
+
+	err := sl.active.Close()
+	sl.active = nil
+
+	return err
+}
+
+func (sl *subjectLog) segmentPath(base uint64) string {
+	return filepath.Join(sl.dir, fmt.Sprintf("%020d%s", base, segmentExt))
+}
+
+// loggable - тип данных, который можно записать в durable log
+func loggable(data interface{}) bool {
+	switch data.(type) {
+	case string, []byte:
+		return true
+	}
+
+	return false
+}
+
+/*
+encodePayload
+
+payload: [expires] | [id | число заголовков | (ключ | значение)...] | данные,
+expires - uvarint unix ns, строки - uvarint длина и байты.
+expires пишется только с payloadExpires, мета-часть - только с payloadMeta.
+*/
+func encodePayload(m *message) (byte, []byte, error) {
+	var (
+		kind byte
+		data []byte
+	)
+
+	switch v := m.data.(type) {
+	case string:
+		kind, data = payloadString, []byte(v)
+	case []byte:
+		kind, data = payloadBytes, v
+	default:
+		return 0, nil, ErrUnsupportedMessage
+	}
+
+	var payload []byte
+	if !m.expires.IsZero() {
+		kind |= payloadExpires
+
+		payload = binary.AppendUvarint(payload, uint64(m.expires.UnixNano()))
+	}
+	if m.id != "" || len(m.headers) > 0 {
+		kind |= payloadMeta
+
+		payload = appendString(payload, m.id)
+		payload = binary.AppendUvarint(payload, uint64(len(m.headers)))
+		for k, v := range m.headers {
+			payload = appendString(payload, k)
+			payload = appendString(payload, v)
+		}
+	}
+	payload = append(payload, data...)
+
+	if len(payload) > maxPayloadSize {
+		return 0, nil, ErrMessageTooLarge
+	}
+
+	return kind, payload, nil
+}
+
+func decodePayload(kind byte, payload []byte, m *message) error {
+	if kind&payloadExpires != 0 {
+		expires, rest, err := readUvarint(payload)
+		if err != nil {
+			return err
+		}
+
+		m.expires = time.Unix(0, int64(expires))
+		payload = rest
+	}
+
+	if kind&payloadMeta != 0 {
+		var (
+			err error
+			n   uint64
+		)
+
+		if m.id, payload, err = readString(payload); err != nil {
+			return err
+		}
+
+		if n, payload, err = readUvarint(payload); err != nil {
+			return err
+		}
+		if n > uint64(len(payload)) {
+			return errCorruptedRecord
+		}
+
+		if n > 0 {
+			m.headers = make(map[string]string, n)
+		}
+		for i := uint64(0); i < n; i++ {
+			var k, v string
+			if k, payload, err = readString(payload); err != nil {
+				return err
+			}
+			if v, payload, err = readString(payload); err != nil {
+				return err
+			}
+			m.headers[k] = v
+		}
+	}
+
+	switch kind &^ (payloadMeta | payloadExpires) {
+	case payloadString:
+		m.data = string(payload)
+	case payloadBytes:
+		m.data = payload
+	default:
+		return errCorruptedRecord
+	}
+
+	return nil
+}
+
+func appendString(b []byte, s string) []byte {
+	b = binary.AppendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+func readUvarint(b []byte) (uint64, []byte, error) {
+	v, n := binary.Uvarint(b)
+	if n <= 0 {
+		return 0, nil, errCorruptedRecord
+	}
+	return v, b[n:], nil
+}
+
+func readString(b []byte) (string, []byte, error) {
+	size, b, err := readUvarint(b)
+	if err != nil {
+		return "", nil, err
+	}
+	if size > uint64(len(b)) {
+		return "", nil, errCorruptedRecord
+	}
+	return string(b[:size]), b[size:], nil
+}
+
+// Запись: header | payload | crc32(header + payload)
+func encodeRecord(offset uint64, ts time.Time, kind byte, payload []byte) []byte {
+	record := make([]byte, recordHeaderSize+len(payload)+recordCRCSize)
+
+	binary.BigEndian.PutUint64(record[0:8], offset)
+	binary.BigEndian.PutUint64(record[8:16], uint64(ts.UnixNano()))
+	record[16] = kind
+	binary.BigEndian.PutUint32(record[17:21], uint32(len(payload)))
+	copy(record[recordHeaderSize:], payload)
+
+	end := recordHeaderSize + len(payload)
+	binary.BigEndian.PutUint32(record[end:], crc32.ChecksumIEEE(record[:end]))
+
+	return record
+}
+
+// readRecord возвращает сообщение и размер записи в байтах
+func readRecord(r io.Reader, subject string) (*message, int64, error) {
+	header := make([]byte, recordHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, 0, err
+	}
+
+	size := binary.BigEndian.Uint32(header[17:21])
+	if size > maxPayloadSize {
+		return nil, 0, errCorruptedRecord
+	}
+
+	body := make([]byte, int(size)+recordCRCSize)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, 0, err
+	}
+
+	crc := crc32.ChecksumIEEE(header)
+	crc = crc32.Update(crc, crc32.IEEETable, body[:size])
+	if crc != binary.BigEndian.Uint32(body[size:]) {
+		return nil, 0, errCorruptedRecord
+	}
+
+	m := &message{
+		subject:   subject,
+		offset:    binary.BigEndian.Uint64(header[0:8]),
+		timestamp: time.Unix(0, int64(binary.BigEndian.Uint64(header[8:16]))),
+	}
+
+	if err := decodePayload(header[16], body[:size], m); err != nil {
+		return nil, 0, err
+	}
+
+	return m, int64(recordHeaderSize) + int64(len(body)), nil
+}
@@ -1,12 +1,14 @@
 package subpub
 
 import (
+	"context"
 	"sync"
 )
 
 type subject struct {
 	subscribers map[string]*subscription
-	queue       chan interface{}
+	groups      map[string]*queueGroup // подписчики с группой есть и в subscribers
+	queue       chan *message
 	mu          sync.RWMutex
 
 	closed bool // true when chan queue is closed
@@ -15,13 +17,23 @@ type subject struct {
 func newSubject(bufferSize int) *subject {
 	return &subject{
 		subscribers: make(map[string]*subscription, 8),
-		queue:       make(chan interface{}, bufferSize),
+		groups:      make(map[string]*queueGroup),
+		queue:       make(chan *message, bufferSize),
 	}
 }
 
 func (s *subject) registerSubscriber(sub *subscription) {
 	s.mu.Lock()
 	s.subscribers[sub.id] = sub
+
+	if sub.group != "" {
+		g, ok := s.groups[sub.group]
+		if !ok {
+			g = &queueGroup{}
+			s.groups[sub.group] = g
+		}
+		g.add(sub)
+	}
 	s.mu.Unlock()
 }
 
@@ -29,24 +41,53 @@ func (s *subject) unregisterSubscriber(id string) bool {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	if sub, ok := s.subscribers[id]; ok && sub.group != "" {
+		if g := s.groups[sub.group]; g != nil && g.remove(id) {
+			delete(s.groups, sub.group)
+		}
+	}
+
 	delete(s.subscribers, id)
 	return len(s.subscribers) == 0
 }
 
-func (s *subject) publish(msg interface{}, closeChan <-chan struct{}) error {
+// onlySubscriber - sub единственная подписка subject
+func (s *subject) onlySubscriber(sub *subscription) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.subscribers) == 1 && s.subscribers[sub.id] == sub
+}
+
+// receivers - сколько подписчиков получит сообщение: группа считается за одного
+func (s *subject) receivers() int {
+	n := len(s.subscribers)
+	for _, g := range s.groups {
+		n -= len(g.members) - 1
+	}
+	return n
+}
+
+/*
+publish возвращает число получателей subject на момент публикации.
+Ждёт места в очереди subject, пока не закрыта шина или не истёк ctx.
+*/
+func (s *subject) publish(ctx context.Context, msg *message, closeChan <-chan struct{}) (int, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	// Защита от паники при записи в закрытый queue канал
 	if s.closed {
-		return nil
+		return 0, nil
 	}
 
 	select {
 	case s.queue <- msg:
-		return nil
+		return s.receivers(), nil
 	case <-closeChan:
-		return ErrSubPubClosed
+		return 0, ErrSubPubClosed
+	case <-ctx.Done():
+		return 0, ctx.Err()
 	}
 }
 
@@ -65,12 +106,21 @@ func (s *subject) dispatchMessages(closeChan <-chan struct{}) {
 	}
 }
 
-func (s *subject) deliverMessage(msg interface{}) {
+func (s *subject) deliverMessage(msg *message) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	for _, sub := range s.subscribers {
-		sub.deliver(msg)
+		if sub.group == "" && sub != msg.origin {
+			sub.deliver(msg)
+		}
+	}
+
+	// pick меняет только next, а deliverMessage вызывается из одной горутины
+	for _, g := range s.groups {
+		if sub := g.pick(msg.origin); sub != nil {
+			sub.deliver(msg)
+		}
 	}
 }
 
@@ -0,0 +1,183 @@
+package subpub
+
+import "strings"
+
+const (
+	tokenSeparator = "."
+	singleWildcard = "*" // ровно один токен
+	fullWildcard   = ">" // один и более токенов в конце
+)
+
+/*
+sublist
+
+Дерево токенов subject. Хранит все subject (обычные и шаблоны),
+по нему Publish находит subject, которым подходит опубликованный ключ.
+*/
+type sublist struct {
+	root *node
+}
+
+type node struct {
+	children map[string]*node
+	subj     *subject // subject, шаблон которого заканчивается на этом узле
+}
+
+func newSublist() *sublist {
+	return &sublist{root: newNode()}
+}
+
+func newNode() *node {
+	return &node{children: make(map[string]*node)}
+}
+
+func (l *sublist) insert(pattern string, subj *subject) {
+	n := l.root
+	for _, token := range strings.Split(pattern, tokenSeparator) {
+		child, ok := n.children[token]
+		if !ok {
+			child = newNode()
+			n.children[token] = child
+		}
+		n = child
+	}
+	n.subj = subj
+}
+
+func (l *sublist) remove(pattern string) {
+	tokens := strings.Split(pattern, tokenSeparator)
+
+	// Путь нужен, чтобы удалить опустевшие узлы снизу вверх
+	path := make([]*node, 0, len(tokens)+1)
+	path = append(path, l.root)
+
+	n := l.root
+	for _, token := range tokens {
+		child, ok := n.children[token]
+		if !ok {
+			return
+		}
+		path = append(path, child)
+		n = child
+	}
+	n.subj = nil
+
+	for i := len(tokens) - 1; i >= 0; i-- {
+		child := path[i+1]
+		if child.subj != nil || len(child.children) > 0 {
+			return
+		}
+		delete(path[i].children, tokens[i])
+	}
+}
+
+// match возвращает все subject, шаблоны которых подходят под literal
+func (l *sublist) match(literal string) []*subject {
+	var result []*subject
+	matchTokens(l.root, strings.Split(literal, tokenSeparator), &result)
+	return result
+}
+
+func matchTokens(n *node, tokens []string, result *[]*subject) {
+	if len(tokens) == 0 {
+		if n.subj != nil {
+			*result = append(*result, n.subj)
+		}
+		return
+	}
+
+	if fwc, ok := n.children[fullWildcard]; ok && fwc.subj != nil {
+		*result = append(*result, fwc.subj)
+	}
+	if pwc, ok := n.children[singleWildcard]; ok {
+		matchTokens(pwc, tokens[1:], result)
+	}
+	if child, ok := n.children[tokens[0]]; ok {
+		matchTokens(child, tokens[1:], result)
+	}
+}
+
+// matchPattern проверяет, подходит ли literal под шаблон pattern
+func matchPattern(pattern, literal string) bool {
+	pTokens := strings.Split(pattern, tokenSeparator)
+	lTokens := strings.Split(literal, tokenSeparator)
+
+	for i, token := range pTokens {
+		if token == fullWildcard {
+			return len(lTokens) > i
+		}
+		if i >= len(lTokens) {
+			return false
+		}
+		if token != singleWildcard && token != lTokens[i] {
+			return false
+		}
+	}
+
+	return len(pTokens) == len(lTokens)
+}
+
+/*
+Covers
+
+Покрывает ли шаблон pattern все subject шаблона sub: например,
+"orders.>" покрывает "orders.*.created", а "orders.*" не покрывает "orders.>".
+Для sub без шаблонов - то же, что совпадение с pattern.
+*/
+func Covers(pattern, sub string) bool {
+	pTokens := strings.Split(pattern, tokenSeparator)
+	sTokens := strings.Split(sub, tokenSeparator)
+
+	for i, token := range pTokens {
+		if token == fullWildcard {
+			return len(sTokens) > i
+		}
+		if i >= len(sTokens) || sTokens[i] == fullWildcard {
+			return false
+		}
+		if token != singleWildcard && token != sTokens[i] {
+			return false
+		}
+	}
+
+	return len(pTokens) == len(sTokens)
+}
+
+// ValidPattern - subject допустим для Subscribe
+func ValidPattern(pattern string) bool {
+	return validPattern(pattern)
+}
+
+/*
+validPattern
+
+Проверяет subject для Subscribe: токены не пустые,
+`*` и `>` занимают токен целиком, `>` только последний.
+*/
+func validPattern(pattern string) bool {
+	tokens := strings.Split(pattern, tokenSeparator)
+	for i, token := range tokens {
+		switch {
+		case token == "":
+			return false
+		case token == singleWildcard:
+		case token == fullWildcard:
+			if i != len(tokens)-1 {
+				return false
+			}
+		case strings.ContainsAny(token, singleWildcard+fullWildcard):
+			return false
+		}
+	}
+	return true
+}
+
+// validLiteral проверяет subject для Publish: шаблоны не допускаются
+func validLiteral(literal string) bool {
+	for _, token := range strings.Split(literal, tokenSeparator) {
+		if token == "" || strings.ContainsAny(token, singleWildcard+fullWildcard) {
+			return false
+		}
+	}
+	return true
+}
@@ -4,11 +4,20 @@ import (
 	"context"
 	"errors"
 	"log/slog"
+	"slices"
 	"sync"
+	"time"
+
+	"VK_task/internal/pkg/logger/sl"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type subPub struct {
 	subjects map[string]*subject
+	sublist  *sublist              // для поиска subject по шаблонам
+	draining map[*subject]struct{} // отключённые DrainSubject, до закрытия их подписок
 	mu       sync.RWMutex
 
 	closed    bool // true when subPub is closed
@@ -16,25 +25,132 @@ type subPub struct {
 
 	wg sync.WaitGroup // MessageHandler WaitGroup
 
-	log *slog.Logger
-	cfg *Config
+	msgLog  *msgLog        // nil, если durable log выключен
+	pending *pendingBuffer // nil, если политика не NoSubscribersRetain
+
+	// Dead-letter сообщения без подписчиков, если их не сохранят pending или лог
+	deadLetters *pendingBuffer
+
+	retained *retainedStore
+
+	log     *slog.Logger
+	cfg     *Config
+	metrics Metrics
+	tracer  trace.Tracer
 }
 
 var (
 	ErrNoSuchSubject   = errors.New("no such subject")
 	ErrInvalidArgument = errors.New("invalid argument")
+	ErrInvalidSubject  = errors.New("invalid subject")
 	ErrSubPubClosed    = errors.New("subPub system is closed")
+	ErrLogDisabled     = errors.New("durable log is disabled")
+	ErrSlowConsumer    = errors.New("subscription disconnected as slow consumer")
+
+	ErrNoSuchSubscription = errors.New("no such subscription")
+	ErrSubscriptionClosed = errors.New("subscription closed by administrator")
+	ErrSubjectDrained     = errors.New("subject drained")
 )
 
 /*
 Subscribe
 
 Если subject не существует, он будет создан.
+
+subject - токены через точку, допускаются шаблоны:
+`*` - ровно один токен, `>` - один и более токенов в конце.
+Например "orders.*.created" или "orders.>".
+
+С опциями StartAt* подписка сначала получит сохранённые
+сообщения из durable log, затем новые.
+*/
+func (sp *subPub) Subscribe(subject string, cb MessageHandler, opts ...SubscribeOption) (Subscription, error) {
+	if cb == nil {
+		return nil, ErrInvalidArgument
+	}
+
+	sub, err := sp.subscribe(subject, cb, nil, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return sub, nil
+}
+
+/*
+QueueSubscribe
+
+Подписка в группе group: подписки на тот же subject с той же группой
+получают сообщения по очереди, каждое - только одна из них.
+То же, что Subscribe с WithQueueGroup(group).
 */
-func (sp *subPub) Subscribe(subject string, cb MessageHandler) (Subscription, error) {
-	if subject == "" || cb == nil {
+func (sp *subPub) QueueSubscribe(subject, group string, cb MessageHandler, opts ...SubscribeOption) (Subscription, error) {
+	if group == "" {
+		return nil, ErrInvalidArgument
+	}
+
+	return sp.Subscribe(subject, cb, append(opts, WithQueueGroup(group))...)
+}
+
+/*
+SubscribeAck
+
+Подписка с подтверждениями (at-least-once): сообщение без Ack
+доставляется повторно после Nack или AckTimeout, после MaxAttempts
+попыток уходит в dead-letter subject (Config.DeadLetterPrefix или WithDeadLetter).
+*/
+func (sp *subPub) SubscribeAck(subject string, cb AckHandler, opts ...SubscribeOption) (AckSubscription, error) {
+	if cb == nil {
+		return nil, ErrInvalidArgument
+	}
+
+	sub, err := sp.subscribe(subject, nil, cb, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return sub, nil
+}
+
+/*
+SubscribeContext
+
+Как Subscribe, но подписка завершается при отмене ctx:
+Err возвращает context.Canceled или context.DeadlineExceeded.
+Если ctx уже отменён, подписка не создаётся.
+*/
+func (sp *subPub) SubscribeContext(ctx context.Context, subject string, cb MessageHandler, opts ...SubscribeOption) (Subscription, error) {
+	if cb == nil {
+		return nil, ErrInvalidArgument
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	sub, err := sp.subscribe(subject, cb, nil, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if ctx.Done() != nil {
+		go sub.closeOnDone(ctx)
+	}
+
+	return sub, nil
+}
+
+func (sp *subPub) subscribe(subject string, cb MessageHandler, ackCb AckHandler, opts []SubscribeOption) (*subscription, error) {
+	if subject == "" {
 		return nil, ErrInvalidArgument
 	}
+	if !validPattern(subject) {
+		return nil, ErrInvalidSubject
+	}
+
+	o := newSubscribeOptions(opts)
+	if o.start != nil && sp.msgLog == nil {
+		return nil, ErrLogDisabled
+	}
 
 	sp.mu.RLock()
 	if sp.closed {
@@ -46,45 +162,369 @@ func (sp *subPub) Subscribe(subject string, cb MessageHandler) (Subscription, er
 	sp.mu.RUnlock()
 
 	if !exists {
-		subj = newSubject(sp.cfg.SubjectBuffer)
-
-		sp.addSubject(subject, subj)
+		var created bool
 
-		go subj.dispatchMessages(sp.closeChan)
+		subj, created = sp.addSubject(subject, newSubject(sp.cfg.SubjectBuffer))
+		if created {
+			go subj.dispatchMessages(sp.closeChan)
+		}
 	}
 
-	sub := newSubscription(subject, cb, sp)
+	sub := newSubscription(subject, cb, sp, o)
+	sub.subj = subj
+	if ackCb != nil {
+		sub.acks = newAckTracker(sub, ackCb, o)
+	}
 
 	subj.registerSubscriber(sub)
+	sp.metrics.Subscriptions(1)
+
+	// Границы лога фиксируются после регистрации,
+	// чтобы между логом и очередью не было пропусков
+	if o.start != nil {
+		sub.cursors = sp.msgLog.cursors(subject, o.start)
+	}
+	if sp.pending != nil {
+		sub.pending = sp.pending.take(subject)
+	}
+	if dead := sp.deadLetters.take(subject); len(dead) > 0 {
+		sub.pending = sortByTimestamp(append(sub.pending, dead...))
+	}
+	// Группа делит новые сообщения, а воспроизведение лога и так отдаст последнее значение
+	if o.group == "" && o.start == nil {
+		sub.setRetained(sp.retained.match(subject))
+	}
 
 	go sub.dispatchMessages()
 
+	if sub.acks != nil {
+		go sub.acks.watchTimeouts()
+	}
+
 	return sub, nil
 }
 
 /*
 Publish
 
-Если subject не существует, возвращает ошибку ErrNoSuchSubject.
+Сообщение получат все subject, шаблоны которых подходят под subject.
+Сам subject шаблоном быть не может.
+
+Если подходящих subject нет, поведение задаёт Config.NoSubscribers:
+ErrNoSuchSubject, отбросить или сохранить для первых подписчиков.
+При включённом durable log сообщение сначала записывается в лог,
+и ошибки ErrNoSuchSubject не будет.
 */
 func (sp *subPub) Publish(subject string, msg interface{}) error {
-	if subject == "" || msg == nil {
-		return ErrInvalidArgument
+	_, err := sp.publish(context.Background(), newMessage(subject, msg))
+	return err
+}
+
+/*
+PublishContext
+
+Как PublishCount, но ожидание места в заполненной очереди subject
+прерывается по ctx с ошибкой ctx.Err(). Если subject несколько,
+сообщение могло попасть в очереди части из них - число получателей
+в них возвращается вместе с ошибкой. Запись в durable log не отменяется.
+*/
+func (sp *subPub) PublishContext(ctx context.Context, subject string, msg interface{}) (int, error) {
+	return sp.publish(ctx, newMessage(subject, msg))
+}
+
+/*
+PublishCount
+
+Как Publish, дополнительно возвращает число подписчиков,
+в очереди которых попало сообщение.
+*/
+func (sp *subPub) PublishCount(subject string, msg interface{}) (int, error) {
+	return sp.publish(context.Background(), newMessage(subject, msg))
+}
+
+/*
+PublishMsg
+
+Публикация с заголовками: берутся msg.Subject, msg.Headers и msg.Data.
+При успехе в msg записываются ID, Timestamp и Offset сообщения.
+*/
+func (sp *subPub) PublishMsg(msg *Message) (int, error) {
+	return sp.PublishMsgContext(context.Background(), msg)
+}
+
+// PublishMsgContext - PublishMsg с ожиданием места в очередях до отмены ctx, см. PublishContext
+func (sp *subPub) PublishMsgContext(ctx context.Context, msg *Message) (int, error) {
+	if msg == nil {
+		return 0, ErrInvalidArgument
+	}
+
+	m := newMessage(msg.Subject, msg.Data)
+	m.headers = msg.Headers
+	m.retain = msg.Retain
+	m.setTTL(msg.TTL)
+
+	n, err := sp.publish(ctx, m)
+	if err != nil {
+		return n, err
+	}
+
+	msg.ID = m.id
+	msg.Timestamp = m.timestamp
+	msg.Offset = m.offset
+
+	return n, nil
+}
+
+func (sp *subPub) publish(ctx context.Context, m *message) (int, error) {
+	if err := validateMessage(m); err != nil {
+		return 0, err
+	}
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	if err := sp.persist(m); err != nil {
+		return 0, err
 	}
 
 	sp.mu.RLock()
 	if sp.closed {
 		sp.mu.RUnlock()
-		return ErrSubPubClosed
+		return 0, ErrSubPubClosed
 	}
 
-	subj, exists := sp.subjects[subject]
+	subjs, err := sp.route(m)
 	sp.mu.RUnlock()
-	if !exists {
-		return ErrNoSuchSubject
+
+	if err != nil {
+		return 0, err
 	}
 
-	return subj.publish(msg, sp.closeChan)
+	return sp.enqueue(ctx, m, subjs)
+}
+
+/*
+PublishBatch
+
+Публикация нескольких сообщений: блокировка SubPub берётся один раз
+на весь пакет. Результат по каждому сообщению - в том же порядке,
+как в PublishMsg; при успехе в msgs заполняются ID, Timestamp и Offset.
+*/
+func (sp *subPub) PublishBatch(msgs []*Message) []PublishResult {
+	results := make([]PublishResult, len(msgs))
+	batch := make([]*message, len(msgs))
+
+	for i, msg := range msgs {
+		if msg == nil {
+			results[i].Err = ErrInvalidArgument
+			continue
+		}
+
+		m := newMessage(msg.Subject, msg.Data)
+		m.headers = msg.Headers
+		m.retain = msg.Retain
+		m.setTTL(msg.TTL)
+
+		if err := validateMessage(m); err != nil {
+			results[i].Err = err
+			continue
+		}
+		if err := sp.persist(m); err != nil {
+			results[i].Err = err
+			continue
+		}
+		batch[i] = m
+	}
+
+	routes := make([][]*subject, len(msgs))
+
+	sp.mu.RLock()
+	closed := sp.closed
+	for i, m := range batch {
+		if m == nil {
+			continue
+		}
+		if closed {
+			results[i].Err = ErrSubPubClosed
+			continue
+		}
+
+		routes[i], results[i].Err = sp.route(m)
+	}
+	sp.mu.RUnlock()
+
+	for i, m := range batch {
+		if m == nil || results[i].Err != nil {
+			continue
+		}
+
+		results[i].Delivered, results[i].Err = sp.enqueue(context.Background(), m, routes[i])
+		if results[i].Err != nil {
+			continue
+		}
+
+		msgs[i].ID = m.id
+		msgs[i].Timestamp = m.timestamp
+		msgs[i].Offset = m.offset
+	}
+
+	return results
+}
+
+func validateMessage(m *message) error {
+	if m.subject == "" || m.data == nil && (!m.retain || m.ephemeral()) {
+		return ErrInvalidArgument
+	}
+	if !validLiteral(m.subject) {
+		return ErrInvalidSubject
+	}
+
+	return nil
+}
+
+/*
+defaultTTL
+
+TTL сообщения без Message.TTL: самый короткий из Config.SubjectTTL
+по подходящим шаблонам, иначе Config.MessageTTL.
+*/
+func (sp *subPub) defaultTTL(subject string) time.Duration {
+	var ttl time.Duration
+
+	for pattern, d := range sp.cfg.SubjectTTL {
+		if d > 0 && (ttl == 0 || d < ttl) && matchPattern(pattern, subject) {
+			ttl = d
+		}
+	}
+
+	if ttl == 0 {
+		return sp.cfg.MessageTTL
+	}
+	return ttl
+}
+
+/*
+persist
+
+TTL по умолчанию и запись в durable log. Вызывается до route и без
+sp.mu: медленный диск не задерживает Subscribe и Close. Запись в лог
+до поиска subject, см. Subscribe: сообщение, записанное до фиксации
+границ курсоров, подписка получит из лога, а повтор в очереди
+отбросит isReplayed. Данные не string и []byte в лог не пишутся
+и доставляются только внутри процесса, с Offset 0.
+*/
+func (sp *subPub) persist(m *message) error {
+	if m.expires.IsZero() {
+		m.setTTL(sp.defaultTTL(m.subject))
+	}
+
+	if sp.msgLog == nil || m.retain && isEmpty(m.data) || !loggable(m.data) || m.ephemeral() {
+		return nil
+	}
+
+	return sp.msgLog.append(m)
+}
+
+/*
+route
+
+Находит подходящие subject для сообщения после persist.
+Вызывается под sp.mu.RLock. Пустой результат без ошибки - сообщение
+сохранено в логе или pending, либо отброшено по NoSubscribersDrop.
+*/
+func (sp *subPub) route(m *message) ([]*subject, error) {
+	ephemeral := m.ephemeral()
+	if ephemeral {
+		m.retain = false
+	}
+
+	// Пустая retained публикация только очищает значение subject
+	if m.retain && isEmpty(m.data) {
+		sp.retained.set(m)
+		return nil, nil
+	}
+
+	sp.metrics.Published(m.subject)
+
+	// До поиска subject: подписка, созданная позже, получит сообщение
+	// из retained или из очереди, повтор отбрасывает isRetained
+	if m.retain {
+		sp.retained.set(m)
+	}
+
+	subjs := sp.sublist.match(m.subject)
+	if m.origin != nil {
+		subjs = slices.DeleteFunc(subjs, func(subj *subject) bool {
+			return subj.onlySubscriber(m.origin)
+		})
+	}
+	if len(subjs) == 0 {
+		// Под RLock, чтобы Subscribe не создал subject между match и add.
+		// Retained сообщение и так получит первая подписка, а запрос
+		// или ответ без ожидающей стороны не нужен никому
+		switch {
+		case m.retain, ephemeral:
+		case sp.pending != nil:
+			sp.pending.add(m)
+		case m.deadLetter && m.offset == 0:
+			// Dead-letter сообщение не теряется при NoSubscribersError и NoSubscribersDrop
+			sp.deadLetters.add(m)
+		}
+
+		if m.offset != 0 || m.retain || m.deadLetter || sp.cfg.NoSubscribers == NoSubscribersRetain && !ephemeral {
+			return nil, nil
+		}
+		if sp.cfg.NoSubscribers != NoSubscribersError {
+			sp.metrics.Dropped(m.subject, DropNoSubscribers)
+			return nil, nil
+		}
+		return nil, ErrNoSuchSubject
+	}
+
+	return subjs, nil
+}
+
+// enqueue кладёт сообщение в очереди subject, возвращает число получателей
+func (sp *subPub) enqueue(ctx context.Context, m *message, subjs []*subject) (delivered int, err error) {
+	if len(subjs) == 0 {
+		return 0, nil
+	}
+
+	span := sp.startEnqueueSpan(m)
+	defer func() {
+		span.SetAttributes(attribute.Int("messaging.delivered", delivered))
+		endSpan(span, err)
+	}()
+
+	for _, subj := range subjs {
+		sp.metrics.SubjectQueueFill(fillRatio(len(subj.queue), cap(subj.queue)))
+
+		var n int
+		n, err = subj.publish(ctx, m, sp.closeChan)
+		if err != nil {
+			return delivered, err
+		}
+		delivered += n
+	}
+
+	return delivered, nil
+}
+
+/*
+beginHandler
+
+Учитывает запуск обработчика в wg. Add под блокировкой: после Close
+новые обработчики не запускаются, и Add не пересекается с wg.Wait.
+*/
+func (sp *subPub) beginHandler() bool {
+	sp.mu.RLock()
+	defer sp.mu.RUnlock()
+
+	if sp.closed {
+		return false
+	}
+	sp.wg.Add(1)
+
+	return true
 }
 
 func (sp *subPub) Close(ctx context.Context) error {
@@ -101,6 +541,15 @@ func (sp *subPub) Close(ctx context.Context) error {
 	for _, subj := range sp.subjects {
 		subj.close()
 	}
+	for subj := range sp.draining {
+		subj.close()
+	}
+
+	if sp.msgLog != nil {
+		if err := sp.msgLog.close(); err != nil {
+			sp.log.Error("Durable log close failed", sl.Err(err))
+		}
+	}
 
 	sp.mu.Unlock()
 
@@ -118,14 +567,31 @@ func (sp *subPub) Close(ctx context.Context) error {
 	}
 }
 
-func (sp *subPub) addSubject(subject string, subj *subject) {
+/*
+addSubject
+
+Если subject уже создан параллельным Subscribe, возвращает его и false.
+*/
+func (sp *subPub) addSubject(subject string, subj *subject) (*subject, bool) {
 	sp.mu.Lock()
+	defer sp.mu.Unlock()
+
+	if existing, ok := sp.subjects[subject]; ok {
+		return existing, false
+	}
+
 	sp.subjects[subject] = subj
-	sp.mu.Unlock()
+	sp.sublist.insert(subject, subj)
+
+	return subj, true
 }
 
-func (sp *subPub) removeSubject(subject string) {
+// removeSubject - subj мог быть уже отключён DrainSubject и заменён новым
+func (sp *subPub) removeSubject(subject string, subj *subject) {
 	sp.mu.Lock()
-	delete(sp.subjects, subject)
+	if sp.subjects[subject] == subj {
+		delete(sp.subjects, subject)
+		sp.sublist.remove(subject)
+	}
 	sp.mu.Unlock()
 }
@@ -0,0 +1,247 @@
+package subpub_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"VK_task/pkg/subpub"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubPubAck(t *testing.T) {
+	newAckSubPub := func() subpub.SubPub {
+		cfg := subpub.DefaultConfig()
+		cfg.AckTimeout = 100 * time.Millisecond
+		cfg.MaxAttempts = 3
+
+		return subpub.NewSubPub(cfg, slog.Default())
+	}
+
+	t.Run("Handler error is redelivered", func(t *testing.T) {
+		sp := newAckSubPub()
+		defer sp.Close(context.Background())
+
+		attempts := make(chan int, 8)
+		_, err := sp.SubscribeAck("test", func(d *subpub.Delivery) error {
+			attempts <- d.Attempt
+			if d.Attempt < 2 {
+				return errors.New("temporary failure")
+			}
+			return nil
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, sp.Publish("test", "data"))
+
+		assert.Equal(t, 1, <-attempts)
+		assert.Equal(t, 2, <-attempts)
+
+		select {
+		case n := <-attempts:
+			t.Fatalf("unexpected attempt %d after success", n)
+		case <-time.After(300 * time.Millisecond):
+		}
+	})
+
+	t.Run("Panic is redelivered", func(t *testing.T) {
+		sp := newAckSubPub()
+		defer sp.Close(context.Background())
+
+		attempts := make(chan int, 8)
+		_, err := sp.SubscribeAck("test", func(d *subpub.Delivery) error {
+			attempts <- d.Attempt
+			if d.Attempt == 1 {
+				panic("test panic")
+			}
+			return nil
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, sp.Publish("test", "data"))
+
+		assert.Equal(t, 1, <-attempts)
+		assert.Equal(t, 2, <-attempts)
+	})
+
+	t.Run("Manual ack timeout", func(t *testing.T) {
+		sp := newAckSubPub()
+		defer sp.Close(context.Background())
+
+		deliveries := make(chan *subpub.Delivery, 8)
+		sub, err := sp.SubscribeAck("test", func(d *subpub.Delivery) error {
+			deliveries <- d
+			return nil
+		}, subpub.WithManualAck())
+		require.NoError(t, err)
+
+		require.NoError(t, sp.Publish("test", "data"))
+
+		first := <-deliveries
+		assert.Equal(t, 1, first.Attempt)
+
+		// Not acknowledged in time
+		second := <-deliveries
+		assert.Equal(t, 2, second.Attempt)
+		assert.Equal(t, first.Tag, second.Tag)
+		assert.Equal(t, "data", second.Data)
+
+		require.NoError(t, sub.Ack(second.Tag))
+		assert.Equal(t, subpub.ErrUnknownDelivery, sub.Ack(second.Tag))
+
+		select {
+		case d := <-deliveries:
+			t.Fatalf("unexpected redelivery, attempt %d", d.Attempt)
+		case <-time.After(300 * time.Millisecond):
+		}
+	})
+
+	t.Run("Dead letter after max attempts", func(t *testing.T) {
+		sp := newAckSubPub()
+		defer sp.Close(context.Background())
+
+		dead := make(chan interface{}, 1)
+		_, err := sp.Subscribe(subpub.DefaultDeadLetterPrefix+".orders.1", func(msg interface{}) {
+			dead <- msg
+		})
+		require.NoError(t, err)
+
+		attempts := make(chan int, 8)
+		_, err = sp.SubscribeAck("orders.*", func(d *subpub.Delivery) error {
+			attempts <- d.Attempt
+			return d.Nack()
+		}, subpub.WithManualAck())
+		require.NoError(t, err)
+
+		require.NoError(t, sp.Publish("orders.1", "poison"))
+
+		for i := 1; i <= 3; i++ {
+			assert.Equal(t, i, <-attempts)
+		}
+
+		select {
+		case msg := <-dead:
+			assert.Equal(t, "poison", msg)
+		case <-time.After(time.Second):
+			t.Fatal("message did not reach dead-letter subject")
+		}
+	})
+
+	t.Run("Dead letter kept without subscribers", func(t *testing.T) {
+		// DefaultConfig - NoSubscribersError
+		sp := newAckSubPub()
+		defer sp.Close(context.Background())
+
+		attempts := make(chan int, 8)
+		_, err := sp.SubscribeAck("orders.*", func(d *subpub.Delivery) error {
+			attempts <- d.Attempt
+			return d.Nack()
+		}, subpub.WithManualAck())
+		require.NoError(t, err)
+
+		require.NoError(t, sp.Publish("orders.1", "poison"))
+
+		for i := 1; i <= 3; i++ {
+			assert.Equal(t, i, <-attempts)
+		}
+
+		dead := make(chan interface{}, 1)
+		require.Eventually(t, func() bool {
+			_, err := sp.Subscribe(subpub.DefaultDeadLetterPrefix+".>", func(msg interface{}) {
+				dead <- msg
+			})
+			require.NoError(t, err)
+
+			select {
+			case msg := <-dead:
+				assert.Equal(t, "poison", msg)
+				return true
+			case <-time.After(50 * time.Millisecond):
+				return false
+			}
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("Dead letter is not delivered to its wildcard subscription", func(t *testing.T) {
+		sp := newAckSubPub()
+
+		subjects := make(chan string, 16)
+		_, err := sp.SubscribeAck(">", func(d *subpub.Delivery) error {
+			subjects <- d.Subject
+			return d.Nack()
+		}, subpub.WithManualAck(), subpub.WithOverflow(subpub.OverflowBlock))
+		require.NoError(t, err)
+
+		require.NoError(t, sp.Publish("orders.1", "poison"))
+
+		for i := 1; i <= 3; i++ {
+			assert.Equal(t, "orders.1", <-subjects)
+		}
+
+		select {
+		case subject := <-subjects:
+			t.Fatalf("dead letter %q delivered back to its subscription", subject)
+		case <-time.After(300 * time.Millisecond):
+		}
+
+		// Без других подписчиков dead letter сохраняется для новой подписки
+		dead := make(chan *subpub.Message, 1)
+		_, err = sp.Subscribe(subpub.DefaultDeadLetterPrefix+".>", func(msg interface{}) {
+			dead <- msg.(*subpub.Message)
+		}, subpub.WithEnvelope())
+		require.NoError(t, err)
+
+		select {
+		case msg := <-dead:
+			assert.Equal(t, subpub.DefaultDeadLetterPrefix+".orders.1", msg.Subject)
+			assert.Equal(t, "poison", msg.Data)
+		case <-time.After(time.Second):
+			t.Fatal("dead letter was not kept")
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		assert.NoError(t, sp.Close(ctx))
+	})
+
+	t.Run("Expired message is not redelivered", func(t *testing.T) {
+		sp := newAckSubPub()
+		defer sp.Close(context.Background())
+
+		attempts := make(chan int, 8)
+		_, err := sp.SubscribeAck("test", func(d *subpub.Delivery) error {
+			attempts <- d.Attempt
+			return nil
+		}, subpub.WithManualAck())
+		require.NoError(t, err)
+
+		// TTL истекает раньше AckTimeout
+		_, err = sp.PublishMsg(&subpub.Message{Subject: "test", Data: "data", TTL: 20 * time.Millisecond})
+		require.NoError(t, err)
+		assert.Equal(t, 1, <-attempts)
+
+		select {
+		case n := <-attempts:
+			t.Fatalf("unexpected redelivery, attempt %d", n)
+		case <-time.After(300 * time.Millisecond):
+		}
+
+		stats := sp.Stats()
+		require.Len(t, stats.Subjects, 1)
+		require.Len(t, stats.Subjects[0].Subscriptions, 1)
+		assert.Equal(t, uint64(1), stats.Subjects[0].Subscriptions[0].Dropped)
+	})
+
+	t.Run("Tiny ack timeout", func(t *testing.T) {
+		sp := newAckSubPub()
+		defer sp.Close(context.Background())
+
+		_, err := sp.SubscribeAck("test", func(d *subpub.Delivery) error { return nil },
+			subpub.WithAckTimeout(time.Nanosecond))
+		require.NoError(t, err)
+	})
+}
@@ -0,0 +1,184 @@
+package subpub_test
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"VK_task/pkg/subpub"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubPubAdmin(t *testing.T) {
+	t.Run("Stats", func(t *testing.T) {
+		cfg := subpub.NewConfig(8, 1)
+		sp := subpub.NewSubPub(cfg, slog.Default())
+		defer sp.Close(context.Background())
+
+		block := make(chan struct{})
+		defer close(block)
+
+		slow, err := sp.Subscribe("orders.*", func(msg interface{}) { <-block }, subpub.WithPeer("10.0.0.1:5000"))
+		require.NoError(t, err)
+
+		_, err = sp.QueueSubscribe("orders.*", "workers", func(msg interface{}) {})
+		require.NoError(t, err)
+
+		_, err = sp.Subscribe("payments", func(msg interface{}) {})
+		require.NoError(t, err)
+
+		// Первое - в обработчике, второе - в очереди, третье отброшено
+		for i := 0; i < 3; i++ {
+			_, err := sp.PublishCount("orders.1", "data")
+			require.NoError(t, err)
+			time.Sleep(20 * time.Millisecond)
+		}
+
+		stats := sp.Stats()
+		require.Len(t, stats.Subjects, 2)
+
+		orders := stats.Subjects[0]
+		assert.Equal(t, "orders.*", orders.Subject)
+		assert.Equal(t, 2, orders.Subscribers)
+		assert.Equal(t, 1, orders.Groups)
+		assert.Equal(t, 8, orders.QueueCapacity)
+		require.Len(t, orders.Subscriptions, 2)
+
+		st := orders.Subscriptions[0]
+		assert.Equal(t, slow.ID(), st.ID)
+		assert.Equal(t, "10.0.0.1:5000", st.Peer)
+		assert.Equal(t, uint64(1), st.Delivered)
+		assert.Equal(t, 1, st.QueueDepth)
+		assert.Equal(t, uint64(1), st.Dropped)
+		assert.Equal(t, st, slow.Stats())
+
+		assert.Equal(t, "workers", orders.Subscriptions[1].Group)
+		assert.Equal(t, "payments", stats.Subjects[1].Subject)
+	})
+
+	t.Run("Close subscription", func(t *testing.T) {
+		sp := subpub.NewSubPub(subpub.DefaultConfig(), slog.Default())
+		defer sp.Close(context.Background())
+
+		sub, err := sp.Subscribe("test", func(msg interface{}) {})
+		require.NoError(t, err)
+
+		assert.ErrorIs(t, sp.CloseSubscription("unknown"), subpub.ErrNoSuchSubscription)
+
+		require.NoError(t, sp.CloseSubscription(sub.ID()))
+
+		select {
+		case <-sub.Done():
+		case <-time.After(time.Second):
+			t.Fatal("subscription not closed")
+		}
+		assert.ErrorIs(t, sub.Err(), subpub.ErrSubscriptionClosed)
+		assert.Empty(t, sp.Stats().Subjects)
+	})
+
+	t.Run("Drain subject", func(t *testing.T) {
+		sp := subpub.NewSubPub(subpub.DefaultConfig(), slog.Default())
+		defer sp.Close(context.Background())
+
+		received := make(chan interface{}, 16)
+		sub, err := sp.Subscribe("test", func(msg interface{}) {
+			time.Sleep(10 * time.Millisecond)
+			received <- msg
+		})
+		require.NoError(t, err)
+
+		for i := 0; i < 5; i++ {
+			require.NoError(t, sp.Publish("test", i))
+		}
+
+		n, err := sp.DrainSubject(context.Background(), "test")
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+
+		<-sub.Done()
+		assert.ErrorIs(t, sub.Err(), subpub.ErrSubjectDrained)
+
+		// Принятые до drain сообщения доставлены
+		require.Eventually(t, func() bool { return len(received) == 5 }, time.Second, 10*time.Millisecond)
+
+		assert.ErrorIs(t, sp.Publish("test", "after"), subpub.ErrNoSuchSubject)
+
+		_, err = sp.DrainSubject(context.Background(), "test")
+		assert.ErrorIs(t, err, subpub.ErrNoSuchSubject)
+
+		// Новый subject на том же шаблоне
+		again, err := sp.Subscribe("test", func(msg interface{}) {})
+		require.NoError(t, err)
+		require.NoError(t, sp.Publish("test", "new"))
+		assert.Nil(t, again.Err())
+	})
+
+	t.Run("Drain timeout", func(t *testing.T) {
+		sp := subpub.NewSubPub(subpub.DefaultConfig(), slog.Default())
+		defer sp.Close(context.Background())
+
+		block := make(chan struct{})
+		defer close(block)
+
+		sub, err := sp.Subscribe("test", func(msg interface{}) { <-block })
+		require.NoError(t, err)
+
+		for i := 0; i < 3; i++ {
+			require.NoError(t, sp.Publish("test", i))
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		n, err := sp.DrainSubject(ctx, "test")
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Equal(t, 1, n)
+
+		<-sub.Done()
+		assert.ErrorIs(t, sub.Err(), subpub.ErrSubjectDrained)
+	})
+
+	t.Run("Drain and close concurrently", func(t *testing.T) {
+		sp := subpub.NewSubPub(subpub.DefaultConfig(), slog.Default())
+
+		block := make(chan struct{})
+
+		sub, err := sp.Subscribe("test", func(msg interface{}) { <-block })
+		require.NoError(t, err)
+
+		for i := 0; i < 3; i++ {
+			require.NoError(t, sp.Publish("test", i))
+		}
+
+		drained := make(chan error, 1)
+		go func() {
+			_, err := sp.DrainSubject(context.Background(), "test")
+			drained <- err
+		}()
+
+		// Drain ждёт очередь, занятую обработчиком
+		time.Sleep(20 * time.Millisecond)
+
+		closed := make(chan error, 1)
+		go func() {
+			closed <- sp.Close(context.Background())
+		}()
+
+		time.Sleep(20 * time.Millisecond)
+		close(block)
+
+		select {
+		case <-sub.Done():
+		case <-time.After(time.Second):
+			t.Fatal("subscription Done is not closed")
+		}
+		assert.ErrorIs(t, sub.Err(), subpub.ErrSubPubClosed)
+
+		assert.ErrorIs(t, <-drained, subpub.ErrSubPubClosed)
+		assert.NoError(t, <-closed)
+	})
+
+}
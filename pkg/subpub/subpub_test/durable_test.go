@@ -0,0 +1,252 @@
+package subpub_test
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"VK_task/pkg/subpub"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func openDurable(t *testing.T, dir string, segmentSize int64) subpub.SubPub {
+	cfg := subpub.DefaultConfig()
+	cfg.Log = subpub.LogConfig{Dir: dir, SegmentSize: segmentSize}
+
+	sp, err := subpub.Open(cfg, slog.Default())
+	require.NoError(t, err)
+
+	return sp
+}
+
+func collect(t *testing.T, sp subpub.SubPub, subject string, opts ...subpub.SubscribeOption) <-chan *subpub.Message {
+	received := make(chan *subpub.Message, 64)
+
+	opts = append(opts, subpub.WithEnvelope())
+	_, err := sp.Subscribe(subject, func(msg interface{}) {
+		received <- msg.(*subpub.Message)
+	}, opts...)
+	require.NoError(t, err)
+
+	return received
+}
+
+func next(t *testing.T, ch <-chan *subpub.Message) *subpub.Message {
+	select {
+	case m := <-ch:
+		return m
+	case <-time.After(time.Second):
+		t.Fatal("message not received")
+		return nil
+	}
+}
+
+func TestSubPubDurableLog(t *testing.T) {
+	t.Run("Replay from earliest", func(t *testing.T) {
+		sp := openDurable(t, t.TempDir(), 0)
+		defer sp.Close(context.Background())
+
+		// No subscribers yet, messages are kept in the log
+		require.NoError(t, sp.Publish("orders.1", "first"))
+		require.NoError(t, sp.Publish("orders.1", "second"))
+
+		received := collect(t, sp, "orders.1", subpub.StartAtEarliest())
+
+		m := next(t, received)
+		assert.Equal(t, "first", m.Data)
+		assert.Equal(t, uint64(1), m.Offset)
+		assert.Equal(t, "orders.1", m.Subject)
+
+		m = next(t, received)
+		assert.Equal(t, "second", m.Data)
+		assert.Equal(t, uint64(2), m.Offset)
+
+		// Live messages continue after the replayed ones
+		require.NoError(t, sp.Publish("orders.1", "third"))
+
+		m = next(t, received)
+		assert.Equal(t, "third", m.Data)
+		assert.Equal(t, uint64(3), m.Offset)
+	})
+
+	t.Run("Replay from offset and time", func(t *testing.T) {
+		sp := openDurable(t, t.TempDir(), 0)
+		defer sp.Close(context.Background())
+
+		require.NoError(t, sp.Publish("test", "first"))
+		time.Sleep(10 * time.Millisecond)
+		since := time.Now()
+		require.NoError(t, sp.Publish("test", "second"))
+		require.NoError(t, sp.Publish("test", "third"))
+
+		byOffset := collect(t, sp, "test", subpub.StartAtOffset(3))
+		assert.Equal(t, "third", next(t, byOffset).Data)
+
+		byTime := collect(t, sp, "test", subpub.StartAtTime(since))
+		assert.Equal(t, "second", next(t, byTime).Data)
+		assert.Equal(t, "third", next(t, byTime).Data)
+	})
+
+	t.Run("Replay wildcard subject", func(t *testing.T) {
+		sp := openDurable(t, t.TempDir(), 0)
+		defer sp.Close(context.Background())
+
+		require.NoError(t, sp.Publish("orders.1", "first"))
+		require.NoError(t, sp.Publish("orders.2", "second"))
+		require.NoError(t, sp.Publish("users.1", "skip"))
+
+		received := collect(t, sp, "orders.*", subpub.StartAtEarliest())
+		assert.Equal(t, "first", next(t, received).Data)
+		assert.Equal(t, "second", next(t, received).Data)
+	})
+
+	t.Run("Recovery after restart", func(t *testing.T) {
+		dir := t.TempDir()
+
+		// Small segments to roll over several files
+		sp := openDurable(t, dir, 64)
+		for _, data := range []string{"first", "second", "third", "fourth"} {
+			require.NoError(t, sp.Publish("test", data))
+		}
+		require.NoError(t, sp.Close(context.Background()))
+
+		segments, err := filepath.Glob(filepath.Join(dir, "test", "*.log"))
+		require.NoError(t, err)
+		assert.Greater(t, len(segments), 1)
+
+		// Torn write at the tail of the last segment
+		f, err := os.OpenFile(segments[len(segments)-1], os.O_APPEND|os.O_WRONLY, 0o644)
+		require.NoError(t, err)
+		_, err = f.Write([]byte{0, 0, 0, 1, 2})
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+
+		sp = openDurable(t, dir, 64)
+		defer sp.Close(context.Background())
+
+		require.NoError(t, sp.Publish("test", "fifth"))
+
+		received := collect(t, sp, "test", subpub.StartAtEarliest())
+		for i, data := range []string{"first", "second", "third", "fourth", "fifth"} {
+			m := next(t, received)
+			assert.Equal(t, data, m.Data)
+			assert.Equal(t, uint64(i+1), m.Offset)
+		}
+	})
+
+	t.Run("Headers and ID after restart", func(t *testing.T) {
+		dir := t.TempDir()
+
+		sp := openDurable(t, dir, 0)
+		msg := &subpub.Message{
+			Subject: "test",
+			Headers: map[string]string{subpub.HeaderContentType: "application/octet-stream", "trace": "abc"},
+			Data:    []byte{0, 1, 2},
+		}
+		_, err := sp.PublishMsg(msg)
+		require.NoError(t, err)
+		require.NoError(t, sp.Close(context.Background()))
+
+		sp = openDurable(t, dir, 0)
+		defer sp.Close(context.Background())
+
+		m := next(t, collect(t, sp, "test", subpub.StartAtEarliest()))
+		assert.Equal(t, msg.ID, m.ID)
+		assert.Equal(t, msg.Headers, m.Headers)
+		assert.Equal(t, []byte{0, 1, 2}, m.Data)
+		assert.Equal(t, msg.Offset, m.Offset)
+	})
+
+	t.Run("Unsupported type is delivered without log", func(t *testing.T) {
+		sp := openDurable(t, t.TempDir(), 0)
+		defer sp.Close(context.Background())
+
+		received := collect(t, sp, "test")
+
+		n, err := sp.PublishCount("test", 42)
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+
+		m := next(t, received)
+		assert.Equal(t, 42, m.Data)
+		assert.Zero(t, m.Offset)
+	})
+
+	t.Run("Expired messages are not replayed", func(t *testing.T) {
+		dir := t.TempDir()
+
+		sp := openDurable(t, dir, 0)
+		_, err := sp.PublishMsg(&subpub.Message{Subject: "test", Data: "short", TTL: 50 * time.Millisecond})
+		require.NoError(t, err)
+		_, err = sp.PublishMsg(&subpub.Message{Subject: "test", Data: "long", TTL: time.Hour})
+		require.NoError(t, err)
+		require.NoError(t, sp.Close(context.Background()))
+
+		time.Sleep(100 * time.Millisecond)
+
+		sp = openDurable(t, dir, 0)
+		defer sp.Close(context.Background())
+
+		received := collect(t, sp, "test", subpub.StartAtEarliest())
+
+		m := next(t, received)
+		assert.Equal(t, "long", m.Data)
+		assert.Equal(t, uint64(2), m.Offset)
+	})
+
+	t.Run("Open segments are bounded", func(t *testing.T) {
+		dir := t.TempDir()
+
+		cfg := subpub.DefaultConfig()
+		cfg.Log = subpub.LogConfig{Dir: dir, MaxOpenFiles: 4}
+
+		sp, err := subpub.Open(cfg, slog.Default())
+		require.NoError(t, err)
+		defer sp.Close(context.Background())
+
+		for i := range 32 {
+			require.NoError(t, sp.Publish(fmt.Sprintf("test.%d", i), "data"))
+		}
+		assert.LessOrEqual(t, openFilesIn(t, dir), 4)
+
+		// Закрытый сегмент открывается снова при следующей записи
+		require.NoError(t, sp.Publish("test.0", "again"))
+
+		m := next(t, collect(t, sp, "test.0", subpub.StartAtEarliest()))
+		assert.Equal(t, "data", m.Data)
+		assert.Equal(t, "again", next(t, collect(t, sp, "test.0", subpub.StartAtOffset(2))).Data)
+	})
+
+	t.Run("Start position without log", func(t *testing.T) {
+		sp := subpub.NewSubPub(subpub.DefaultConfig(), slog.Default())
+		defer sp.Close(context.Background())
+
+		_, err := sp.Subscribe("test", func(msg interface{}) {}, subpub.StartAtEarliest())
+		assert.Equal(t, subpub.ErrLogDisabled, err)
+	})
+}
+
+// openFilesIn - открытые процессом файлы внутри dir
+func openFilesIn(t *testing.T, dir string) int {
+	fds, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		t.Skip("no /proc/self/fd")
+	}
+
+	var n int
+	for _, fd := range fds {
+		target, err := os.Readlink(filepath.Join("/proc/self/fd", fd.Name()))
+		if err == nil && strings.HasPrefix(target, dir) {
+			n++
+		}
+	}
+
+	return n
+}
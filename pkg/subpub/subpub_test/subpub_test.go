@@ -3,7 +3,9 @@ package subpub_test
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log/slog"
+	"os"
 	"sync"
 	"testing"
 	"time"
@@ -12,6 +14,10 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func TestSubPub(t *testing.T) {
@@ -113,7 +119,12 @@ func TestSubPub(t *testing.T) {
 	})
 
 	t.Run("Concurrent operations", func(t *testing.T) {
-		sp := subpub.NewSubPub(subpub.NewConfig(32, 100), slog.Default())
+		// Публикация может опередить все подписки: без подписчиков
+		// сообщение отбрасывается, а не возвращает ErrNoSuchSubject
+		cfg := subpub.NewConfig(32, 100)
+		cfg.NoSubscribers = subpub.NoSubscribersDrop
+
+		sp := subpub.NewSubPub(cfg, slog.Default())
 		var wg sync.WaitGroup
 
 		// Start 10 subscribers
@@ -185,3 +196,1102 @@ func TestSubPub(t *testing.T) {
 		assert.Equal(t, subpub.ErrSubPubClosed, err)
 	})
 }
+
+func TestSubPubWildcards(t *testing.T) {
+	t.Run("Single token wildcard", func(t *testing.T) {
+		sp := subpub.NewSubPub(subpub.DefaultConfig(), slog.Default())
+		defer sp.Close(context.Background())
+
+		received := make(chan interface{}, 4)
+		_, err := sp.Subscribe("orders.*.created", func(msg interface{}) {
+			received <- msg
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, sp.Publish("orders.1.created", "first"))
+		require.NoError(t, sp.Publish("orders.2.created", "second"))
+		assert.Equal(t, subpub.ErrNoSuchSubject, sp.Publish("orders.1.deleted", "skip"))
+		assert.Equal(t, subpub.ErrNoSuchSubject, sp.Publish("orders.1.2.created", "skip"))
+
+		assert.Equal(t, "first", <-received)
+		assert.Equal(t, "second", <-received)
+	})
+
+	t.Run("Tail wildcard", func(t *testing.T) {
+		sp := subpub.NewSubPub(subpub.DefaultConfig(), slog.Default())
+		defer sp.Close(context.Background())
+
+		received := make(chan interface{}, 4)
+		_, err := sp.Subscribe("orders.>", func(msg interface{}) {
+			received <- msg
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, sp.Publish("orders.1", "first"))
+		require.NoError(t, sp.Publish("orders.1.created", "second"))
+		assert.Equal(t, subpub.ErrNoSuchSubject, sp.Publish("orders", "skip"))
+
+		assert.Equal(t, "first", <-received)
+		assert.Equal(t, "second", <-received)
+	})
+
+	t.Run("Literal and pattern subscribers", func(t *testing.T) {
+		sp := subpub.NewSubPub(subpub.DefaultConfig(), slog.Default())
+		defer sp.Close(context.Background())
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+
+		for _, subject := range []string{"orders.1.created", "orders.*.created"} {
+			_, err := sp.Subscribe(subject, func(msg interface{}) {
+				defer wg.Done()
+				assert.Equal(t, "hello", msg)
+			})
+			require.NoError(t, err)
+		}
+
+		require.NoError(t, sp.Publish("orders.1.created", "hello"))
+
+		wg.Wait()
+	})
+
+	t.Run("Unsubscribe pattern", func(t *testing.T) {
+		sp := subpub.NewSubPub(subpub.DefaultConfig(), slog.Default())
+		defer sp.Close(context.Background())
+
+		sub, err := sp.Subscribe("orders.*", func(msg interface{}) {})
+		require.NoError(t, err)
+
+		sub.Unsubscribe()
+		assert.Equal(t, subpub.ErrNoSuchSubject, sp.Publish("orders.1", "data"))
+	})
+
+	t.Run("Invalid subjects", func(t *testing.T) {
+		sp := subpub.NewSubPub(subpub.DefaultConfig(), slog.Default())
+		defer sp.Close(context.Background())
+
+		for _, subject := range []string{"orders.", ".orders", "orders..1", "orders.>.created", "orders.a*"} {
+			_, err := sp.Subscribe(subject, func(msg interface{}) {})
+			assert.Equal(t, subpub.ErrInvalidSubject, err, subject)
+		}
+
+		for _, subject := range []string{"orders.*", "orders.>", "orders..1"} {
+			assert.Equal(t, subpub.ErrInvalidSubject, sp.Publish(subject, "data"), subject)
+		}
+	})
+}
+
+func TestSubPubNoSubscribers(t *testing.T) {
+	t.Run("Drop policy", func(t *testing.T) {
+		cfg := subpub.DefaultConfig()
+		cfg.NoSubscribers = subpub.NoSubscribersDrop
+
+		sp := subpub.NewSubPub(cfg, slog.Default())
+		defer sp.Close(context.Background())
+
+		n, err := sp.PublishCount("test", "data")
+		assert.NoError(t, err)
+		assert.Equal(t, 0, n)
+	})
+
+	t.Run("Retain policy", func(t *testing.T) {
+		cfg := subpub.DefaultConfig()
+		cfg.NoSubscribers = subpub.NoSubscribersRetain
+		cfg.PendingBuffer = 2
+
+		sp := subpub.NewSubPub(cfg, slog.Default())
+		defer sp.Close(context.Background())
+
+		// The oldest message is evicted from the bounded buffer
+		for _, data := range []string{"first", "second", "third"} {
+			n, err := sp.PublishCount("orders.1", data)
+			require.NoError(t, err)
+			assert.Equal(t, 0, n)
+		}
+
+		received := make(chan interface{}, 4)
+		_, err := sp.Subscribe("orders.*", func(msg interface{}) {
+			received <- msg
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, "second", <-received)
+		assert.Equal(t, "third", <-received)
+
+		// Pending messages are handed out only once
+		late := make(chan interface{}, 4)
+		_, err = sp.Subscribe("orders.1", func(msg interface{}) {
+			late <- msg
+		})
+		require.NoError(t, err)
+
+		n, err := sp.PublishCount("orders.1", "live")
+		require.NoError(t, err)
+		assert.Equal(t, 2, n)
+
+		assert.Equal(t, "live", <-received)
+		assert.Equal(t, "live", <-late)
+	})
+
+	t.Run("Publish count", func(t *testing.T) {
+		sp := subpub.NewSubPub(subpub.DefaultConfig(), slog.Default())
+		defer sp.Close(context.Background())
+
+		for i := 0; i < 3; i++ {
+			_, err := sp.Subscribe("test", func(msg interface{}) {})
+			require.NoError(t, err)
+		}
+
+		n, err := sp.PublishCount("test", "data")
+		assert.NoError(t, err)
+		assert.Equal(t, 3, n)
+
+		_, err = sp.PublishCount("nonexistent", "data")
+		assert.Equal(t, subpub.ErrNoSuchSubject, err)
+	})
+}
+
+func TestSubPubOverflow(t *testing.T) {
+	// blockedHandler holds the first message until release is closed
+	blockedHandler := func(received chan<- interface{}, release <-chan struct{}) subpub.MessageHandler {
+		var once sync.Once
+		return func(msg interface{}) {
+			received <- msg
+			once.Do(func() { <-release })
+		}
+	}
+
+	t.Run("Drop oldest", func(t *testing.T) {
+		sp := subpub.NewSubPub(subpub.NewConfig(16, 1), slog.Default())
+		defer sp.Close(context.Background())
+
+		received := make(chan interface{}, 8)
+		release := make(chan struct{})
+
+		_, err := sp.Subscribe("test", blockedHandler(received, release),
+			subpub.WithOverflow(subpub.OverflowDropOldest))
+		require.NoError(t, err)
+
+		require.NoError(t, sp.Publish("test", 0))
+		assert.Equal(t, 0, <-received)
+
+		for i := 1; i <= 3; i++ {
+			require.NoError(t, sp.Publish("test", i))
+		}
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+
+		assert.Equal(t, 3, <-received)
+	})
+
+	t.Run("Block with timeout", func(t *testing.T) {
+		sp := subpub.NewSubPub(subpub.NewConfig(16, 1), slog.Default())
+		defer sp.Close(context.Background())
+
+		received := make(chan interface{}, 8)
+		release := make(chan struct{})
+
+		_, err := sp.Subscribe("test", blockedHandler(received, release),
+			subpub.WithOverflow(subpub.OverflowBlock),
+			subpub.WithBlockTimeout(time.Second))
+		require.NoError(t, err)
+
+		require.NoError(t, sp.Publish("test", 0))
+		assert.Equal(t, 0, <-received)
+
+		for i := 1; i <= 3; i++ {
+			require.NoError(t, sp.Publish("test", i))
+		}
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+
+		// Nothing is lost while the consumer catches up within the timeout
+		for i := 1; i <= 3; i++ {
+			assert.Equal(t, i, <-received)
+		}
+	})
+
+	t.Run("Disconnect slow consumer", func(t *testing.T) {
+		cfg := subpub.NewConfig(16, 1)
+		cfg.Overflow = subpub.OverflowDisconnect
+
+		sp := subpub.NewSubPub(cfg, slog.Default())
+		defer sp.Close(context.Background())
+
+		received := make(chan interface{}, 8)
+		release := make(chan struct{})
+		defer close(release)
+
+		sub, err := sp.Subscribe("test", blockedHandler(received, release))
+		require.NoError(t, err)
+
+		require.NoError(t, sp.Publish("test", 0))
+		<-received
+
+		for i := 1; i <= 3; i++ {
+			require.NoError(t, sp.Publish("test", i))
+		}
+
+		select {
+		case <-sub.Done():
+			assert.Equal(t, subpub.ErrSlowConsumer, sub.Err())
+		case <-time.After(time.Second):
+			t.Fatal("slow consumer was not disconnected")
+		}
+	})
+
+	t.Run("Unsubscribe closes done", func(t *testing.T) {
+		sp := subpub.NewSubPub(subpub.DefaultConfig(), slog.Default())
+		defer sp.Close(context.Background())
+
+		sub, err := sp.Subscribe("test", func(msg interface{}) {})
+		require.NoError(t, err)
+		assert.NoError(t, sub.Err())
+
+		sub.Unsubscribe()
+
+		<-sub.Done()
+		assert.NoError(t, sub.Err())
+	})
+}
+
+func TestSubPubContext(t *testing.T) {
+	t.Run("Publish deadline on full subject queue", func(t *testing.T) {
+		sp := subpub.NewSubPub(subpub.NewConfig(1, 1), slog.Default())
+		defer sp.Close(context.Background())
+
+		started := make(chan struct{})
+		release := make(chan struct{})
+		defer close(release)
+
+		var once sync.Once
+		_, err := sp.Subscribe("test", func(msg interface{}) {
+			once.Do(func() { close(started) })
+			<-release
+		}, subpub.WithOverflow(subpub.OverflowBlock), subpub.WithBlockTimeout(time.Minute))
+		require.NoError(t, err)
+
+		require.NoError(t, sp.Publish("test", 0))
+		<-started
+
+		// Handler, subscription queue, dispatcher and subject queue hold one message each
+		var publishErr error
+		for i := 1; i <= 8 && publishErr == nil; i++ {
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			start := time.Now()
+			_, publishErr = sp.PublishContext(ctx, "test", i)
+			cancel()
+
+			if publishErr != nil {
+				assert.Less(t, time.Since(start), time.Second)
+			}
+		}
+		assert.ErrorIs(t, publishErr, context.DeadlineExceeded)
+	})
+
+	t.Run("Publish with done context", func(t *testing.T) {
+		sp := subpub.NewSubPub(subpub.DefaultConfig(), slog.Default())
+		defer sp.Close(context.Background())
+
+		received := make(chan interface{}, 1)
+		_, err := sp.Subscribe("test", func(msg interface{}) { received <- msg })
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err = sp.PublishContext(ctx, "test", "data")
+		assert.ErrorIs(t, err, context.Canceled)
+
+		_, err = sp.PublishMsgContext(ctx, &subpub.Message{Subject: "test", Data: "data"})
+		assert.ErrorIs(t, err, context.Canceled)
+
+		select {
+		case msg := <-received:
+			t.Fatalf("unexpected message %v", msg)
+		case <-time.After(50 * time.Millisecond):
+		}
+	})
+
+	t.Run("Subscription ends with context", func(t *testing.T) {
+		sp := subpub.NewSubPub(subpub.DefaultConfig(), slog.Default())
+		defer sp.Close(context.Background())
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		received := make(chan interface{}, 1)
+		sub, err := sp.SubscribeContext(ctx, "test", func(msg interface{}) { received <- msg })
+		require.NoError(t, err)
+
+		require.NoError(t, sp.Publish("test", "data"))
+		assert.Equal(t, "data", <-received)
+
+		cancel()
+
+		select {
+		case <-sub.Done():
+			assert.ErrorIs(t, sub.Err(), context.Canceled)
+		case <-time.After(time.Second):
+			t.Fatal("subscription was not closed")
+		}
+
+		assert.ErrorIs(t, sp.Publish("test", "data"), subpub.ErrNoSuchSubject)
+	})
+
+	t.Run("Subscription deadline", func(t *testing.T) {
+		sp := subpub.NewSubPub(subpub.DefaultConfig(), slog.Default())
+		defer sp.Close(context.Background())
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		sub, err := sp.SubscribeContext(ctx, "test", func(msg interface{}) {})
+		require.NoError(t, err)
+
+		<-sub.Done()
+		assert.ErrorIs(t, sub.Err(), context.DeadlineExceeded)
+
+		_, err = sp.SubscribeContext(ctx, "test", func(msg interface{}) {})
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
+
+func TestSubPubTTL(t *testing.T) {
+	// Первое сообщение держит обработчик, остальные ждут в очереди дольше TTL
+	setup := func(t *testing.T, cfg *subpub.Config) (subpub.SubPub, subpub.Subscription, <-chan interface{}, chan<- struct{}) {
+		sp := subpub.NewSubPub(cfg, slog.Default())
+		t.Cleanup(func() { sp.Close(context.Background()) })
+
+		received := make(chan interface{}, 8)
+		release := make(chan struct{})
+
+		var once sync.Once
+		sub, err := sp.Subscribe("orders.>", func(msg interface{}) {
+			received <- msg
+			once.Do(func() { <-release })
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, sp.Publish("orders.1", "first"))
+		assert.Equal(t, "first", <-received)
+
+		return sp, sub, received, release
+	}
+
+	t.Run("Publish TTL", func(t *testing.T) {
+		m := newCountingMetrics()
+		cfg := subpub.DefaultConfig()
+		cfg.Metrics = m
+
+		sp, sub, received, release := setup(t, cfg)
+
+		_, err := sp.PublishMsg(&subpub.Message{Subject: "orders.1", Data: "expired", TTL: 50 * time.Millisecond})
+		require.NoError(t, err)
+		_, err = sp.PublishMsg(&subpub.Message{Subject: "orders.1", Data: "alive", TTL: time.Minute})
+		require.NoError(t, err)
+		require.NoError(t, sp.Publish("orders.1", "no ttl"))
+
+		time.Sleep(100 * time.Millisecond)
+		close(release)
+
+		assert.Equal(t, "alive", <-received)
+		assert.Equal(t, "no ttl", <-received)
+		assert.Equal(t, uint64(1), sub.Stats().Dropped)
+
+		m.mu.Lock()
+		assert.Equal(t, 1, m.dropped[subpub.DropExpired])
+		m.mu.Unlock()
+	})
+
+	t.Run("Subject TTL", func(t *testing.T) {
+		cfg := subpub.DefaultConfig()
+		cfg.MessageTTL = time.Minute
+		cfg.SubjectTTL = map[string]time.Duration{
+			"orders.*":       time.Hour,
+			"orders.*.audit": 50 * time.Millisecond,
+		}
+
+		sp, sub, received, release := setup(t, cfg)
+
+		require.NoError(t, sp.Publish("orders.1.audit", "expired"))
+		_, err := sp.PublishMsg(&subpub.Message{Subject: "orders.2.audit", Data: "own ttl", TTL: time.Minute})
+		require.NoError(t, err)
+		require.NoError(t, sp.Publish("orders.1", "subject ttl"))
+		require.NoError(t, sp.Publish("orders.1.created", "message ttl"))
+
+		time.Sleep(100 * time.Millisecond)
+		close(release)
+
+		assert.Equal(t, "own ttl", <-received)
+		assert.Equal(t, "subject ttl", <-received)
+		assert.Equal(t, "message ttl", <-received)
+		assert.Equal(t, uint64(1), sub.Stats().Dropped)
+	})
+}
+
+func TestSubPubRetained(t *testing.T) {
+	retain := func(t *testing.T, sp subpub.SubPub, subject string, data interface{}) int {
+		n, err := sp.PublishMsg(&subpub.Message{Subject: subject, Data: data, Retain: true})
+		require.NoError(t, err)
+		return n
+	}
+
+	t.Run("New subscriber gets latest value", func(t *testing.T) {
+		sp := subpub.NewSubPub(subpub.DefaultConfig(), slog.Default())
+		defer sp.Close(context.Background())
+
+		// Без подписчиков retained публикация не ошибка
+		assert.Equal(t, 0, retain(t, sp, "status.a", "a1"))
+		retain(t, sp, "status.b", "b1")
+		retain(t, sp, "status.a", "a2")
+
+		received := make(chan *subpub.Message, 8)
+		_, err := sp.Subscribe("status.*", func(msg interface{}) {
+			received <- msg.(*subpub.Message)
+		}, subpub.WithEnvelope())
+		require.NoError(t, err)
+
+		for _, want := range []string{"b1", "a2"} {
+			m := <-received
+			assert.Equal(t, want, m.Data)
+			assert.True(t, m.Retain)
+		}
+
+		// Новая публикация - не retained значение для уже подписанных
+		assert.Equal(t, 1, retain(t, sp, "status.a", "a3"))
+		m := <-received
+		assert.Equal(t, "a3", m.Data)
+		assert.False(t, m.Retain)
+
+		select {
+		case m := <-received:
+			t.Fatalf("unexpected message %v", m.Data)
+		case <-time.After(50 * time.Millisecond):
+		}
+	})
+
+	t.Run("Concurrent publish and subscribe", func(t *testing.T) {
+		// Для go test -race: retained значение читается подписками, пока публикация его ставит в очереди
+		sp := subpub.NewSubPub(subpub.DefaultConfig(), slog.Default())
+		defer sp.Close(context.Background())
+
+		_, err := sp.Subscribe("status.>", func(msg interface{}) {})
+		require.NoError(t, err)
+
+		var wg sync.WaitGroup
+		for range 4 {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				for i := range 100 {
+					retain(t, sp, "status.a", fmt.Sprint(i))
+				}
+			}()
+			go func() {
+				defer wg.Done()
+				for range 100 {
+					sub, err := sp.Subscribe("status.*", func(msg interface{}) {}, subpub.WithEnvelope())
+					if assert.NoError(t, err) {
+						sub.Unsubscribe()
+					}
+				}
+			}()
+		}
+		wg.Wait()
+	})
+
+	t.Run("Empty publish clears value", func(t *testing.T) {
+		sp := subpub.NewSubPub(subpub.DefaultConfig(), slog.Default())
+		defer sp.Close(context.Background())
+
+		retain(t, sp, "status.a", "a1")
+		retain(t, sp, "status.b", "b1")
+		assert.Equal(t, 0, retain(t, sp, "status.a", ""))
+		assert.Equal(t, 0, retain(t, sp, "status.b", nil))
+
+		received := make(chan interface{}, 8)
+		_, err := sp.Subscribe("status.*", func(msg interface{}) { received <- msg })
+		require.NoError(t, err)
+
+		select {
+		case msg := <-received:
+			t.Fatalf("unexpected message %v", msg)
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		_, err = sp.PublishMsg(&subpub.Message{Subject: "status.a"})
+		assert.ErrorIs(t, err, subpub.ErrInvalidArgument)
+	})
+
+	t.Run("Queue group skips retained", func(t *testing.T) {
+		sp := subpub.NewSubPub(subpub.DefaultConfig(), slog.Default())
+		defer sp.Close(context.Background())
+
+		retain(t, sp, "status.a", "a1")
+
+		grouped := make(chan interface{}, 8)
+		_, err := sp.QueueSubscribe("status.a", "workers", func(msg interface{}) { grouped <- msg })
+		require.NoError(t, err)
+
+		plain := make(chan interface{}, 8)
+		_, err = sp.Subscribe("status.a", func(msg interface{}) { plain <- msg })
+		require.NoError(t, err)
+
+		assert.Equal(t, "a1", <-plain)
+
+		require.NoError(t, sp.Publish("status.a", "a2"))
+		assert.Equal(t, "a2", <-grouped)
+		assert.Equal(t, "a2", <-plain)
+	})
+}
+
+func TestSubPubQueueGroups(t *testing.T) {
+	t.Run("Each message goes to one member", func(t *testing.T) {
+		sp := subpub.NewSubPub(subpub.DefaultConfig(), slog.Default())
+		defer sp.Close(context.Background())
+
+		const messages = 100
+
+		var wg sync.WaitGroup
+		wg.Add(messages)
+
+		var mu sync.Mutex
+		perMember := make([]int, 3)
+		seen := make(map[interface{}]int)
+
+		for i := range perMember {
+			_, err := sp.QueueSubscribe("jobs", "workers", func(msg interface{}) {
+				mu.Lock()
+				perMember[i]++
+				seen[msg]++
+				mu.Unlock()
+				wg.Done()
+			})
+			require.NoError(t, err)
+		}
+
+		for i := 0; i < messages; i++ {
+			n, err := sp.PublishCount("jobs", i)
+			require.NoError(t, err)
+			assert.Equal(t, 1, n)
+		}
+
+		wg.Wait()
+		time.Sleep(50 * time.Millisecond)
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		assert.Len(t, seen, messages)
+		for msg, count := range seen {
+			assert.Equal(t, 1, count, "message %v delivered more than once", msg)
+		}
+		for i, count := range perMember {
+			assert.NotZero(t, count, "member %d received nothing", i)
+		}
+	})
+
+	t.Run("Groups and plain subscribers", func(t *testing.T) {
+		sp := subpub.NewSubPub(subpub.DefaultConfig(), slog.Default())
+		defer sp.Close(context.Background())
+
+		plain := make(chan interface{}, 1)
+		groupA := make(chan interface{}, 2)
+		groupB := make(chan interface{}, 1)
+
+		_, err := sp.Subscribe("jobs", func(msg interface{}) { plain <- msg })
+		require.NoError(t, err)
+
+		for i := 0; i < 2; i++ {
+			_, err = sp.QueueSubscribe("jobs", "a", func(msg interface{}) { groupA <- msg })
+			require.NoError(t, err)
+		}
+		_, err = sp.Subscribe("jobs", func(msg interface{}) { groupB <- msg }, subpub.WithQueueGroup("b"))
+		require.NoError(t, err)
+
+		n, err := sp.PublishCount("jobs", "data")
+		require.NoError(t, err)
+		assert.Equal(t, 3, n)
+
+		assert.Equal(t, "data", <-plain)
+		assert.Equal(t, "data", <-groupA)
+		assert.Equal(t, "data", <-groupB)
+
+		select {
+		case <-groupA:
+			t.Fatal("message delivered twice within group")
+		case <-time.After(50 * time.Millisecond):
+		}
+	})
+
+	t.Run("Unsubscribed member", func(t *testing.T) {
+		sp := subpub.NewSubPub(subpub.DefaultConfig(), slog.Default())
+		defer sp.Close(context.Background())
+
+		first, err := sp.QueueSubscribe("jobs", "workers", func(msg interface{}) {
+			t.Errorf("unsubscribed member received %v", msg)
+		})
+		require.NoError(t, err)
+
+		received := make(chan interface{}, 4)
+		_, err = sp.QueueSubscribe("jobs", "workers", func(msg interface{}) { received <- msg })
+		require.NoError(t, err)
+
+		first.Unsubscribe()
+
+		for i := 0; i < 3; i++ {
+			require.NoError(t, sp.Publish("jobs", i))
+			assert.Equal(t, i, <-received)
+		}
+	})
+
+	t.Run("Empty group", func(t *testing.T) {
+		sp := subpub.NewSubPub(subpub.DefaultConfig(), slog.Default())
+		defer sp.Close(context.Background())
+
+		_, err := sp.QueueSubscribe("jobs", "", func(msg interface{}) {})
+		assert.Equal(t, subpub.ErrInvalidArgument, err)
+	})
+}
+
+func TestSubPubPublishMsg(t *testing.T) {
+	t.Run("Headers and metadata", func(t *testing.T) {
+		sp := subpub.NewSubPub(subpub.DefaultConfig(), slog.Default())
+		defer sp.Close(context.Background())
+
+		received := make(chan *subpub.Message, 1)
+		_, err := sp.Subscribe("test", func(msg interface{}) {
+			received <- msg.(*subpub.Message)
+		}, subpub.WithEnvelope())
+		require.NoError(t, err)
+
+		msg := &subpub.Message{
+			Subject: "test",
+			Headers: map[string]string{subpub.HeaderContentType: "text/plain"},
+			Data:    []byte("hello"),
+		}
+
+		n, err := sp.PublishMsg(msg)
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+		assert.NotEmpty(t, msg.ID)
+		assert.False(t, msg.Timestamp.IsZero())
+
+		m := <-received
+		assert.Equal(t, msg.ID, m.ID)
+		assert.Equal(t, msg.Headers, m.Headers)
+		assert.Equal(t, []byte("hello"), m.Data)
+		assert.True(t, msg.Timestamp.Equal(m.Timestamp))
+	})
+
+	t.Run("Unique IDs", func(t *testing.T) {
+		sp := subpub.NewSubPub(subpub.DefaultConfig(), slog.Default())
+		defer sp.Close(context.Background())
+
+		received := make(chan *subpub.Message, 2)
+		_, err := sp.Subscribe("test", func(msg interface{}) {
+			received <- msg.(*subpub.Message)
+		}, subpub.WithEnvelope())
+		require.NoError(t, err)
+
+		require.NoError(t, sp.Publish("test", "first"))
+		require.NoError(t, sp.Publish("test", "second"))
+
+		assert.NotEqual(t, (<-received).ID, (<-received).ID)
+	})
+
+	t.Run("Invalid message", func(t *testing.T) {
+		sp := subpub.NewSubPub(subpub.DefaultConfig(), slog.Default())
+		defer sp.Close(context.Background())
+
+		_, err := sp.PublishMsg(nil)
+		assert.Equal(t, subpub.ErrInvalidArgument, err)
+
+		_, err = sp.PublishMsg(&subpub.Message{Subject: "test"})
+		assert.Equal(t, subpub.ErrInvalidArgument, err)
+	})
+}
+
+func TestSubPubPublishBatch(t *testing.T) {
+	t.Run("Per-message results", func(t *testing.T) {
+		sp := subpub.NewSubPub(subpub.DefaultConfig(), slog.Default())
+		defer sp.Close(context.Background())
+
+		received := make(chan interface{}, 4)
+		_, err := sp.Subscribe("orders.*", func(msg interface{}) {
+			received <- msg
+		})
+		require.NoError(t, err)
+
+		msgs := []*subpub.Message{
+			{Subject: "orders.1", Data: "first"},
+			{Subject: "users.1", Data: "skip"},
+			{Subject: "orders.*", Data: "skip"},
+			nil,
+			{Subject: "orders.2", Data: "second"},
+		}
+
+		results := sp.PublishBatch(msgs)
+		require.Len(t, results, len(msgs))
+
+		assert.NoError(t, results[0].Err)
+		assert.Equal(t, 1, results[0].Delivered)
+		assert.NotEmpty(t, msgs[0].ID)
+		assert.Equal(t, subpub.ErrNoSuchSubject, results[1].Err)
+		assert.Equal(t, subpub.ErrInvalidSubject, results[2].Err)
+		assert.Equal(t, subpub.ErrInvalidArgument, results[3].Err)
+		assert.NoError(t, results[4].Err)
+
+		assert.Equal(t, "first", <-received)
+		assert.Equal(t, "second", <-received)
+	})
+
+	t.Run("Closed", func(t *testing.T) {
+		sp := subpub.NewSubPub(subpub.DefaultConfig(), slog.Default())
+		require.NoError(t, sp.Close(context.Background()))
+
+		results := sp.PublishBatch([]*subpub.Message{{Subject: "test", Data: "data"}})
+		assert.Equal(t, subpub.ErrSubPubClosed, results[0].Err)
+	})
+}
+
+// countingMetrics records subpub.Metrics calls
+type countingMetrics struct {
+	mu            sync.Mutex
+	published     map[string]int
+	delivered     map[string]int
+	dropped       map[string]int // by reason
+	panics        int
+	subscriptions int
+}
+
+func newCountingMetrics() *countingMetrics {
+	return &countingMetrics{
+		published: make(map[string]int),
+		delivered: make(map[string]int),
+		dropped:   make(map[string]int),
+	}
+}
+
+func (m *countingMetrics) Published(subject string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.published[subject]++
+}
+
+func (m *countingMetrics) Delivered(subject string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.delivered[subject]++
+}
+
+func (m *countingMetrics) Dropped(_, reason string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.dropped[reason]++
+}
+
+func (m *countingMetrics) HandlerPanic(string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.panics++
+}
+
+func (m *countingMetrics) Subscriptions(delta int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.subscriptions += delta
+}
+
+func (m *countingMetrics) HandlerDuration(time.Duration) {}
+func (m *countingMetrics) SubjectQueueFill(float64)      {}
+func (m *countingMetrics) SubscriptionQueueFill(float64) {}
+
+func TestSubPubMetrics(t *testing.T) {
+	m := newCountingMetrics()
+
+	cfg := subpub.NewConfig(16, 1)
+	cfg.NoSubscribers = subpub.NoSubscribersDrop
+	cfg.Metrics = m
+
+	sp := subpub.NewSubPub(cfg, slog.Default())
+
+	release := make(chan struct{})
+	handled := make(chan struct{}, 8)
+
+	sub, err := sp.Subscribe("orders.*", func(msg interface{}) {
+		handled <- struct{}{}
+		if msg == "panic" {
+			panic("test panic")
+		}
+		<-release
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, sp.Publish("orders.1", "first"))
+	<-handled
+
+	// Queue of 1 is full after the second message
+	require.NoError(t, sp.Publish("orders.1", "second"))
+	require.NoError(t, sp.Publish("orders.1", "third"))
+	time.Sleep(50 * time.Millisecond)
+
+	require.NoError(t, sp.Publish("users.1", "nobody"))
+
+	close(release)
+	<-handled
+
+	require.NoError(t, sp.Publish("orders.2", "panic"))
+	<-handled
+	time.Sleep(50 * time.Millisecond)
+
+	m.mu.Lock()
+	assert.Equal(t, 3, m.published["orders.1"])
+	assert.Equal(t, 1, m.published["users.1"])
+	assert.Equal(t, 2, m.delivered["orders.1"])
+	assert.Equal(t, 1, m.dropped[subpub.DropQueueFull])
+	assert.Equal(t, 1, m.dropped[subpub.DropNoSubscribers])
+	assert.Equal(t, 1, m.panics)
+	assert.Equal(t, 1, m.subscriptions)
+	m.mu.Unlock()
+
+	sub.Unsubscribe()
+	require.NoError(t, sp.Close(context.Background()))
+
+	m.mu.Lock()
+	assert.Equal(t, 0, m.subscriptions)
+	m.mu.Unlock()
+}
+
+func TestSubPubTracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	cfg := subpub.DefaultConfig()
+	cfg.TracerProvider = tp
+
+	sp := subpub.NewSubPub(cfg, slog.Default())
+	defer sp.Close(context.Background())
+
+	received := make(chan *subpub.Message, 1)
+	_, err := sp.Subscribe("orders.*", func(msg interface{}) {
+		received <- msg.(*subpub.Message)
+	}, subpub.WithEnvelope())
+	require.NoError(t, err)
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "publish")
+
+	headers := map[string]string{subpub.HeaderContentType: "text/plain"}
+	msg := &subpub.Message{
+		Subject: "orders.1",
+		Headers: subpub.WithTraceContext(ctx, headers),
+		Data:    "hello",
+	}
+	assert.NotContains(t, headers, subpub.HeaderTraceParent)
+
+	_, err = sp.PublishMsg(msg)
+	require.NoError(t, err)
+	parent.End()
+
+	m := <-received
+	assert.Equal(t, "text/plain", m.Headers[subpub.HeaderContentType])
+	require.Contains(t, m.Headers, subpub.HeaderTraceParent)
+
+	// Обработчик завершился после отправки в канал, span доставки - чуть позже
+	require.Eventually(t, func() bool {
+		return len(exporter.GetSpans()) == 3
+	}, time.Second, 10*time.Millisecond)
+
+	spans := make(map[string]tracetest.SpanStub)
+	for _, s := range exporter.GetSpans() {
+		spans[s.Name] = s
+	}
+
+	enqueue, deliver := spans["subpub enqueue"], spans["subpub deliver"]
+	traceID := parent.SpanContext().TraceID()
+
+	assert.Equal(t, traceID, enqueue.SpanContext.TraceID())
+	assert.Equal(t, parent.SpanContext().SpanID(), enqueue.Parent.SpanID())
+	assert.Equal(t, enqueue.SpanContext.SpanID(), deliver.Parent.SpanID())
+
+	// В заголовках доставленного сообщения - контекст span доставки
+	sc := trace.SpanContextFromContext(propagation.TraceContext{}.Extract(
+		context.Background(), propagation.MapCarrier(m.Headers)))
+	assert.Equal(t, traceID, sc.TraceID())
+	assert.Equal(t, deliver.SpanContext.SpanID(), sc.SpanID())
+}
+
+func TestCovers(t *testing.T) {
+	tests := []struct {
+		pattern, sub string
+		want         bool
+	}{
+		{"orders.1", "orders.1", true},
+		{"orders.*", "orders.1", true},
+		{"orders.*", "orders.*", true},
+		{"orders.*", "orders.>", false},
+		{"orders.*", "orders.1.created", false},
+		{"orders.>", "orders.*.created", true},
+		{"orders.>", "orders.>", true},
+		{"orders.>", "orders", false},
+		{"orders.1", "orders.*", false},
+		{">", "orders.>", true},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, subpub.Covers(tt.pattern, tt.sub), "%s covers %s", tt.pattern, tt.sub)
+	}
+}
+
+func TestSubPubRequest(t *testing.T) {
+	t.Run("Reply to requester", func(t *testing.T) {
+		sp := subpub.NewSubPub(subpub.DefaultConfig(), slog.Default())
+		defer sp.Close(context.Background())
+
+		_, err := sp.Subscribe("math.double", func(msg interface{}) {
+			m := msg.(*subpub.Message)
+			assert.NotEmpty(t, m.ReplyTo())
+			assert.Equal(t, "v", m.Headers["k"])
+			assert.NoError(t, subpub.Respond(sp, m, m.Data.(int)*2))
+		}, subpub.WithEnvelope())
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		headers := map[string]string{"k": "v"}
+		reply, err := sp.RequestMsg(ctx, &subpub.Message{Subject: "math.double", Headers: headers, Data: 21})
+		require.NoError(t, err)
+		assert.Equal(t, 42, reply.Data)
+
+		// Заголовки запроса не изменяются
+		assert.NotContains(t, headers, subpub.HeaderReplyTo)
+
+		// Временный subject удалён после ответа
+		for _, subj := range sp.Stats().Subjects {
+			assert.NotContains(t, subj.Subject, subpub.InboxPrefix)
+		}
+	})
+
+	t.Run("Inbox subjects are not kept in the log", func(t *testing.T) {
+		dir := t.TempDir()
+
+		cfg := subpub.DefaultConfig()
+		cfg.NoSubscribers = subpub.NoSubscribersRetain
+		cfg.Log = subpub.LogConfig{Dir: dir}
+
+		sp, err := subpub.Open(cfg, slog.Default())
+		require.NoError(t, err)
+		defer sp.Close(context.Background())
+
+		_, err = sp.Subscribe("math.double", func(msg interface{}) {
+			m := msg.(*subpub.Message)
+			assert.NoError(t, subpub.Respond(sp, m, m.Data.(string)+m.Data.(string)))
+		}, subpub.WithEnvelope())
+		require.NoError(t, err)
+
+		request := func() {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			reply, err := sp.Request(ctx, "math.double", "ab")
+			require.NoError(t, err)
+			assert.Equal(t, "abab", reply.Data)
+		}
+
+		request()
+		dirs, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		files := openFilesIn(t, dir)
+
+		for range 100 {
+			request()
+		}
+
+		after, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		assert.Len(t, after, len(dirs))
+		assert.Equal(t, files, openFilesIn(t, dir))
+
+		// Ответ после завершения Request не попадает в pending
+		err = subpub.Respond(sp, &subpub.Message{Headers: map[string]string{subpub.HeaderReplyTo: subpub.InboxPrefix + ".gone"}}, "late")
+		require.NoError(t, err)
+
+		late := make(chan interface{}, 1)
+		_, err = sp.Subscribe(subpub.InboxPrefix+".gone", func(msg interface{}) { late <- msg })
+		require.NoError(t, err)
+
+		select {
+		case msg := <-late:
+			t.Fatalf("unexpected late reply %v", msg)
+		case <-time.After(50 * time.Millisecond):
+		}
+	})
+
+	t.Run("Requests without responders are not kept", func(t *testing.T) {
+		cfg := subpub.DefaultConfig()
+		cfg.NoSubscribers = subpub.NoSubscribersRetain
+
+		sp := subpub.NewSubPub(cfg, slog.Default())
+		defer sp.Close(context.Background())
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		_, err := sp.RequestMsg(ctx, &subpub.Message{Subject: "nobody", Data: "req", Retain: true})
+		assert.ErrorIs(t, err, subpub.ErrNoResponders)
+
+		// Ни pending, ни retained: поздний подписчик запрос не получает
+		late := make(chan interface{}, 1)
+		_, err = sp.Subscribe("nobody", func(msg interface{}) { late <- msg })
+		require.NoError(t, err)
+
+		select {
+		case msg := <-late:
+			t.Fatalf("unexpected request %v", msg)
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		// И не попадает в durable log
+		durable := openDurable(t, t.TempDir(), 0)
+		defer durable.Close(context.Background())
+
+		_, err = durable.Request(ctx, "nobody", "req")
+		assert.ErrorIs(t, err, subpub.ErrNoResponders)
+		require.NoError(t, durable.Publish("nobody", "plain"))
+
+		m := next(t, collect(t, durable, "nobody", subpub.StartAtEarliest()))
+		assert.Equal(t, "plain", m.Data)
+		assert.Equal(t, uint64(1), m.Offset)
+	})
+
+	t.Run("No responders", func(t *testing.T) {
+		sp := subpub.NewSubPub(subpub.DefaultConfig(), slog.Default())
+		defer sp.Close(context.Background())
+
+		_, err := sp.Request(context.Background(), "math.double", 21)
+		assert.ErrorIs(t, err, subpub.ErrNoResponders)
+	})
+
+	t.Run("Deadline without reply", func(t *testing.T) {
+		sp := subpub.NewSubPub(subpub.DefaultConfig(), slog.Default())
+		defer sp.Close(context.Background())
+
+		_, err := sp.Subscribe("math.double", func(msg interface{}) {})
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		_, err = sp.Request(ctx, "math.double", 21)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("Respond without reply subject", func(t *testing.T) {
+		sp := subpub.NewSubPub(subpub.DefaultConfig(), slog.Default())
+		defer sp.Close(context.Background())
+
+		err := subpub.Respond(sp, &subpub.Message{Subject: "math.double", Data: 21}, 42)
+		assert.ErrorIs(t, err, subpub.ErrNoReplyTo)
+	})
+}
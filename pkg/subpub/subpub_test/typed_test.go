@@ -0,0 +1,109 @@
+package subpub_test
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	pb "VK_task/pkg/api/pubsub"
+	"VK_task/pkg/subpub"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+type order struct {
+	ID    int
+	Items []string
+}
+
+func TestSubPubTyped(t *testing.T) {
+	t.Run("Publish/Subscribe", func(t *testing.T) {
+		sp := subpub.NewSubPub(subpub.DefaultConfig(), slog.Default())
+		defer sp.Close(context.Background())
+
+		got := make(chan order, 1)
+		_, err := subpub.Subscribe(sp, "orders", func(o order) {
+			got <- o
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, subpub.Publish(sp, "orders", order{ID: 1, Items: []string{"a"}}))
+
+		assert.Equal(t, order{ID: 1, Items: []string{"a"}}, <-got)
+	})
+
+	t.Run("Type mismatch is dropped", func(t *testing.T) {
+		m := newCountingMetrics()
+
+		cfg := subpub.DefaultConfig()
+		cfg.Metrics = m
+
+		sp := subpub.NewSubPub(cfg, slog.Default())
+		defer sp.Close(context.Background())
+
+		got := make(chan int, 2)
+		sub, err := subpub.Subscribe(sp, "numbers", func(n int) {
+			got <- n
+		}, subpub.WithEnvelope())
+		require.NoError(t, err)
+
+		require.NoError(t, sp.Publish("numbers", "not a number"))
+		require.NoError(t, subpub.Publish(sp, "numbers", 42))
+
+		assert.Equal(t, 42, <-got)
+		assert.Equal(t, uint64(1), sub.Stats().Dropped)
+
+		m.mu.Lock()
+		assert.Equal(t, 1, m.dropped[subpub.DropDecode])
+		assert.Equal(t, 1, m.delivered["numbers"])
+		m.mu.Unlock()
+	})
+
+	t.Run("Codecs", func(t *testing.T) {
+		sp := subpub.NewSubPub(subpub.DefaultConfig(), slog.Default())
+		defer sp.Close(context.Background())
+
+		jsonTopic := subpub.NewTopic[order](sp, "json", subpub.JSONCodec[order]{})
+		gobTopic := subpub.NewTopic[order](sp, "gob", subpub.GobCodec[order]{})
+		protoTopic := subpub.NewTopic[*pb.Event](sp, "proto", subpub.ProtoCodec[*pb.Event]{})
+
+		orders := make(chan order, 4)
+		events := make(chan *pb.Event, 1)
+
+		_, err := jsonTopic.Subscribe(func(o order) { orders <- o })
+		require.NoError(t, err)
+		_, err = gobTopic.Subscribe(func(o order) { orders <- o })
+		require.NoError(t, err)
+		_, err = protoTopic.Subscribe(func(e *pb.Event) { events <- e })
+		require.NoError(t, err)
+
+		want := order{ID: 7, Items: []string{"x", "y"}}
+
+		require.NoError(t, jsonTopic.Publish(want))
+		assert.Equal(t, want, <-orders)
+
+		// Данные из gRPC приходят строкой
+		require.NoError(t, sp.Publish("json", `{"ID":8}`))
+		assert.Equal(t, order{ID: 8}, <-orders)
+
+		require.NoError(t, gobTopic.Publish(want))
+		assert.Equal(t, want, <-orders)
+
+		event := &pb.Event{Key: "proto", Data: "hello", Offset: 3}
+		n, err := protoTopic.PublishCount(event)
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+		assert.True(t, proto.Equal(event, <-events))
+
+		// Не JSON - отбрасывается, обработчик не вызывается
+		require.NoError(t, sp.Publish("json", []byte("garbage")))
+		select {
+		case o := <-orders:
+			t.Fatalf("unexpected message %+v", o)
+		case <-time.After(100 * time.Millisecond):
+		}
+	})
+}
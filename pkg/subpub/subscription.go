@@ -1,25 +1,57 @@
 package subpub
 
 import (
+	"VK_task/internal/pkg/logger/sl"
 	fast_id "VK_task/pkg/fast-id"
+	"context"
+	"fmt"
 	"github.com/google/uuid"
 	"log/slog"
 	"runtime/debug"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type subscription struct {
 	id      string
 	subject string
+	group   string // пусто - подписка получает все сообщения subject
 	cb      MessageHandler
-	queue   chan interface{}
+	queue   chan *message
+	done    chan struct{} // закрывается вместе с queue
+
+	subj      *subject // может быть уже отключён от шины DrainSubject
+	peer      string
+	createdAt time.Time
+	delivered atomic.Uint64 // попыток доставки в обработчик
+	dropped   atomic.Uint64
+
+	envelope bool
+	decode   decodeFunc  // типизированная подписка, nil - без преобразования
+	acks     *ackTracker // nil - подписка без подтверждений
+
+	policy       OverflowPolicy
+	blockTimeout time.Duration
+	kicked       atomic.Bool // отключена как медленный потребитель
+
+	err error // причина закрытия, читать после закрытия done
+
+	// Сообщения, опубликованные до появления подписчиков, и retained значения
+	pending []*message
+	// ID отданных retained значений по subject, пока из очереди не пришло следующее
+	retained map[string]string
+	// Воспроизведение durable log перед чтением очереди
+	cursors []*logCursor
+	// Смещения, которые уже пришли из лога, по subject
+	replayed map[string]uint64
 
 	sp *subPub
 
 	once sync.Once // For single Unsubscribe
 }
 
-func newSubscription(subject string, cb MessageHandler, sp *subPub) *subscription {
+func newSubscription(subject string, cb MessageHandler, sp *subPub, opts *subscribeOptions) *subscription {
 	var id string
 	UUID, err := uuid.NewRandom()
 	if err != nil {
@@ -28,89 +60,356 @@ func newSubscription(subject string, cb MessageHandler, sp *subPub) *subscriptio
 		id = UUID.String()
 	}
 
-	return &subscription{
-		id:      id,
-		subject: subject,
-		cb:      cb,
-		queue:   make(chan interface{}, sp.cfg.SubscriptionBuffer),
-		sp:      sp,
+	sub := &subscription{
+		id:           id,
+		subject:      subject,
+		group:        opts.group,
+		cb:           cb,
+		queue:        make(chan *message, sp.cfg.SubscriptionBuffer),
+		done:         make(chan struct{}),
+		peer:         opts.peer,
+		createdAt:    time.Now(),
+		envelope:     opts.envelope,
+		decode:       opts.decode,
+		policy:       sp.cfg.Overflow,
+		blockTimeout: sp.cfg.BlockTimeout,
+		sp:           sp,
+	}
+
+	if opts.overflow != nil {
+		sub.policy = *opts.overflow
+	}
+	if opts.blockTimeout > 0 {
+		sub.blockTimeout = opts.blockTimeout
 	}
+
+	return sub
+}
+
+func (sub *subscription) ID() string {
+	return sub.id
 }
 
 func (sub *subscription) Unsubscribe() {
+	sub.close(nil)
+}
+
+// Done закрывается, когда подписка завершена
+func (sub *subscription) Done() <-chan struct{} {
+	return sub.done
+}
+
+/*
+Err
+
+Причина завершения подписки: ErrSlowConsumer, ErrSubPubClosed,
+ErrSubscriptionClosed, ErrSubjectDrained, ошибка ctx для SubscribeContext
+или nil после Unsubscribe. До закрытия Done возвращает nil.
+*/
+func (sub *subscription) Err() error {
+	select {
+	case <-sub.done:
+		return sub.err
+	default:
+		return nil
+	}
+}
+
+func (sub *subscription) close(reason error) {
 	sub.once.Do(func() {
 		sp := sub.sp
 		sp.mu.RLock()
-
-		if sp.closed {
-			sp.mu.RUnlock()
-			return
-		}
-
-		subj, exists := sp.subjects[sub.subject]
+		closed := sp.closed
 		sp.mu.RUnlock()
 
-		if !exists {
-			return
-		}
-
-		if subj.unregisterSubscriber(sub.id) {
+		// После Close каналы тоже закрываются: иначе Done не закроется,
+		// если Close не застал подписку в subject
+		if sub.subj.unregisterSubscriber(sub.id) && !closed {
 
 			// Удаление subject если нет подписчиков
-			sp.removeSubject(sub.subject)
-			subj.close()
+			sp.removeSubject(sub.subject, sub.subj)
+			sub.subj.close()
+		}
+		if closed {
+			reason = ErrSubPubClosed
 		}
 
+		sub.err = reason
 		close(sub.queue)
+		close(sub.done)
+
+		sp.metrics.Subscriptions(-1)
 	})
 }
 
-func (sub *subscription) deliver(msg interface{}) {
+// closeOnDone - завершение подписки SubscribeContext с причиной ctx.Err()
+func (sub *subscription) closeOnDone(ctx context.Context) {
+	select {
+	case <-ctx.Done():
+		sub.close(ctx.Err())
+	case <-sub.done:
+	case <-sub.sp.closeChan:
+	}
+}
+
+func (sub *subscription) deliver(msg *message) {
+	if sub.kicked.Load() {
+		return
+	}
+	// Истёкшее в очереди subject не занимает место в очереди подписки
+	if msg.expired(time.Now()) {
+		sub.expired(msg)
+		return
+	}
+
+	sub.sp.metrics.SubscriptionQueueFill(fillRatio(len(sub.queue), cap(sub.queue)))
+
 	select {
 	case sub.queue <- msg:
 	default:
-		sub.sp.log.Warn("Subscription queue is full",
-			slog.String("id", sub.id),
-			slog.String("subject", sub.subject),
-		)
+		if sub.policy == OverflowDisconnect && sub.kicked.Swap(true) {
+			return
+		}
+		sub.overflow(msg)
 	}
 }
 
 func (sub *subscription) dispatchMessages() {
+	if !sub.replay() {
+		return
+	}
+
 	for {
 		select {
 		case msg, ok := <-sub.queue:
 			if !ok {
 				return
 			}
+			if sub.isReplayed(msg) || sub.isRetained(msg) {
+				continue
+			}
 			sub.handleMessage(msg)
 
+		case <-sub.ackNotify():
+			for _, f := range sub.acks.takeReady() {
+				sub.redeliver(f)
+			}
+
 		case <-sub.sp.closeChan:
 			return
 		}
 	}
 }
 
-func (sub *subscription) handleMessage(msg interface{}) {
-	sub.sp.wg.Add(1)
+// ackNotify - nil канал для подписок без подтверждений
+func (sub *subscription) ackNotify() <-chan struct{} {
+	if sub.acks == nil {
+		return nil
+	}
+	return sub.acks.notify
+}
+
+/*
+replay
+
+Отдаёт обработчику сохранённые до подписки сообщения
+и сообщения из durable log до границ курсоров.
+Новые сообщения в это время копятся в очереди подписки.
+Возвращает false, если подписка или subPub закрыты.
+*/
+func (sub *subscription) replay() bool {
+	for _, msg := range sub.pending {
+		if !sub.handleReplayed(msg) {
+			return false
+		}
+	}
+	sub.pending = nil
+
+	if len(sub.cursors) == 0 {
+		return true
+	}
+
+	sub.replayed = make(map[string]uint64, len(sub.cursors))
+
+	for _, cursor := range sub.cursors {
+		active := true
+
+		err := cursor.replay(func(msg *message) bool {
+			active = sub.handleReplayed(msg)
+			return active
+		})
+		if err != nil {
+			sub.sp.log.Error("Durable log replay failed",
+				sl.Err(err),
+				slog.String("id", sub.id),
+				slog.String("subject", cursor.subject),
+			)
+		}
+		if !active {
+			return false
+		}
+
+		sub.replayed[cursor.subject] = cursor.until
+	}
+
+	sub.cursors = nil
+
+	return true
+}
+
+// handleReplayed возвращает false, если подписка или subPub закрыты
+func (sub *subscription) handleReplayed(msg *message) bool {
+	select {
+	case <-sub.done:
+		return false
+	case <-sub.sp.closeChan:
+		return false
+	default:
+		sub.handleMessage(msg)
+		return true
+	}
+}
+
+// setRetained - retained значения отдаются перед остальными сообщениями, до запуска dispatchMessages
+func (sub *subscription) setRetained(msgs []*message) {
+	if len(msgs) == 0 {
+		return
+	}
+
+	sub.retained = make(map[string]string, len(msgs))
+	for _, msg := range msgs {
+		sub.retained[msg.subject] = msg.id
+	}
+
+	sub.pending = append(msgs, sub.pending...)
+}
+
+/*
+isRetained
+
+Сообщение уже отдано как retained значение: публикация попала
+и в retainedStore, и в очередь подписки. Проверяется только первое
+сообщение каждого subject из очереди.
+*/
+func (sub *subscription) isRetained(msg *message) bool {
+	if len(sub.retained) == 0 {
+		return false
+	}
+
+	id, ok := sub.retained[msg.subject]
+	if !ok {
+		return false
+	}
+	delete(sub.retained, msg.subject)
+
+	return id == msg.id
+}
+
+// isReplayed - сообщение уже было отдано при воспроизведении лога
+func (sub *subscription) isReplayed(msg *message) bool {
+	if msg.offset == 0 {
+		return false
+	}
+
+	until, ok := sub.replayed[msg.subject]
+	return ok && msg.offset < until
+}
+
+func (sub *subscription) handleMessage(msg *message) {
+	if !sub.sp.beginHandler() {
+		return
+	}
 	defer sub.sp.wg.Done()
 
+	if msg.expired(time.Now()) {
+		sub.expired(msg)
+		return
+	}
+
+	if sub.acks != nil {
+		sub.acks.first(msg)
+		return
+	}
+
+	data := msg.data
+	if sub.decode != nil {
+		var err error
+		if data, err = sub.decode(data); err != nil {
+			sub.rejected(msg, err)
+			return
+		}
+	}
+
+	sub.sp.metrics.Delivered(msg.subject)
+	sub.delivered.Add(1)
+
+	span, headers := sub.startDeliverSpan(msg, 1)
+
+	start := time.Now()
 	defer func() {
+		sub.sp.metrics.HandlerDuration(time.Since(start))
+
+		var err error
 		if r := recover(); r != nil {
+			sub.sp.metrics.HandlerPanic(msg.subject)
 			sub.sp.log.Error("Panic in message handler",
 				slog.Any("panic", r),
 				slog.String("stack", string(debug.Stack())),
 				slog.String("id", sub.id),
 				slog.String("subject", sub.subject),
 			)
+
+			err = fmt.Errorf("panic in message handler: %v", r)
 		}
+		endSpan(span, err)
 	}()
 
-	sub.cb(msg)
+	if sub.envelope {
+		m := msg.export()
+		m.Headers = headers
+		m.Data = data
+		sub.cb(m)
+		return
+	}
+
+	sub.cb(data)
+}
+
+func (sub *subscription) redeliver(f *inflight) {
+	if !sub.sp.beginHandler() {
+		return
+	}
+	defer sub.sp.wg.Done()
+
+	// TTL проверяется и перед повтором: ответ на истёкшее сообщение уже не нужен
+	if f.msg.expired(time.Now()) {
+		sub.expired(f.msg)
+		return
+	}
+
+	sub.acks.deliver(f)
+}
+
+func (sub *subscription) Ack(tag uint64) error {
+	if sub.acks == nil {
+		return ErrUnknownDelivery
+	}
+	return sub.acks.ack(tag)
+}
+
+func (sub *subscription) Nack(tag uint64) error {
+	if sub.acks == nil {
+		return ErrUnknownDelivery
+	}
+	return sub.acks.nack(tag)
 }
 
 func (sub *subscription) clear() {
 	sub.once.Do(func() {
+		sub.err = ErrSubPubClosed
 		close(sub.queue)
+		close(sub.done)
+
+		sub.sp.metrics.Subscriptions(-1)
 	})
 }
@@ -0,0 +1,99 @@
+package subpub
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "VK_task/pkg/subpub"
+
+// HeaderTraceParent - W3C traceparent, контекст трассировки сообщения
+const HeaderTraceParent = "traceparent"
+
+var propagator = propagation.TraceContext{}
+
+/*
+startEnqueueSpan
+
+Span постановки сообщения в очереди subject. Родитель - traceparent
+из заголовков сообщения, в заголовки записывается контекст нового span.
+Заголовки копируются: исходная map принадлежит публикующему.
+*/
+func (sp *subPub) startEnqueueSpan(m *message) trace.Span {
+	parent := propagator.Extract(context.Background(), propagation.MapCarrier(m.headers))
+
+	ctx, span := sp.tracer.Start(parent, "subpub enqueue",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			attribute.String("messaging.destination.name", m.subject),
+			attribute.String("messaging.message.id", m.id),
+		),
+	)
+
+	m.headers = injectTrace(ctx, parent, m.headers)
+
+	return span
+}
+
+/*
+startDeliverSpan
+
+Span обработки сообщения подпиской. Возвращает заголовки для
+Message обработчика с контекстом этого span, общая map сообщения
+не меняется.
+*/
+func (sub *subscription) startDeliverSpan(msg *message, attempt int) (trace.Span, map[string]string) {
+	parent := propagator.Extract(context.Background(), propagation.MapCarrier(msg.headers))
+
+	ctx, span := sub.sp.tracer.Start(parent, "subpub deliver",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("messaging.destination.name", msg.subject),
+			attribute.String("messaging.message.id", msg.id),
+			attribute.String("messaging.subscription.id", sub.id),
+			attribute.Int("messaging.delivery.attempt", attempt),
+		),
+	)
+
+	return span, injectTrace(ctx, parent, msg.headers)
+}
+
+/*
+WithTraceContext
+
+Копия headers с traceparent span из ctx - для PublishMsg, чтобы
+span публикации и доставки стали потомками span вызывающего.
+Без span в ctx возвращает headers как есть.
+*/
+func WithTraceContext(ctx context.Context, headers map[string]string) map[string]string {
+	return injectTrace(ctx, context.Background(), headers)
+}
+
+// injectTrace - копия headers с traceparent из ctx, если span в ctx новый
+func injectTrace(ctx, parent context.Context, headers map[string]string) map[string]string {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() || sc.Equal(trace.SpanContextFromContext(parent)) {
+		return headers
+	}
+
+	carrier := make(propagation.MapCarrier, len(headers)+1)
+	for k, v := range headers {
+		carrier[k] = v
+	}
+	propagator.Inject(ctx, carrier)
+
+	return carrier
+}
+
+// endSpan - завершает span, err != nil помечает его ошибкой
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -3,45 +3,183 @@ package subpub
 import (
 	"context"
 	"log/slog"
+	"time"
+
+	"VK_task/pkg/e"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
 )
 
 type MessageHandler func(msg interface{})
 
+/*
+Message
+
+Передаётся в MessageHandler вместо данных при подписке с WithEnvelope.
+В PublishMsg задаются Subject, Headers, Data, TTL и Retain, остальное заполняет SubPub.
+*/
+type Message struct {
+	ID        string    // уникальный идентификатор, назначается при публикации
+	Subject   string    // subject, в который опубликовано сообщение
+	Offset    uint64    // смещение в durable log, 0 - сообщение не записано
+	Timestamp time.Time // время публикации
+	Headers   map[string]string
+	Data      interface{}
+
+	// Время жизни от публикации, 0 - по Config.SubjectTTL и Config.MessageTTL.
+	// Истёкшее сообщение отбрасывается до доставки в обработчик
+	TTL time.Duration
+
+	// При публикации - сохранить как последнее значение subject для новых подписок,
+	// пустые Data удаляют значение. В обработчике - сообщение отдано из сохранённых
+	// при подписке, а не новая публикация
+	Retain bool
+}
+
+// PublishResult - результат публикации одного сообщения из PublishBatch
+type PublishResult struct {
+	Delivered int // число подписчиков, получивших сообщение
+	Err       error
+}
+
 type Subscription interface {
+	ID() string
 	Unsubscribe()
+	Done() <-chan struct{}
+	Err() error
+	Stats() SubscriptionStats
 }
 
 type SubPub interface {
-	Subscribe(subject string, cb MessageHandler) (Subscription, error)
+	Subscribe(subject string, cb MessageHandler, opts ...SubscribeOption) (Subscription, error)
+	SubscribeContext(ctx context.Context, subject string, cb MessageHandler, opts ...SubscribeOption) (Subscription, error)
+	QueueSubscribe(subject, group string, cb MessageHandler, opts ...SubscribeOption) (Subscription, error)
+	SubscribeAck(subject string, cb AckHandler, opts ...SubscribeOption) (AckSubscription, error)
 	Publish(subject string, msg interface{}) error
+	PublishCount(subject string, msg interface{}) (int, error)
+	PublishContext(ctx context.Context, subject string, msg interface{}) (int, error)
+	PublishMsg(msg *Message) (int, error)
+	PublishMsgContext(ctx context.Context, msg *Message) (int, error)
+	PublishBatch(msgs []*Message) []PublishResult
+	Request(ctx context.Context, subject string, msg interface{}) (*Message, error)
+	RequestMsg(ctx context.Context, msg *Message) (*Message, error)
 	Close(ctx context.Context) error
+
+	// Администрирование
+	Stats() Stats
+	CloseSubscription(id string) error
+	DrainSubject(ctx context.Context, subject string) (int, error)
 }
 
 type Config struct {
 	SubjectBuffer      int
 	SubscriptionBuffer int
+
+	// Публикация без подписчиков
+	NoSubscribers NoSubscribersPolicy
+	PendingBuffer int // Сообщений на subject при NoSubscribersRetain и dead-letter без подписчиков
+
+	// Переполнение очереди подписки, по умолчанию для всех Subscribe
+	Overflow     OverflowPolicy
+	BlockTimeout time.Duration // Ожидание места в очереди при OverflowBlock
+
+	// Время жизни сообщений без Message.TTL, 0 - без ограничения
+	MessageTTL time.Duration
+	SubjectTTL map[string]time.Duration // по шаблонам subject, важнее MessageTTL
+
+	// SubscribeAck
+	AckTimeout       time.Duration // Ожидание Ack до повторной доставки
+	MaxAttempts      int           // Попыток доставки до dead-letter
+	DeadLetterPrefix string        // Dead-letter subject: <prefix>.<subject>, пусто - сообщение отбрасывается
+
+	Log LogConfig
+
+	Metrics Metrics // nil - без метрик
+
+	TracerProvider trace.TracerProvider // nil - без трассировки
+}
+
+type LogConfig struct {
+	Dir          string // Каталог durable log, пусто - лог выключен
+	SegmentSize  int64  // Размер сегмента в байтах
+	Sync         bool   // fsync после каждой записи
+	MaxOpenFiles int    // Открытых на запись сегментов, по одному на subject
 }
 
+/*
+NewSubPub
+
+Создаёт шину без durable log, cfg.Log не используется.
+Для работы с логом - Open.
+*/
 func NewSubPub(cfg *Config, log *slog.Logger) SubPub {
 	cfg.validate()
 
-	return &subPub{
-		subjects:  make(map[string]*subject, 8),
-		closeChan: make(chan struct{}),
-		log:       log,
-		cfg:       cfg,
+	return newSubPub(cfg, log)
+}
+
+/*
+Open
+
+Создаёт шину и, если задан cfg.Log.Dir, открывает durable log:
+восстанавливает сегменты с диска и смещения каждого subject.
+*/
+func Open(cfg *Config, log *slog.Logger) (SubPub, error) {
+	cfg.validate()
+
+	sp := newSubPub(cfg, log)
+
+	if cfg.Log.Dir != "" {
+		msgLog, err := openMsgLog(cfg.Log)
+		if err != nil {
+			return nil, e.Wrap("durable log open failed", err)
+		}
+
+		sp.msgLog = msgLog
 	}
+
+	return sp, nil
+}
+
+func newSubPub(cfg *Config, log *slog.Logger) *subPub {
+	sp := &subPub{
+		subjects:    make(map[string]*subject, 8),
+		sublist:     newSublist(),
+		draining:    make(map[*subject]struct{}),
+		closeChan:   make(chan struct{}),
+		log:         log,
+		cfg:         cfg,
+		metrics:     inboxMetrics{cfg.Metrics},
+		tracer:      cfg.TracerProvider.Tracer(tracerName),
+		retained:    newRetainedStore(),
+		deadLetters: newPendingBuffer(cfg.PendingBuffer),
+	}
+
+	if cfg.NoSubscribers == NoSubscribersRetain {
+		sp.pending = newPendingBuffer(cfg.PendingBuffer)
+	}
+
+	return sp
 }
 
 const (
 	defaultSubjectPuffer      = 16
 	defaultSubscriptionPuffer = 64
+	defaultPendingBuffer      = 16
+	defaultBlockTimeout       = time.Second
+	defaultAckTimeout         = 30 * time.Second
+	defaultMaxAttempts        = 5
+	DefaultDeadLetterPrefix   = "dlq"
+	defaultSegmentSize        = 64 << 20
+	defaultMaxOpenFiles       = 256
 )
 
 func DefaultConfig() *Config {
 	return &Config{
 		SubjectBuffer:      defaultSubjectPuffer,
 		SubscriptionBuffer: defaultSubscriptionPuffer,
+		DeadLetterPrefix:   DefaultDeadLetterPrefix,
 	}
 }
 
@@ -49,14 +187,39 @@ func NewConfig(subjectBuffer, subscriptionBuffer int) *Config {
 	return &Config{
 		SubjectBuffer:      subjectBuffer,
 		SubscriptionBuffer: subscriptionBuffer,
+		DeadLetterPrefix:   DefaultDeadLetterPrefix,
 	}
 }
 
 func (cfg *Config) validate() {
+	if cfg.Metrics == nil {
+		cfg.Metrics = noopMetrics{}
+	}
+	if cfg.TracerProvider == nil {
+		cfg.TracerProvider = noop.NewTracerProvider()
+	}
 	if cfg.SubjectBuffer <= 0 {
 		cfg.SubjectBuffer = defaultSubjectPuffer
 	}
 	if cfg.SubscriptionBuffer <= 0 {
 		cfg.SubscriptionBuffer = defaultSubscriptionPuffer
 	}
+	if cfg.PendingBuffer <= 0 {
+		cfg.PendingBuffer = defaultPendingBuffer
+	}
+	if cfg.BlockTimeout <= 0 {
+		cfg.BlockTimeout = defaultBlockTimeout
+	}
+	if cfg.AckTimeout <= 0 {
+		cfg.AckTimeout = defaultAckTimeout
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = defaultMaxAttempts
+	}
+	if cfg.Log.SegmentSize <= 0 {
+		cfg.Log.SegmentSize = defaultSegmentSize
+	}
+	if cfg.Log.MaxOpenFiles <= 0 {
+		cfg.Log.MaxOpenFiles = defaultMaxOpenFiles
+	}
 }
@@ -0,0 +1,117 @@
+package subpub
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrTypeMismatch - данные сообщения не приводятся к типу типизированной подписки
+var ErrTypeMismatch = errors.New("message type mismatch")
+
+// decodeFunc - преобразование данных сообщения перед вызовом MessageHandler
+type decodeFunc func(data interface{}) (interface{}, error)
+
+// withDecode - данные сообщения проходят через decode, при ошибке сообщение отбрасывается
+func withDecode(decode decodeFunc) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.decode = decode
+		o.envelope = false
+	}
+}
+
+/*
+Topic
+
+Типизированный subject поверх SubPub. Без кодека данные передаются
+в процессе как есть; с кодеком публикуются []byte, а подписчик
+принимает []byte или string - так сообщения Topic проходят через gRPC.
+*/
+type Topic[T any] struct {
+	ps      SubPub
+	subject string
+	codec   Codec[T] // nil - значения T без сериализации
+}
+
+// NewTopic - codec nil для обмена значениями T внутри процесса
+func NewTopic[T any](ps SubPub, subject string, codec Codec[T]) *Topic[T] {
+	return &Topic[T]{ps: ps, subject: subject, codec: codec}
+}
+
+func (t *Topic[T]) Subject() string {
+	return t.subject
+}
+
+func (t *Topic[T]) Publish(v T) error {
+	_, err := t.PublishCount(v)
+	return err
+}
+
+// PublishCount - число подписчиков, в очередь которых попало сообщение
+func (t *Topic[T]) PublishCount(v T) (int, error) {
+	if t.codec == nil {
+		return t.ps.PublishCount(t.subject, v)
+	}
+
+	data, err := t.codec.Encode(v)
+	if err != nil {
+		return 0, fmt.Errorf("encode %s: %w", t.subject, err)
+	}
+
+	return t.ps.PublishCount(t.subject, data)
+}
+
+/*
+Subscribe
+
+cb получает только значения T: сообщения другого типа или
+не декодируемые кодеком отбрасываются с причиной DropDecode
+и учитываются в SubscriptionStats.Dropped. WithEnvelope игнорируется.
+*/
+func (t *Topic[T]) Subscribe(cb func(T), opts ...SubscribeOption) (Subscription, error) {
+	opts = append(opts, withDecode(decoder(t.codec)))
+
+	return t.ps.Subscribe(t.subject, func(msg interface{}) {
+		cb(msg.(T))
+	}, opts...)
+}
+
+// Publish - публикация значения T без кодека, для подписчиков Subscribe[T]
+func Publish[T any](ps SubPub, subject string, v T) error {
+	return NewTopic[T](ps, subject, nil).Publish(v)
+}
+
+// Subscribe - подписка, получающая только значения T, см. Topic.Subscribe
+func Subscribe[T any](ps SubPub, subject string, cb func(T), opts ...SubscribeOption) (Subscription, error) {
+	return NewTopic[T](ps, subject, nil).Subscribe(cb, opts...)
+}
+
+func decoder[T any](codec Codec[T]) decodeFunc {
+	if codec == nil {
+		return func(data interface{}) (interface{}, error) {
+			v, ok := data.(T)
+			if !ok {
+				return nil, fmt.Errorf("%w: got %T, want %T", ErrTypeMismatch, data, v)
+			}
+			return v, nil
+		}
+	}
+
+	return func(data interface{}) (interface{}, error) {
+		var raw []byte
+
+		switch d := data.(type) {
+		case []byte:
+			raw = d
+		case string:
+			raw = []byte(d)
+		default:
+			return nil, fmt.Errorf("%w: got %T, want []byte or string", ErrTypeMismatch, data)
+		}
+
+		v, err := codec.Decode(raw)
+		if err != nil {
+			return nil, err
+		}
+		return v, nil
+	}
+}
@@ -11,7 +11,6 @@ import (
 	grpc "google.golang.org/grpc"
 	codes "google.golang.org/grpc/codes"
 	status "google.golang.org/grpc/status"
-	emptypb "google.golang.org/protobuf/types/known/emptypb"
 )
 
 // This is a compile-time assertion to ensure that this generated file
@@ -20,8 +19,13 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	PubSub_Subscribe_FullMethodName = "/PubSub/Subscribe"
-	PubSub_Publish_FullMethodName   = "/PubSub/Publish"
+	PubSub_Subscribe_FullMethodName     = "/PubSub/Subscribe"
+	PubSub_Publish_FullMethodName       = "/PubSub/Publish"
+	PubSub_PublishBatch_FullMethodName  = "/PubSub/PublishBatch"
+	PubSub_PublishStream_FullMethodName = "/PubSub/PublishStream"
+	PubSub_SubscribeAck_FullMethodName  = "/PubSub/SubscribeAck"
+	PubSub_Session_FullMethodName       = "/PubSub/Session"
+	PubSub_Request_FullMethodName       = "/PubSub/Request"
 )
 
 // PubSubClient is the client API for PubSub service.
@@ -31,7 +35,21 @@ type PubSubClient interface {
 	// Подписка (сервер отправляет поток событий)
 	Subscribe(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Event], error)
 	// Публикация (классический запрос-ответ)
-	Publish(ctx context.Context, in *PublishRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	Publish(ctx context.Context, in *PublishRequest, opts ...grpc.CallOption) (*PublishResponse, error)
+	// Публикация пакета сообщений, результат по каждому в том же порядке
+	PublishBatch(ctx context.Context, in *PublishBatchRequest, opts ...grpc.CallOption) (*PublishBatchResponse, error)
+	// Потоковая публикация: результаты по всем сообщениям потока после его закрытия
+	PublishStream(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[PublishRequest, PublishBatchResponse], error)
+	// Подписка с подтверждениями (at-least-once): первым сообщением клиент
+	// отправляет subscribe, затем ack по tag каждого полученного Event
+	SubscribeAck(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[SubscribeAckRequest, Event], error)
+	// Сессия: много подписок и публикаций в одном потоке.
+	// События приходят с sid подписки, на каждый запрос - SessionReply с его request_id
+	// (кроме успешного ack)
+	Session(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[SessionRequest, SessionResponse], error)
+	// Запрос-ответ: публикация в key с reply_to и ожидание первого ответа
+	// до дедлайна вызова. Отвечающий публикует ответ в reply_to из Event
+	Request(ctx context.Context, in *RequestMessage, opts ...grpc.CallOption) (*ReplyMessage, error)
 }
 
 type pubSubClient struct {
@@ -61,9 +79,9 @@ func (c *pubSubClient) Subscribe(ctx context.Context, in *SubscribeRequest, opts
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type PubSub_SubscribeClient = grpc.ServerStreamingClient[Event]
 
-func (c *pubSubClient) Publish(ctx context.Context, in *PublishRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+func (c *pubSubClient) Publish(ctx context.Context, in *PublishRequest, opts ...grpc.CallOption) (*PublishResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
-	out := new(emptypb.Empty)
+	out := new(PublishResponse)
 	err := c.cc.Invoke(ctx, PubSub_Publish_FullMethodName, in, out, cOpts...)
 	if err != nil {
 		return nil, err
@@ -71,6 +89,65 @@ func (c *pubSubClient) Publish(ctx context.Context, in *PublishRequest, opts ...
 	return out, nil
 }
 
+func (c *pubSubClient) PublishBatch(ctx context.Context, in *PublishBatchRequest, opts ...grpc.CallOption) (*PublishBatchResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PublishBatchResponse)
+	err := c.cc.Invoke(ctx, PubSub_PublishBatch_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pubSubClient) PublishStream(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[PublishRequest, PublishBatchResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &PubSub_ServiceDesc.Streams[1], PubSub_PublishStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[PublishRequest, PublishBatchResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PubSub_PublishStreamClient = grpc.ClientStreamingClient[PublishRequest, PublishBatchResponse]
+
+func (c *pubSubClient) SubscribeAck(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[SubscribeAckRequest, Event], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &PubSub_ServiceDesc.Streams[2], PubSub_SubscribeAck_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[SubscribeAckRequest, Event]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PubSub_SubscribeAckClient = grpc.BidiStreamingClient[SubscribeAckRequest, Event]
+
+func (c *pubSubClient) Session(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[SessionRequest, SessionResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &PubSub_ServiceDesc.Streams[3], PubSub_Session_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[SessionRequest, SessionResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PubSub_SessionClient = grpc.BidiStreamingClient[SessionRequest, SessionResponse]
+
+func (c *pubSubClient) Request(ctx context.Context, in *RequestMessage, opts ...grpc.CallOption) (*ReplyMessage, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReplyMessage)
+	err := c.cc.Invoke(ctx, PubSub_Request_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // PubSubServer is the server API for PubSub service.
 // All implementations must embed UnimplementedPubSubServer
 // for forward compatibility.
@@ -78,7 +155,21 @@ type PubSubServer interface {
 	// Подписка (сервер отправляет поток событий)
 	Subscribe(*SubscribeRequest, grpc.ServerStreamingServer[Event]) error
 	// Публикация (классический запрос-ответ)
-	Publish(context.Context, *PublishRequest) (*emptypb.Empty, error)
+	Publish(context.Context, *PublishRequest) (*PublishResponse, error)
+	// Публикация пакета сообщений, результат по каждому в том же порядке
+	PublishBatch(context.Context, *PublishBatchRequest) (*PublishBatchResponse, error)
+	// Потоковая публикация: результаты по всем сообщениям потока после его закрытия
+	PublishStream(grpc.ClientStreamingServer[PublishRequest, PublishBatchResponse]) error
+	// Подписка с подтверждениями (at-least-once): первым сообщением клиент
+	// отправляет subscribe, затем ack по tag каждого полученного Event
+	SubscribeAck(grpc.BidiStreamingServer[SubscribeAckRequest, Event]) error
+	// Сессия: много подписок и публикаций в одном потоке.
+	// События приходят с sid подписки, на каждый запрос - SessionReply с его request_id
+	// (кроме успешного ack)
+	Session(grpc.BidiStreamingServer[SessionRequest, SessionResponse]) error
+	// Запрос-ответ: публикация в key с reply_to и ожидание первого ответа
+	// до дедлайна вызова. Отвечающий публикует ответ в reply_to из Event
+	Request(context.Context, *RequestMessage) (*ReplyMessage, error)
 	mustEmbedUnimplementedPubSubServer()
 }
 
@@ -92,9 +183,24 @@ type UnimplementedPubSubServer struct{}
 func (UnimplementedPubSubServer) Subscribe(*SubscribeRequest, grpc.ServerStreamingServer[Event]) error {
 	return status.Errorf(codes.Unimplemented, "method Subscribe not implemented")
 }
-func (UnimplementedPubSubServer) Publish(context.Context, *PublishRequest) (*emptypb.Empty, error) {
+func (UnimplementedPubSubServer) Publish(context.Context, *PublishRequest) (*PublishResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Publish not implemented")
 }
+func (UnimplementedPubSubServer) PublishBatch(context.Context, *PublishBatchRequest) (*PublishBatchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PublishBatch not implemented")
+}
+func (UnimplementedPubSubServer) PublishStream(grpc.ClientStreamingServer[PublishRequest, PublishBatchResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method PublishStream not implemented")
+}
+func (UnimplementedPubSubServer) SubscribeAck(grpc.BidiStreamingServer[SubscribeAckRequest, Event]) error {
+	return status.Errorf(codes.Unimplemented, "method SubscribeAck not implemented")
+}
+func (UnimplementedPubSubServer) Session(grpc.BidiStreamingServer[SessionRequest, SessionResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method Session not implemented")
+}
+func (UnimplementedPubSubServer) Request(context.Context, *RequestMessage) (*ReplyMessage, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Request not implemented")
+}
 func (UnimplementedPubSubServer) mustEmbedUnimplementedPubSubServer() {}
 func (UnimplementedPubSubServer) testEmbeddedByValue()                {}
 
@@ -145,6 +251,63 @@ func _PubSub_Publish_Handler(srv interface{}, ctx context.Context, dec func(inte
 	return interceptor(ctx, in, info, handler)
 }
 
+func _PubSub_PublishBatch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PublishBatchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PubSubServer).PublishBatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PubSub_PublishBatch_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PubSubServer).PublishBatch(ctx, req.(*PublishBatchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PubSub_PublishStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(PubSubServer).PublishStream(&grpc.GenericServerStream[PublishRequest, PublishBatchResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PubSub_PublishStreamServer = grpc.ClientStreamingServer[PublishRequest, PublishBatchResponse]
+
+func _PubSub_SubscribeAck_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(PubSubServer).SubscribeAck(&grpc.GenericServerStream[SubscribeAckRequest, Event]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PubSub_SubscribeAckServer = grpc.BidiStreamingServer[SubscribeAckRequest, Event]
+
+func _PubSub_Session_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(PubSubServer).Session(&grpc.GenericServerStream[SessionRequest, SessionResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PubSub_SessionServer = grpc.BidiStreamingServer[SessionRequest, SessionResponse]
+
+func _PubSub_Request_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RequestMessage)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PubSubServer).Request(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PubSub_Request_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PubSubServer).Request(ctx, req.(*RequestMessage))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // PubSub_ServiceDesc is the grpc.ServiceDesc for PubSub service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -156,6 +319,14 @@ var PubSub_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Publish",
 			Handler:    _PubSub_Publish_Handler,
 		},
+		{
+			MethodName: "PublishBatch",
+			Handler:    _PubSub_PublishBatch_Handler,
+		},
+		{
+			MethodName: "Request",
+			Handler:    _PubSub_Request_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -163,6 +334,23 @@ var PubSub_ServiceDesc = grpc.ServiceDesc{
 			Handler:       _PubSub_Subscribe_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "PublishStream",
+			Handler:       _PubSub_PublishStream_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "SubscribeAck",
+			Handler:       _PubSub_SubscribeAck_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "Session",
+			Handler:       _PubSub_Session_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
 	},
 	Metadata: "proto/pubSub.proto",
 }